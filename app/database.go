@@ -1,27 +1,60 @@
 package app
 
 import (
-	"os"
+	"context"
+	"project-app/config"
 	"project-app/helper"
-	"project-app/schema"
+	"project-app/lifecycle"
+	"project-app/migration"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func DbConnection() *gorm.DB {
+// OpenDatabase connects to the database without touching the schema.
+func OpenDatabase(cfg config.DatabaseConfig) *gorm.DB {
 
-	postgresDsn := os.Getenv("APP_DSN")
-	db, err := gorm.Open(postgres.Open(postgresDsn), &gorm.Config{})
+	// TranslateError turns unique violations into gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.Open(cfg.Dsn), &gorm.Config{
+		TranslateError: true,
+	})
 
 	helper.PanicIfError(err)
 
-	db.AutoMigrate(
-		&schema.Category{},
-		&schema.Users{},
-		&schema.Project{},
-		&schema.ProjectItem{},
-	)
+	return db
+}
+
+// DbConnection connects to the database for the server. It refuses to start
+// when a migration is not applied, run `migrate up` first.
+func DbConnection(cfg *config.Config) *gorm.DB {
+
+	db := OpenDatabase(cfg.Database)
+
+	migrator, err := migration.NewMigrator(db)
+	helper.PanicIfError(err)
+
+	err = migrator.EnsureUpToDate()
+	helper.PanicIfError(err)
+
+	err = SeedRoles(db, cfg.Auth.AdminEmail)
+	helper.PanicIfError(err)
 
 	return db
 }
+
+// DatabaseHook closes the connection pool when the app stops, after the
+// queries that are still running finished.
+func DatabaseHook(db *gorm.DB) lifecycle.Hook {
+
+	return lifecycle.Hook{
+		Name: "database",
+		Stop: func(ctx context.Context) error {
+			sqlDb, err := db.DB()
+			if err != nil {
+				return err
+			}
+
+			return sqlDb.Close()
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"net"
+	"project-app/config"
+	"project-app/health"
+
+	"gorm.io/gorm"
+)
+
+// RegisterHealthChecks adds the checks of the dependencies the app is
+// configured with. The mail server is optional, the app still serves
+// requests without it.
+func RegisterHealthChecks(registry *health.Registry, db *gorm.DB, cfg *config.Config) {
+
+	registry.Register(health.Database(db))
+	registry.Register(health.Migrations(db))
+
+	if cfg.Mail.Driver == "smtp" {
+		registry.RegisterOptional(health.Dial("smtp", net.JoinHostPort(cfg.Mail.Smtp.Host, cfg.Mail.Smtp.Port)))
+	}
+}
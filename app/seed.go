@@ -0,0 +1,87 @@
+package app
+
+import (
+	"project-app/model"
+	"project-app/schema"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SeedRoles creates the default roles and permissions. The first time the
+// roles are created every existing user gets the user role, so accounts made
+// before roles existed keep working. The user with adminEmail always gets the
+// admin role, the email is compared without case like at login.
+func SeedRoles(db *gorm.DB, adminEmail string) error {
+
+	adminEmail = strings.ToLower(strings.TrimSpace(adminEmail))
+
+	return db.Transaction(func(tx *gorm.DB) error {
+
+		var existingRoles int64
+		if err := tx.Model(&schema.Role{}).Count(&existingRoles).Error; err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(model.DefaultRoles))
+		for name := range model.DefaultRoles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			role := schema.Role{Name: name}
+			if err := tx.Where(schema.Role{Name: name}).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			permissions := make([]schema.Permission, 0, len(model.DefaultRoles[name]))
+			for _, permissionName := range model.DefaultRoles[name] {
+				permission := schema.Permission{Name: permissionName}
+				if err := tx.Where(schema.Permission{Name: permissionName}).FirstOrCreate(&permission).Error; err != nil {
+					return err
+				}
+				permissions = append(permissions, permission)
+			}
+
+			if err := tx.Model(&role).Association("Permissions").Append(permissions); err != nil {
+				return err
+			}
+		}
+
+		if existingRoles == 0 {
+			err := tx.Exec(`INSERT INTO user_roles (user_id, role_id, created_at)
+				SELECT users.id, roles.id, CURRENT_TIMESTAMP FROM users, roles
+				WHERE roles.name = ? AND users.deleted_at IS NULL`, model.RoleUser).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if adminEmail == "" {
+			return nil
+		}
+
+		var admin schema.Users
+		result := tx.Where("lower(email) = ?", adminEmail).Limit(1).Find(&admin)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			slog.Warn("no user has the admin email, no admin role given", "email", adminEmail)
+			return nil
+		}
+
+		var adminRole schema.Role
+		if err := tx.Where("name = ?", model.RoleAdmin).Take(&adminRole).Error; err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&schema.UserRole{UserID: admin.ID, RoleID: adminRole.ID}).
+			Error
+	})
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"project-app/apperror"
+	"project-app/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FiberConfig is the configuration of the server. c.IP() reads the proxy
+// header only on requests from a trusted proxy, and only the IPs in it.
+func FiberConfig(cfg *config.Config) fiber.Config {
+
+	return fiber.Config{
+		AppName:                 cfg.App.Name,
+		BodyLimit:               cfg.App.BodyLimit,
+		ReadTimeout:             cfg.App.ReadTimeout,
+		WriteTimeout:            cfg.App.WriteTimeout,
+		ErrorHandler:            apperror.ErrorHandler,
+		ProxyHeader:             cfg.App.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.App.ProxyHeader != "",
+		TrustedProxies:          cfg.App.TrustedProxies,
+		EnableIPValidation:      true,
+	}
+}
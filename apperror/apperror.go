@@ -0,0 +1,187 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"project-app/validation"
+	"time"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Kind groups errors by how the client should react to them. It is sent as
+// the "error" field of the response, so clients can rely on it.
+type Kind string
+
+const (
+	KindValidation      Kind = "validation_error"
+	KindUnauthorized    Kind = "unauthorized"
+	KindForbidden       Kind = "forbidden"
+	KindNotFound        Kind = "not_found"
+	KindConflict        Kind = "conflict"
+	KindTooManyRequests Kind = "too_many_requests"
+	KindTimeout         Kind = "timeout"
+	KindInternal        Kind = "internal_error"
+)
+
+var statusByKind = map[Kind]int{
+	KindValidation:      fiber.StatusBadRequest,
+	KindUnauthorized:    fiber.StatusUnauthorized,
+	KindForbidden:       fiber.StatusForbidden,
+	KindNotFound:        fiber.StatusNotFound,
+	KindConflict:        fiber.StatusConflict,
+	KindTooManyRequests: fiber.StatusTooManyRequests,
+	KindTimeout:         fiber.StatusServiceUnavailable,
+	KindInternal:        fiber.StatusInternalServerError,
+}
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// AppError is an error that can be shown to the client. Message is sent as
+// is, Err is only logged.
+type AppError struct {
+	Kind    Kind
+	Message string
+	Fields  []FieldError
+	Err     error
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration
+	// status overrides the status of the kind, for errors raised by fiber
+	status int
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Kind, e.Message, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Status is the HTTP status of the response.
+func (e *AppError) Status() int {
+	if e.status != 0 {
+		return e.status
+	}
+
+	if status, ok := statusByKind[e.Kind]; ok {
+		return status
+	}
+
+	return fiber.StatusInternalServerError
+}
+
+// Wrap keeps err as the cause, errors.Is and errors.As still see it.
+func (e *AppError) Wrap(err error) *AppError {
+	e.Err = err
+	return e
+}
+
+// RetryIn tells the client when to try again.
+func (e *AppError) RetryIn(wait time.Duration) *AppError {
+	e.RetryAfter = wait
+	return e
+}
+
+func Validation(message string, fields ...FieldError) *AppError {
+	return &AppError{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+func Unauthorized(message string) *AppError {
+	return &AppError{Kind: KindUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *AppError {
+	return &AppError{Kind: KindForbidden, Message: message}
+}
+
+func NotFound(message string) *AppError {
+	return &AppError{Kind: KindNotFound, Message: message}
+}
+
+func Conflict(message string) *AppError {
+	return &AppError{Kind: KindConflict, Message: message}
+}
+
+func TooManyRequests(message string) *AppError {
+	return &AppError{Kind: KindTooManyRequests, Message: message}
+}
+
+// Internal hides err from the client behind a generic message.
+func Internal(err error) *AppError {
+	return &AppError{Kind: KindInternal, Message: "Internal server error", Err: err}
+}
+
+// InvalidBody is returned when the body cannot be parsed.
+func InvalidBody(err error) *AppError {
+	return Validation("Invalid request body").Wrap(err)
+}
+
+// FromValidator turns the errors of validator.Struct into a validation
+// error with one entry per invalid field. Messages are in the default
+// language, ErrorHandler translates them for the client. Other errors
+// become internal.
+func FromValidator(err error) *AppError {
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return Internal(err)
+	}
+
+	fields := fieldErrors(validationErrors, validation.Translator(validation.DefaultLanguage))
+
+	return Validation("Invalid request", fields...).Wrap(err)
+}
+
+// Translate returns the field errors with messages in language. Only the
+// errors built by FromValidator can be translated, the others are returned
+// as is.
+func (e *AppError) Translate(language string) []FieldError {
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(e.Err, &validationErrors) {
+		return e.Fields
+	}
+
+	return fieldErrors(validationErrors, validation.Translator(language))
+}
+
+func fieldErrors(validationErrors validator.ValidationErrors, translator ut.Translator) []FieldError {
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+
+		// Rules without a message in the catalog translate to the raw error
+		message := fieldError.Translate(translator)
+		if message == fieldError.Error() {
+			message = fmt.Sprintf("%s failed on the %s rule", fieldError.Field(), fieldError.Tag())
+		}
+
+		fields = append(fields, FieldError{
+			Field:   fieldError.Field(),
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: message,
+		})
+	}
+
+	return fields
+}
+
+// Is reports whether err is an AppError of kind.
+func Is(err error, kind Kind) bool {
+	var appError *AppError
+	return errors.As(err, &appError) && appError.Kind == kind
+}
@@ -0,0 +1,134 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStatus(t *testing.T) {
+
+	tests := []struct {
+		err  *AppError
+		want int
+	}{
+		{Validation("invalid"), fiber.StatusBadRequest},
+		{Unauthorized("unauthorized"), fiber.StatusUnauthorized},
+		{Forbidden("forbidden"), fiber.StatusForbidden},
+		{NotFound("not found"), fiber.StatusNotFound},
+		{Conflict("conflict"), fiber.StatusConflict},
+		{TooManyRequests("too many"), fiber.StatusTooManyRequests},
+		{&AppError{Kind: KindTimeout}, fiber.StatusServiceUnavailable},
+		{Internal(errors.New("boom")), fiber.StatusInternalServerError},
+		{InvalidBody(errors.New("bad json")), fiber.StatusBadRequest},
+		{&AppError{Kind: "unknown"}, fiber.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.err.Kind), func(t *testing.T) {
+			if got := test.err.Status(); got != test.want {
+				t.Errorf("Status() = %d, want %d", got, test.want)
+			}
+		})
+	}
+
+	// Every kind has its status
+	for kind := range statusByKind {
+		if status := (&AppError{Kind: kind}).Status(); status == 0 {
+			t.Errorf("%s has no status", kind)
+		}
+	}
+}
+
+type causeError struct {
+	code int
+}
+
+func (e *causeError) Error() string {
+	return fmt.Sprintf("cause %d", e.code)
+}
+
+func TestWrap(t *testing.T) {
+
+	cause := &causeError{code: 42}
+	err := fmt.Errorf("find user: %w", NotFound("User not found").Wrap(cause))
+
+	// 1. The kind is found through other wrappers
+	if !Is(err, KindNotFound) {
+		t.Errorf("Is(err, %s) = false, want true", KindNotFound)
+	}
+	if Is(err, KindConflict) {
+		t.Errorf("Is(err, %s) = true, want false", KindConflict)
+	}
+	if Is(cause, KindNotFound) || Is(nil, KindNotFound) {
+		t.Errorf("Is is true for an error that is not an AppError")
+	}
+
+	// 2. The cause is still seen by errors.Is and errors.As
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(err, cause) = false, want true")
+	}
+
+	var found *causeError
+	if !errors.As(err, &found) || found.code != 42 {
+		t.Errorf("errors.As(err, *causeError) = %v, want the cause", found)
+	}
+
+	var appError *AppError
+	if !errors.As(err, &appError) || appError.Message != "User not found" {
+		t.Errorf("errors.As(err, *AppError) = %v", appError)
+	}
+
+	// 3. The cause is part of the message for the logs only
+	if got, want := appError.Error(), "not_found: User not found: cause 42"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestFrom(t *testing.T) {
+
+	appError := Conflict("taken")
+
+	tests := []struct {
+		name           string
+		err            error
+		wantKind       Kind
+		wantStatus     int
+		wantMessage    string
+		wantRetryAfter time.Duration
+	}{
+		{name: "app error", err: fmt.Errorf("wrapped: %w", appError), wantKind: KindConflict, wantStatus: fiber.StatusConflict, wantMessage: "taken"},
+		{name: "fiber not found", err: fiber.ErrNotFound, wantKind: KindNotFound, wantStatus: fiber.StatusNotFound, wantMessage: "Not Found"},
+		{name: "fiber unauthorized", err: fiber.ErrUnauthorized, wantKind: KindUnauthorized, wantStatus: fiber.StatusUnauthorized, wantMessage: "Unauthorized"},
+		{name: "fiber forbidden", err: fiber.ErrForbidden, wantKind: KindForbidden, wantStatus: fiber.StatusForbidden, wantMessage: "Forbidden"},
+		{name: "fiber conflict", err: fiber.ErrConflict, wantKind: KindConflict, wantStatus: fiber.StatusConflict, wantMessage: "Conflict"},
+		{name: "fiber too many requests", err: fiber.ErrTooManyRequests, wantKind: KindTooManyRequests, wantStatus: fiber.StatusTooManyRequests, wantMessage: "Too Many Requests"},
+		{name: "fiber client error keeps its status", err: fiber.ErrRequestEntityTooLarge, wantKind: KindValidation, wantStatus: fiber.StatusRequestEntityTooLarge, wantMessage: "Request Entity Too Large"},
+		{name: "fiber method not allowed", err: fiber.ErrMethodNotAllowed, wantKind: KindValidation, wantStatus: fiber.StatusMethodNotAllowed, wantMessage: "Method Not Allowed"},
+		{name: "fiber server error is hidden", err: fiber.NewError(fiber.StatusBadGateway, "upstream secret"), wantKind: KindInternal, wantStatus: fiber.StatusInternalServerError, wantMessage: "Internal server error"},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantKind: KindTimeout, wantStatus: fiber.StatusServiceUnavailable, wantMessage: "Request timed out or was cancelled", wantRetryAfter: timeoutRetryAfter},
+		{name: "cancelled", err: context.Canceled, wantKind: KindTimeout, wantStatus: fiber.StatusServiceUnavailable, wantMessage: "Request timed out or was cancelled", wantRetryAfter: timeoutRetryAfter},
+		{name: "other error is hidden", err: errors.New("pq: password authentication failed"), wantKind: KindInternal, wantStatus: fiber.StatusInternalServerError, wantMessage: "Internal server error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			got := From(test.err)
+
+			if got.Kind != test.wantKind || got.Status() != test.wantStatus || got.Message != test.wantMessage {
+				t.Errorf("From() = %s %d %q, want %s %d %q", got.Kind, got.Status(), got.Message, test.wantKind, test.wantStatus, test.wantMessage)
+			}
+			if got.RetryAfter != test.wantRetryAfter {
+				t.Errorf("RetryAfter = %v, want %v", got.RetryAfter, test.wantRetryAfter)
+			}
+			if !errors.Is(got, test.err) && got != appError {
+				t.Errorf("From() lost the cause %v", test.err)
+			}
+		})
+	}
+}
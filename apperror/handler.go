@@ -0,0 +1,129 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"project-app/logger"
+	"project-app/validation"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const mimeProblemJson = "application/problem+json"
+
+// timeoutRetryAfter is the Retry-After of a request that timed out or was
+// cancelled by the shutdown, another instance can usually answer by then.
+const timeoutRetryAfter = 5 * time.Second
+
+// Response is the body of every error response.
+type Response struct {
+	Code    int          `json:"code"`
+	Error   Kind         `json:"error"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Problem is the RFC 7807 body, sent to clients that accept
+// application/problem+json.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance"`
+	Code     Kind         `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// ErrorHandler is the fiber.Config ErrorHandler. Handlers and middlewares
+// return errors and this writes the response, so every error has the same
+// shape. Errors that are not an AppError or a fiber.Error are logged and
+// answered with a generic 500. Field messages follow Accept-Language.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+
+	appError := From(err)
+	if appError.Kind == KindInternal {
+		logger.FromContext(c.UserContext()).Error("request failed", "method", c.Method(), "path", c.Path(), "error", err)
+	}
+
+	status := appError.Status()
+	fields := appError.Translate(validation.Language(c.Get(fiber.HeaderAcceptLanguage)))
+
+	// Round up, a client retrying after 0 seconds would be refused again
+	if appError.RetryAfter > 0 {
+		seconds := int((appError.RetryAfter + time.Second - 1) / time.Second)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	}
+
+	if strings.Contains(c.Get(fiber.HeaderAccept), mimeProblemJson) {
+		c.Status(status)
+		c.Set(fiber.HeaderContentType, mimeProblemJson)
+		return c.JSON(Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   appError.Message,
+			Instance: c.OriginalURL(),
+			Code:     appError.Kind,
+			Errors:   fields,
+		}, mimeProblemJson)
+	}
+
+	return c.Status(status).JSON(Response{
+		Code:    status,
+		Error:   appError.Kind,
+		Message: appError.Message,
+		Details: fields,
+	})
+}
+
+// From converts any error to an AppError. Errors of fiber, like an unknown
+// route, keep their status and message. A request context that ended, see
+// middleware.RequestContext, is a timeout.
+func From(err error) *AppError {
+
+	var appError *AppError
+	if errors.As(err, &appError) {
+		return appError
+	}
+
+	var fiberError *fiber.Error
+	if errors.As(err, &fiberError) {
+		return fromFiberError(fiberError)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return &AppError{Kind: KindTimeout, Message: "Request timed out or was cancelled", Err: err, RetryAfter: timeoutRetryAfter}
+	}
+
+	return Internal(err)
+}
+
+func fromFiberError(err *fiber.Error) *AppError {
+
+	kind := KindInternal
+	switch {
+	case err.Code == fiber.StatusUnauthorized:
+		kind = KindUnauthorized
+	case err.Code == fiber.StatusForbidden:
+		kind = KindForbidden
+	case err.Code == fiber.StatusNotFound:
+		kind = KindNotFound
+	case err.Code == fiber.StatusConflict:
+		kind = KindConflict
+	case err.Code == fiber.StatusTooManyRequests:
+		kind = KindTooManyRequests
+	case err.Code >= 400 && err.Code < 500:
+		kind = KindValidation
+	}
+
+	if kind == KindInternal {
+		return Internal(err)
+	}
+
+	return &AppError{Kind: kind, Message: err.Message, Err: err, status: err.Code}
+}
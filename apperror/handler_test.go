@@ -0,0 +1,141 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"project-app/validation"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// respond answers GET /items/1 with err through ErrorHandler.
+func respond(t *testing.T, err error, headers map[string]string) (int, string, string, map[string]any) {
+
+	t.Helper()
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		return err
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/items/1?expand=all", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, errTest := app.Test(req, -1)
+	if errTest != nil {
+		t.Fatalf("app.Test: %v", errTest)
+	}
+	defer res.Body.Close()
+
+	body, errRead := io.ReadAll(res.Body)
+	if errRead != nil {
+		t.Fatalf("read body: %v", errRead)
+	}
+
+	decoded := map[string]any{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+
+	return res.StatusCode, res.Header.Get(fiber.HeaderContentType), res.Header.Get(fiber.HeaderRetryAfter), decoded
+}
+
+func TestErrorHandler(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantKind   Kind
+		wantText   string
+	}{
+		{"app error", NotFound("Item not found"), fiber.StatusNotFound, KindNotFound, "Item not found"},
+		{"fiber error", fiber.ErrMethodNotAllowed, fiber.StatusMethodNotAllowed, KindValidation, "Method Not Allowed"},
+		{"unknown error", errors.New("connection reset"), fiber.StatusInternalServerError, KindInternal, "Internal server error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			// 1. JSON by default
+			status, contentType, _, body := respond(t, test.err, nil)
+			if status != test.wantStatus || contentType != fiber.MIMEApplicationJSON {
+				t.Errorf("status = %d %q, want %d %q", status, contentType, test.wantStatus, fiber.MIMEApplicationJSON)
+			}
+			if body["code"] != float64(test.wantStatus) || body["error"] != string(test.wantKind) || body["message"] != test.wantText {
+				t.Errorf("body = %v", body)
+			}
+
+			// 2. RFC 7807 when the client accepts it
+			status, contentType, _, body = respond(t, test.err, map[string]string{fiber.HeaderAccept: "application/problem+json, application/json"})
+			if status != test.wantStatus || contentType != mimeProblemJson {
+				t.Errorf("problem: status = %d %q, want %d %q", status, contentType, test.wantStatus, mimeProblemJson)
+			}
+			if body["status"] != float64(test.wantStatus) || body["code"] != string(test.wantKind) || body["detail"] != test.wantText ||
+				body["type"] != "about:blank" || body["instance"] != "/items/1?expand=all" || body["title"] == "" {
+				t.Errorf("problem: body = %v", body)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerRetryAfter(t *testing.T) {
+
+	tests := []struct {
+		name string
+		wait time.Duration
+		want string
+	}{
+		{"none", 0, ""},
+		{"whole seconds", 2 * time.Second, "2"},
+		{"fraction rounds up", 1500 * time.Millisecond, "2"},
+		{"below a second is one", time.Millisecond, "1"},
+		{"just above a minute", time.Minute + time.Nanosecond, "61"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, retryAfter, _ := respond(t, TooManyRequests("Slow down").RetryIn(test.wait), nil)
+			if retryAfter != test.want {
+				t.Errorf("Retry-After = %q, want %q", retryAfter, test.want)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerTranslates(t *testing.T) {
+
+	type request struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	err := FromValidator(validation.Validator().Struct(request{}))
+
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"", "name is a required field"},
+		{"id", "name wajib diisi"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.language, func(t *testing.T) {
+			_, _, _, body := respond(t, err, map[string]string{fiber.HeaderAcceptLanguage: test.language})
+
+			details, _ := body["details"].([]any)
+			if len(details) != 1 {
+				t.Fatalf("details = %v, want one field", body["details"])
+			}
+			if message := details[0].(map[string]any)["message"]; message != test.want {
+				t.Errorf("message = %v, want %q", message, test.want)
+			}
+		})
+	}
+}
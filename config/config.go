@@ -0,0 +1,415 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Email verification policies, see AuthConfig.EmailVerificationPolicy.
+const (
+	// Unverified accounts can do everything.
+	EmailVerificationOff = "off"
+	// Unverified accounts cannot login.
+	EmailVerificationLogin = "login"
+	// Unverified accounts can login and read, but not write.
+	EmailVerificationWrite = "write"
+)
+
+type Config struct {
+	App       AppConfig       `yaml:"app"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Jwt       JwtConfig       `yaml:"jwt"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Mail      MailConfig      `yaml:"mail"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+}
+
+type AppConfig struct {
+	Name         string        `yaml:"name"`
+	Port         string        `yaml:"port"`
+	BodyLimit    int           `yaml:"bodyLimit"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// RequestTimeout aborts the queries of a request that runs longer, 0
+	// disables it.
+	RequestTimeout time.Duration `yaml:"requestTimeout"`
+	// ShutdownTimeout is how long running requests get to finish after
+	// SIGTERM before they are cancelled and the server stops.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// ProxyHeader is the header in which the reverse proxy sends the client
+	// IP, like X-Real-IP. Empty uses the address of the connection.
+	ProxyHeader string `yaml:"proxyHeader"`
+	// TrustedProxies are the IPs or CIDR ranges of the proxies that may set
+	// ProxyHeader. Requests from other addresses keep their own IP, so
+	// clients cannot pick the IP the rate limits see.
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// ShutdownGrace is how long running requests get after SIGTERM before their
+// context is cancelled. It is shorter than ShutdownTimeout, so the cancelled
+// requests still have time to roll back and answer before the server stops.
+func (cfg AppConfig) ShutdownGrace() time.Duration {
+	return cfg.ShutdownTimeout * 4 / 5
+}
+
+type DatabaseConfig struct {
+	Dsn           string `yaml:"dsn"`
+	MigrationsDir string `yaml:"migrationsDir"`
+}
+
+type JwtConfig struct {
+	Secret string `yaml:"secret"`
+}
+
+type AuthConfig struct {
+	AdminEmail              string `yaml:"adminEmail"`
+	EmailVerificationPolicy string `yaml:"emailVerificationPolicy"`
+	ResetPasswordUrl        string `yaml:"resetPasswordUrl"`
+	VerifyEmailUrl          string `yaml:"verifyEmailUrl"`
+	// LockoutThreshold is how many wrong passwords in a row lock the
+	// account, 0 disables the lockout.
+	LockoutThreshold int `yaml:"lockoutThreshold"`
+	// LockoutDuration is the first lock, it doubles with every wrong password
+	// after that up to LockoutMaxDuration.
+	LockoutDuration    time.Duration `yaml:"lockoutDuration"`
+	LockoutMaxDuration time.Duration `yaml:"lockoutMaxDuration"`
+}
+
+type MailConfig struct {
+	Driver  string     `yaml:"driver"`
+	From    string     `yaml:"from"`
+	LogFile string     `yaml:"logFile"`
+	Smtp    SmtpConfig `yaml:"smtp"`
+}
+
+type SmtpConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type LogConfig struct {
+	// Level is the lowest level that is written: debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Format is json, or text for reading the logs in a terminal.
+	Format string `yaml:"format"`
+}
+
+// Tracing exporters, see TracingConfig.Exporter.
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOtlp   = "otlp"
+)
+
+type TracingConfig struct {
+	// Exporter is none, stdout to print the spans, or otlp.
+	Exporter string `yaml:"exporter"`
+	// OtlpEndpoint is the URL of the OTLP/HTTP collector, like
+	// http://localhost:4318. Empty uses OTEL_EXPORTER_OTLP_ENDPOINT.
+	OtlpEndpoint string `yaml:"otlpEndpoint"`
+	// SampleRatio is the share of new traces that are recorded, from 0 to 1.
+	// A request that is part of a trace follows the decision of its caller.
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+type RateLimitConfig struct {
+	// LoginPerIp is how many logins a client IP can try per minute, 0
+	// disables the limit.
+	LoginPerIp int `yaml:"loginPerIp"`
+	// LoginPerAccount is how many logins can be tried per minute for one
+	// email, from any IP, 0 disables the limit.
+	LoginPerAccount int `yaml:"loginPerAccount"`
+}
+
+// Default returns the configuration used for every value that is not set.
+func Default() *Config {
+
+	return &Config{
+		App: AppConfig{
+			Name:            "project-app",
+			Port:            ":8080",
+			BodyLimit:       4 * 1024 * 1024,
+			ReadTimeout:     time.Second * 30,
+			WriteTimeout:    time.Second * 30,
+			RequestTimeout:  time.Second * 25,
+			ShutdownTimeout: time.Second * 30,
+		},
+		Database: DatabaseConfig{
+			MigrationsDir: "migration/sql",
+		},
+		Auth: AuthConfig{
+			EmailVerificationPolicy: EmailVerificationOff,
+			ResetPasswordUrl:        "http://localhost:3000/reset-password",
+			VerifyEmailUrl:          "http://localhost:8080/api/v1/user/verify",
+			LockoutThreshold:        5,
+			LockoutDuration:         time.Minute,
+			LockoutMaxDuration:      time.Hour,
+		},
+		Mail: MailConfig{
+			Driver: "log",
+			From:   "no-reply@project-app.local",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			LoginPerIp:      20,
+			LoginPerAccount: 10,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file in
+// CONFIG_FILE (config.yaml when it exists) and the environment, the latter
+// winning. Variables in .env are loaded into the environment first, without
+// replacing the ones that are already set. The result is not validated, the
+// server calls Validate before it starts.
+func Load() (*Config, error) {
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	cfg := Default()
+
+	path, required := os.LookupEnv("CONFIG_FILE")
+	if !required {
+		path = "config.yaml"
+	}
+
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(content, cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	case required || !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *Config) loadEnv() error {
+
+	setString(&cfg.App.Name, "APP_NAME")
+	setString(&cfg.App.Port, "APP_PORT")
+	setString(&cfg.App.ProxyHeader, "APP_PROXY_HEADER")
+	setList(&cfg.App.TrustedProxies, "APP_TRUSTED_PROXIES")
+	setString(&cfg.Database.Dsn, "APP_DSN")
+	setString(&cfg.Database.MigrationsDir, "MIGRATIONS_DIR")
+	// JWT_SECRECT_KEY is the old misspelled name, kept for existing .env files
+	setString(&cfg.Jwt.Secret, "JWT_SECRECT_KEY")
+	setString(&cfg.Jwt.Secret, "JWT_SECRET_KEY")
+	setString(&cfg.Auth.AdminEmail, "ADMIN_EMAIL")
+	setString(&cfg.Auth.EmailVerificationPolicy, "EMAIL_VERIFICATION_POLICY")
+	setString(&cfg.Auth.ResetPasswordUrl, "RESET_PASSWORD_URL")
+	setString(&cfg.Auth.VerifyEmailUrl, "VERIFY_EMAIL_URL")
+	setString(&cfg.Mail.Driver, "MAIL_DRIVER")
+	setString(&cfg.Mail.From, "MAIL_FROM")
+	setString(&cfg.Mail.LogFile, "MAIL_LOG_FILE")
+	setString(&cfg.Mail.Smtp.Host, "SMTP_HOST")
+	setString(&cfg.Mail.Smtp.Port, "SMTP_PORT")
+	setString(&cfg.Mail.Smtp.Username, "SMTP_USERNAME")
+	setString(&cfg.Mail.Smtp.Password, "SMTP_PASSWORD")
+	setString(&cfg.Log.Level, "LOG_LEVEL")
+	setString(&cfg.Log.Format, "LOG_FORMAT")
+	setString(&cfg.Tracing.Exporter, "TRACING_EXPORTER")
+	setString(&cfg.Tracing.OtlpEndpoint, "TRACING_OTLP_ENDPOINT")
+
+	return errors.Join(
+		setInt(&cfg.App.BodyLimit, "APP_BODY_LIMIT"),
+		setDuration(&cfg.App.ReadTimeout, "APP_READ_TIMEOUT"),
+		setDuration(&cfg.App.WriteTimeout, "APP_WRITE_TIMEOUT"),
+		setDuration(&cfg.App.RequestTimeout, "APP_REQUEST_TIMEOUT"),
+		setDuration(&cfg.App.ShutdownTimeout, "APP_SHUTDOWN_TIMEOUT"),
+		setFloat(&cfg.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO"),
+		setInt(&cfg.Auth.LockoutThreshold, "LOCKOUT_THRESHOLD"),
+		setDuration(&cfg.Auth.LockoutDuration, "LOCKOUT_DURATION"),
+		setDuration(&cfg.Auth.LockoutMaxDuration, "LOCKOUT_MAX_DURATION"),
+		setInt(&cfg.RateLimit.LoginPerIp, "RATE_LIMIT_LOGIN_PER_IP"),
+		setInt(&cfg.RateLimit.LoginPerAccount, "RATE_LIMIT_LOGIN_PER_ACCOUNT"),
+	)
+}
+
+// Validate reports every invalid value at once, so the app refuses to start
+// instead of failing on the first request that needs it.
+func (cfg *Config) Validate() error {
+
+	var errs []error
+
+	if cfg.App.Port == "" {
+		errs = append(errs, errors.New("app port is required (APP_PORT)"))
+	}
+
+	if cfg.App.BodyLimit <= 0 {
+		errs = append(errs, errors.New("app body limit must be positive (APP_BODY_LIMIT)"))
+	}
+
+	if cfg.App.RequestTimeout < 0 {
+		errs = append(errs, errors.New("app request timeout cannot be negative (APP_REQUEST_TIMEOUT)"))
+	}
+
+	if cfg.App.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("app shutdown timeout cannot be negative (APP_SHUTDOWN_TIMEOUT)"))
+	}
+
+	if cfg.App.ProxyHeader != "" && len(cfg.App.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("trusted proxies are required with a proxy header (APP_TRUSTED_PROXIES)"))
+	}
+
+	for _, proxy := range cfg.App.TrustedProxies {
+		_, _, errCidr := net.ParseCIDR(proxy)
+		if net.ParseIP(proxy) == nil && errCidr != nil {
+			errs = append(errs, fmt.Errorf("trusted proxy %q must be an IP or a CIDR range (APP_TRUSTED_PROXIES)", proxy))
+		}
+	}
+
+	if cfg.Database.Dsn == "" {
+		errs = append(errs, errors.New("database dsn is required (APP_DSN)"))
+	}
+
+	if cfg.Jwt.Secret == "" {
+		errs = append(errs, errors.New("jwt secret is required (JWT_SECRET_KEY)"))
+	}
+
+	switch cfg.Auth.EmailVerificationPolicy {
+	case EmailVerificationOff, EmailVerificationLogin, EmailVerificationWrite:
+	default:
+		errs = append(errs, fmt.Errorf("email verification policy %q must be off, login or write (EMAIL_VERIFICATION_POLICY)", cfg.Auth.EmailVerificationPolicy))
+	}
+
+	if cfg.Auth.LockoutThreshold < 0 {
+		errs = append(errs, errors.New("lockout threshold cannot be negative (LOCKOUT_THRESHOLD)"))
+	}
+
+	if cfg.Auth.LockoutThreshold > 0 && (cfg.Auth.LockoutDuration <= 0 || cfg.Auth.LockoutMaxDuration < cfg.Auth.LockoutDuration) {
+		errs = append(errs, errors.New("lockout duration must be positive and not above the max duration (LOCKOUT_DURATION, LOCKOUT_MAX_DURATION)"))
+	}
+
+	switch cfg.Mail.Driver {
+	case "log":
+	case "smtp":
+		if cfg.Mail.Smtp.Host == "" || cfg.Mail.Smtp.Port == "" {
+			errs = append(errs, errors.New("smtp host and port are required by the smtp mail driver (SMTP_HOST, SMTP_PORT)"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail driver %q must be log or smtp (MAIL_DRIVER)", cfg.Mail.Driver))
+	}
+
+	switch cfg.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log level %q must be debug, info, warn or error (LOG_LEVEL)", cfg.Log.Level))
+	}
+
+	switch cfg.Log.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log format %q must be json or text (LOG_FORMAT)", cfg.Log.Format))
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout, TracingExporterOtlp:
+	default:
+		errs = append(errs, fmt.Errorf("tracing exporter %q must be none, stdout or otlp (TRACING_EXPORTER)", cfg.Tracing.Exporter))
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing sample ratio must be between 0 and 1 (TRACING_SAMPLE_RATIO)"))
+	}
+
+	if cfg.RateLimit.LoginPerIp < 0 || cfg.RateLimit.LoginPerAccount < 0 {
+		errs = append(errs, errors.New("login rate limits cannot be negative (RATE_LIMIT_LOGIN_PER_IP, RATE_LIMIT_LOGIN_PER_ACCOUNT)"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func setString(target *string, key string) {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		*target = value
+	}
+}
+
+// setList splits a comma separated value.
+func setList(target *[]string, key string) {
+
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+
+	*target = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*target = append(*target, item)
+		}
+	}
+}
+
+func setInt(target *int, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
+
+func setFloat(target *float64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
+
+func setDuration(target *time.Duration, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppConfigShutdownGrace(t *testing.T) {
+
+	for _, timeout := range []time.Duration{time.Second, 30 * time.Second, time.Minute} {
+		grace := AppConfig{ShutdownTimeout: timeout}.ShutdownGrace()
+		if grace <= 0 || grace >= timeout {
+			t.Errorf("grace of %s = %s, want shorter than the timeout", timeout, grace)
+		}
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		proxyHeader    string
+		trustedProxies []string
+		wantErr        bool
+	}{
+		{name: "no proxy"},
+		{name: "ip and cidr", proxyHeader: "X-Real-IP", trustedProxies: []string{"10.0.0.1", "192.168.0.0/16"}},
+		{name: "header without proxies", proxyHeader: "X-Real-IP", wantErr: true},
+		{name: "invalid proxy", proxyHeader: "X-Real-IP", trustedProxies: []string{"proxy.local"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			cfg := Default()
+			cfg.Database.Dsn = "host=localhost"
+			cfg.Jwt.Secret = "secret"
+			cfg.App.ProxyHeader = test.proxyHeader
+			cfg.App.TrustedProxies = test.trustedProxies
+
+			if err := cfg.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("Validate() = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+
+	// The environment replaces the list of the file
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("app:\n  trustedProxies: [\"172.16.0.1\"]\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("APP_PROXY_HEADER", "X-Real-IP")
+	t.Setenv("APP_TRUSTED_PROXIES", "10.0.0.1, 192.168.0.0/16,")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.App.ProxyHeader != "X-Real-IP" || len(cfg.App.TrustedProxies) != 2 || cfg.App.TrustedProxies[1] != "192.168.0.0/16" {
+		t.Errorf("app config = %+v", cfg.App)
+	}
+}
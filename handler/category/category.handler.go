@@ -1,8 +1,11 @@
 package category
 
 import (
+	"project-app/apperror"
+	"project-app/helper"
 	"project-app/model"
-	categoryRepository "project-app/repository/category"
+	"project-app/pagination"
+	categoryService "project-app/service/category"
 
 	"strconv"
 
@@ -19,15 +22,12 @@ type CategoryHandler interface {
 }
 
 type CategoryHandlerImpl struct {
-	CategoryRepository categoryRepository.CategoryRepository
-	Validator          *validator.Validate
+	CategoryService categoryService.CategoryService
 }
 
 func NewCategoryHandler(db *gorm.DB, validate *validator.Validate) CategoryHandler {
-	categoryRepository := categoryRepository.NewCategoryRepository(db)
 	return &CategoryHandlerImpl{
-		CategoryRepository: categoryRepository,
-		Validator:          validate,
+		CategoryService: categoryService.NewCategoryService(db, validate),
 	}
 }
 
@@ -39,41 +39,24 @@ func NewCategoryHandler(db *gorm.DB, validate *validator.Validate) CategoryHandl
 // @Produce json
 // @Param body body model.CategoryCreateRequest true "Create category"
 // @Success 200 {object} map[string]interface{} "Success create category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /category [post]
 // @Security Bearer
 func (handler *CategoryHandlerImpl) Create(c *fiber.Ctx) error {
 
+	userId := helper.AuthUserId(c)
+
 	// Read body request
 	var request model.CategoryCreateRequest
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
-	}
-
-	// Validate incoming request
-	errValidate := handler.Validator.Struct(request)
-	if errValidate != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": errValidate.Error(),
-		})
+		return apperror.InvalidBody(err)
 	}
 
 	// Create Category
-	createRequest := model.Category{
-		Name: request.Name,
-	}
-
-	err := handler.CategoryRepository.Create(c, &createRequest)
+	_, err := handler.CategoryService.Create(c.UserContext(), userId, request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -90,51 +73,33 @@ func (handler *CategoryHandlerImpl) Create(c *fiber.Ctx) error {
 // @Produce json
 // @Param body body model.CategoryUpdateRequest true "Update category"
 // @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
-// @Router /category/{} [put]
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 404 {object} apperror.Response "Category not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Param id path string true "category id"
+// @Router /category/{id} [put]
 // @Security Bearer
 func (handler *CategoryHandlerImpl) Update(c *fiber.Ctx) error {
 
+	userId := helper.AuthUserId(c)
+
 	// Read body request
 	var request model.CategoryUpdateRequest
 	idString := c.Params("id", "")
 	idInt, errConv := strconv.Atoi(idString)
 
 	if errConv != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": errConv.Error(),
-		})
+		return apperror.Validation("Invalid category id")
 	}
 
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+		return apperror.InvalidBody(err)
 	}
 
-	// Validate incoming request
-	errValidate := handler.Validator.Struct(&request)
-	if errValidate != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": errValidate.Error(),
-		})
-	}
-
-	// Update request
-	updateRequest := &model.Category{
-		Name: request.Name,
-	}
-
-	errResult := handler.CategoryRepository.Update(c, idInt, updateRequest)
+	// Update the category of the user
+	errResult := handler.CategoryService.Update(c.UserContext(), userId, idInt, request)
 	if errResult != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": errResult.Error(),
-		})
+		return errResult
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -151,28 +116,25 @@ func (handler *CategoryHandlerImpl) Update(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "category id"
 // @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 404 {object} apperror.Response "Category not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /category/{id} [delete]
 // @Security Bearer
 func (handler *CategoryHandlerImpl) Delete(c *fiber.Ctx) error {
 
+	userId := helper.AuthUserId(c)
+
 	// Read body request
 	idString := c.Params("id")
 	idInt, err := strconv.Atoi(idString)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": err.Error(),
-		})
+		return apperror.Validation("Invalid category id")
 	}
 
-	errResult := handler.CategoryRepository.Delete(c, idInt)
+	errResult := handler.CategoryService.Delete(c.UserContext(), userId, idInt)
 	if errResult != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": errResult.Error(),
-		})
+		return errResult
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -183,45 +145,39 @@ func (handler *CategoryHandlerImpl) Delete(c *fiber.Ctx) error {
 
 // Get all category
 // @Summary Get all category
-// @Description Get all category
+// @Description Get the categories of the user by page, or after a cursor of an earlier page. The Link header has the next, prev, first and last pages.
 // @Tags Category
 // @Accept json
 // @Produce json
-// @Param page path string false "page"
-// @Param pageSize path string false "pageSize"
-// @Param categoryName path string false "categoryName"
-// @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Param page query int false "page, from 1"
+// @Param pageSize query int false "pageSize, at most 100"
+// @Param cursor query string false "nextCursor or prevCursor of an earlier page"
+// @Param categoryName query string false "categoryName"
+// @Success 200 {object} map[string]interface{} "Success get category"
+// @Failure 400 {object} apperror.Response "Invalid pagination"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /category/ [get]
 // @Security Bearer
 func (handler *CategoryHandlerImpl) FindAll(c *fiber.Ctx) error {
 
-	page := c.Query("page", "1")
-	pageInt, _ := strconv.Atoi(page)
-	pageSize := c.Query("pageSize", "10")
-	pageSizeInt, _ := strconv.Atoi(pageSize)
+	userId := helper.AuthUserId(c)
+
+	params, errParams := pagination.FromQuery(c)
+	if errParams != nil {
+		return errParams
+	}
 	categoryName := c.Query("categoryName", "")
 
-	category, totalEntries, errResult := handler.CategoryRepository.FindAll(c, pageInt, pageSizeInt, categoryName)
+	category, page, errResult := handler.CategoryService.FindAll(c.UserContext(), userId, params, categoryName)
 	if errResult != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": errResult.Error(),
-		})
+		return errResult
 	}
 
-	totalPages := int(totalEntries) / pageSizeInt
-	if int(totalEntries)%pageSizeInt > 0 { // Tambahkan satu halaman jika ada sisa pembagian
-		totalPages++
-	}
+	page.SetLinks(c)
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"code":       fiber.StatusOK,
-		"message":    "Successfully get category",
-		"page":       page,
-		"pageSize":   pageSize,
-		"totalPages": totalEntries,
-		"data":       category,
-	})
+	return c.Status(fiber.StatusOK).JSON(page.Fields(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get category",
+		"data":    category,
+	}))
 }
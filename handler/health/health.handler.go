@@ -0,0 +1,55 @@
+package health
+
+import (
+	"project-app/health"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The probes are served outside of /api/v1 and are not part of the API
+// documentation.
+type HealthHandler interface {
+	Liveness(c *fiber.Ctx) error
+	Readiness(c *fiber.Ctx) error
+}
+
+type HealthHandlerImpl struct {
+	Registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) HealthHandler {
+	return &HealthHandlerImpl{
+		Registry: registry,
+	}
+}
+
+// Liveness answers as long as the process can serve requests, it does not
+// check any dependency.
+func (handler *HealthHandlerImpl) Liveness(c *fiber.Ctx) error {
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "OK",
+	})
+}
+
+// Readiness runs the checks of the registry. It fails with 503 when a
+// required check is down, a down optional check is reported as degraded.
+func (handler *HealthHandlerImpl) Readiness(c *fiber.Ctx) error {
+
+	report := handler.Registry.Check(c.UserContext())
+
+	if !report.Ready() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"code":    fiber.StatusServiceUnavailable,
+			"message": "Not ready",
+			"data":    report,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Ready",
+		"data":    report,
+	})
+}
@@ -0,0 +1,215 @@
+package project
+
+import (
+	"project-app/apperror"
+	"project-app/helper"
+	"project-app/model"
+	"project-app/pagination"
+	projectService "project-app/service/project"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ProjectHandler interface {
+	Create(c *fiber.Ctx) error
+	Update(c *fiber.Ctx) error
+	Delete(c *fiber.Ctx) error
+	FindById(c *fiber.Ctx) error
+	FindAll(c *fiber.Ctx) error
+}
+
+type ProjectHandlerImpl struct {
+	ProjectService projectService.ProjectService
+}
+
+func NewProjectHandler(db *gorm.DB, validate *validator.Validate) ProjectHandler {
+	return &ProjectHandlerImpl{
+		ProjectService: projectService.NewProjectService(db, validate),
+	}
+}
+
+// Create project
+// @Summary Create project
+// @Description Create a new project
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param body body model.ProjectCreateRequest true "Create project"
+// @Success 200 {object} map[string]interface{} "Success create project"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project [post]
+// @Security Bearer
+func (handler *ProjectHandlerImpl) Create(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	// Read body request
+	var request model.ProjectCreateRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	// Create project in a category of the user
+	project, err := handler.ProjectService.Create(c.UserContext(), userId, request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully create project",
+		"data":    project,
+	})
+}
+
+// Update project
+// @Summary Update project
+// @Description Update project
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param id path string true "project id"
+// @Param body body model.ProjectUpdateRequest true "Update project"
+// @Success 200 {object} map[string]interface{} "Success update project"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 404 {object} apperror.Response "Project not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id} [put]
+// @Security Bearer
+func (handler *ProjectHandlerImpl) Update(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	// Read body request
+	var request model.ProjectUpdateRequest
+	idInt, errConv := strconv.Atoi(c.Params("id", ""))
+	if errConv != nil {
+		return apperror.Validation("Invalid project id")
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	// Update the project of the user
+	errResult := handler.ProjectService.Update(c.UserContext(), userId, idInt, request)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully update project",
+	})
+}
+
+// Delete project
+// @Summary Delete project
+// @Description Delete project
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param id path string true "project id"
+// @Success 200 {object} map[string]interface{} "Success delete project"
+// @Failure 400 {object} apperror.Response "Invalid project id"
+// @Failure 404 {object} apperror.Response "Project not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id} [delete]
+// @Security Bearer
+func (handler *ProjectHandlerImpl) Delete(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	idInt, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperror.Validation("Invalid project id")
+	}
+
+	errResult := handler.ProjectService.Delete(c.UserContext(), userId, idInt)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully delete project",
+	})
+}
+
+// Get project by id
+// @Summary Get project by id
+// @Description Get project by id, including its category and project items
+// @Tags Project
+// @Produce json
+// @Param id path string true "project id"
+// @Success 200 {object} map[string]interface{} "Success get project"
+// @Failure 400 {object} apperror.Response "Invalid project id"
+// @Failure 404 {object} apperror.Response "Project not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id} [get]
+// @Security Bearer
+func (handler *ProjectHandlerImpl) FindById(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	idInt, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperror.Validation("Invalid project id")
+	}
+
+	result, errResult := handler.ProjectService.FindById(c.UserContext(), userId, idInt)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get project",
+		"data":    result,
+	})
+}
+
+// Get all project
+// @Summary Get all project
+// @Description Get the projects of the user by page, or after a cursor of an earlier page. The Link header has the next, prev, first and last pages.
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param page query int false "page, from 1"
+// @Param pageSize query int false "pageSize, at most 100"
+// @Param cursor query string false "nextCursor or prevCursor of an earlier page"
+// @Param categoryId query string false "categoryId"
+// @Param projectName query string false "projectName"
+// @Success 200 {object} map[string]interface{} "Success get project"
+// @Failure 400 {object} apperror.Response "Invalid pagination"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/ [get]
+// @Security Bearer
+func (handler *ProjectHandlerImpl) FindAll(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	params, errParams := pagination.FromQuery(c)
+	if errParams != nil {
+		return errParams
+	}
+	categoryId := c.QueryInt("categoryId", 0)
+	projectName := c.Query("projectName", "")
+
+	projects, page, errResult := handler.ProjectService.FindAll(c.UserContext(), userId, params, categoryId, projectName)
+	if errResult != nil {
+		return errResult
+	}
+
+	page.SetLinks(c)
+
+	return c.Status(fiber.StatusOK).JSON(page.Fields(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get project",
+		"data":    projects,
+	}))
+}
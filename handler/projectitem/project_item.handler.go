@@ -0,0 +1,294 @@
+package projectitem
+
+import (
+	"project-app/apperror"
+	"project-app/helper"
+	"project-app/model"
+	"project-app/pagination"
+	projectService "project-app/service/project"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ProjectItemHandler interface {
+	Create(c *fiber.Ctx) error
+	Update(c *fiber.Ctx) error
+	ToggleStatus(c *fiber.Ctx) error
+	Reorder(c *fiber.Ctx) error
+	Delete(c *fiber.Ctx) error
+	FindAll(c *fiber.Ctx) error
+}
+
+type ProjectItemHandlerImpl struct {
+	ProjectService projectService.ProjectService
+}
+
+func NewProjectItemHandler(db *gorm.DB, validate *validator.Validate) ProjectItemHandler {
+	return &ProjectItemHandlerImpl{
+		ProjectService: projectService.NewProjectService(db, validate),
+	}
+}
+
+// Create project item
+// @Summary Create project item
+// @Description Add a new item to the end of a project
+// @Tags Project Item
+// @Accept json
+// @Produce json
+// @Param id path string true "project id"
+// @Param body body model.ProjectItemCreateRequest true "Create project item"
+// @Success 200 {object} map[string]interface{} "Success create project item"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 404 {object} apperror.Response "Project not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id}/items [post]
+// @Security Bearer
+func (handler *ProjectItemHandlerImpl) Create(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	// Read body request
+	projectId, errProject := projectIdParam(c)
+	if errProject != nil {
+		return errProject
+	}
+
+	var request model.ProjectItemCreateRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	// Add the item to the end of the project
+	item, err := handler.ProjectService.CreateItem(c.UserContext(), userId, projectId, request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully create project item",
+		"data":    item,
+	})
+}
+
+// Update project item
+// @Summary Update project item
+// @Description Update project item
+// @Tags Project Item
+// @Accept json
+// @Produce json
+// @Param id path string true "project id"
+// @Param itemId path string true "project item id"
+// @Param body body model.ProjectItemUpdateRequest true "Update project item"
+// @Success 200 {object} map[string]interface{} "Success update project item"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 404 {object} apperror.Response "Project or project item not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id}/items/{itemId} [put]
+// @Security Bearer
+func (handler *ProjectItemHandlerImpl) Update(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	// Read body request
+	projectId, itemId, errItem := itemIdParams(c)
+	if errItem != nil {
+		return errItem
+	}
+
+	var request model.ProjectItemUpdateRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	// Update the item of the project
+	errResult := handler.ProjectService.UpdateItem(c.UserContext(), userId, projectId, itemId, request)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully update project item",
+	})
+}
+
+// Toggle project item status
+// @Summary Toggle project item status
+// @Description Mark a project item as done, or as not done when it already is
+// @Tags Project Item
+// @Produce json
+// @Param id path string true "project id"
+// @Param itemId path string true "project item id"
+// @Success 200 {object} map[string]interface{} "Success toggle project item status"
+// @Failure 400 {object} apperror.Response "Invalid project or project item id"
+// @Failure 404 {object} apperror.Response "Project or project item not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id}/items/{itemId}/status [patch]
+// @Security Bearer
+func (handler *ProjectItemHandlerImpl) ToggleStatus(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	projectId, itemId, errItem := itemIdParams(c)
+	if errItem != nil {
+		return errItem
+	}
+
+	result, errResult := handler.ProjectService.ToggleItemStatus(c.UserContext(), userId, projectId, itemId)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully toggle project item status",
+		"data":    result,
+	})
+}
+
+// Reorder project items
+// @Summary Reorder project items
+// @Description Reorder project items, itemIds must contain every item of the project in the new order
+// @Tags Project Item
+// @Accept json
+// @Produce json
+// @Param id path string true "project id"
+// @Param body body model.ProjectItemReorderRequest true "Reorder project items"
+// @Success 200 {object} map[string]interface{} "Success reorder project items"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 404 {object} apperror.Response "Project not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id}/items/order [put]
+// @Security Bearer
+func (handler *ProjectItemHandlerImpl) Reorder(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	// Read body request
+	projectId, errProject := projectIdParam(c)
+	if errProject != nil {
+		return errProject
+	}
+
+	var request model.ProjectItemReorderRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	errResult := handler.ProjectService.ReorderItems(c.UserContext(), userId, projectId, request)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully reorder project items",
+	})
+}
+
+// Delete project item
+// @Summary Delete project item
+// @Description Delete project item
+// @Tags Project Item
+// @Produce json
+// @Param id path string true "project id"
+// @Param itemId path string true "project item id"
+// @Success 200 {object} map[string]interface{} "Success delete project item"
+// @Failure 400 {object} apperror.Response "Invalid project or project item id"
+// @Failure 404 {object} apperror.Response "Project or project item not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id}/items/{itemId} [delete]
+// @Security Bearer
+func (handler *ProjectItemHandlerImpl) Delete(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	projectId, itemId, errItem := itemIdParams(c)
+	if errItem != nil {
+		return errItem
+	}
+
+	errResult := handler.ProjectService.DeleteItem(c.UserContext(), userId, projectId, itemId)
+	if errResult != nil {
+		return errResult
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully delete project item",
+	})
+}
+
+// Get all project items
+// @Summary Get all project items
+// @Description Get the items of a project ordered by position, by page or after a cursor of an earlier page. The Link header has the next, prev, first and last pages.
+// @Tags Project Item
+// @Produce json
+// @Param id path string true "project id"
+// @Param page query int false "page, from 1"
+// @Param pageSize query int false "pageSize, at most 100"
+// @Param cursor query string false "nextCursor or prevCursor of an earlier page"
+// @Success 200 {object} map[string]interface{} "Success get project items"
+// @Failure 400 {object} apperror.Response "Invalid project id or pagination"
+// @Failure 404 {object} apperror.Response "Project not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /project/{id}/items [get]
+// @Security Bearer
+func (handler *ProjectItemHandlerImpl) FindAll(c *fiber.Ctx) error {
+
+	userId := helper.AuthUserId(c)
+
+	projectId, errProject := projectIdParam(c)
+	if errProject != nil {
+		return errProject
+	}
+
+	params, errParams := pagination.FromQuery(c)
+	if errParams != nil {
+		return errParams
+	}
+
+	result, page, errResult := handler.ProjectService.FindItems(c.UserContext(), userId, projectId, params)
+	if errResult != nil {
+		return errResult
+	}
+
+	page.SetLinks(c)
+
+	return c.Status(fiber.StatusOK).JSON(page.Fields(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get project items",
+		"data":    result,
+	}))
+}
+
+// projectIdParam reads the project id from the path.
+func projectIdParam(c *fiber.Ctx) (uint, error) {
+
+	projectId, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, apperror.Validation("Invalid project id")
+	}
+
+	return uint(projectId), nil
+}
+
+// itemIdParams is projectIdParam for routes that also address a single item.
+func itemIdParams(c *fiber.Ctx) (uint, uint, error) {
+
+	projectId, errProject := projectIdParam(c)
+	if errProject != nil {
+		return 0, 0, errProject
+	}
+
+	itemId, err := strconv.ParseUint(c.Params("itemId"), 10, 32)
+	if err != nil {
+		return 0, 0, apperror.Validation("Invalid project item id")
+	}
+
+	return projectId, uint(itemId), nil
+}
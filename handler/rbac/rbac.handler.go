@@ -0,0 +1,204 @@
+package rbac
+
+import (
+	"project-app/apperror"
+	"project-app/helper"
+	"project-app/model"
+	rbacRepository "project-app/repository/rbac"
+	userRepository "project-app/repository/users"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type RbacHandler interface {
+	FindAllRoles(c *fiber.Ctx) error
+	FindUserRoles(c *fiber.Ctx) error
+	AssignRole(c *fiber.Ctx) error
+	RevokeRole(c *fiber.Ctx) error
+}
+
+type RbacHandlerImpl struct {
+	RbacRepository  rbacRepository.RbacRepository
+	UsersRepository userRepository.UsersRepository
+	Validator       *validator.Validate
+}
+
+func NewRbacHandler(db *gorm.DB, validate *validator.Validate) RbacHandler {
+	rbacRepository := rbacRepository.NewRbacRepository(db)
+	usersRepository := userRepository.NewUsersRepository(db)
+	return &RbacHandlerImpl{
+		RbacRepository:  rbacRepository,
+		UsersRepository: usersRepository,
+		Validator:       validate,
+	}
+}
+
+// Get all roles
+// @Summary Get all roles
+// @Description Get all roles with their permissions
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Success get roles"
+// @Failure 403 {object} apperror.Response "Permission role:read required"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /admin/roles [get]
+// @Security Bearer
+func (handler *RbacHandlerImpl) FindAllRoles(c *fiber.Ctx) error {
+
+	result, err := handler.RbacRepository.FindAllRoles(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get roles",
+		"data":    result,
+	})
+}
+
+// Get user roles
+// @Summary Get user roles
+// @Description Get the roles assigned to a user
+// @Tags Admin
+// @Produce json
+// @Param user_id path string true "user id"
+// @Success 200 {object} map[string]interface{} "Success get user roles"
+// @Failure 400 {object} apperror.Response "Invalid user id"
+// @Failure 403 {object} apperror.Response "Permission role:read required"
+// @Failure 404 {object} apperror.Response "User not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /admin/users/{user_id}/roles [get]
+// @Security Bearer
+func (handler *RbacHandlerImpl) FindUserRoles(c *fiber.Ctx) error {
+
+	userId, errUser := handler.findUserId(c)
+	if errUser != nil {
+		return errUser
+	}
+
+	result, err := handler.RbacRepository.FindRolesByUserId(c.UserContext(), userId)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get user roles",
+		"data":    result,
+	})
+}
+
+// Assign role
+// @Summary Assign role
+// @Description Assign a role to a user
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user_id path string true "user id"
+// @Param body body model.AssignRoleRequest true "Assign role"
+// @Success 200 {object} map[string]interface{} "Success assign role"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 403 {object} apperror.Response "Permission role:write required"
+// @Failure 404 {object} apperror.Response "User or role not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /admin/users/{user_id}/roles [post]
+// @Security Bearer
+func (handler *RbacHandlerImpl) AssignRole(c *fiber.Ctx) error {
+
+	userId, errUser := handler.findUserId(c)
+	if errUser != nil {
+		return errUser
+	}
+
+	var request model.AssignRoleRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	errValidate := handler.Validator.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	_, errRole := handler.RbacRepository.FindRoleById(c.UserContext(), request.RoleId)
+	if errRole != nil {
+		return errRole
+	}
+
+	err := handler.RbacRepository.AssignRole(c.UserContext(), userId, request.RoleId)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully assign role",
+	})
+}
+
+// Revoke role
+// @Summary Revoke role
+// @Description Remove a role from a user. Admins cannot remove their own admin role.
+// @Tags Admin
+// @Produce json
+// @Param user_id path string true "user id"
+// @Param role_id path string true "role id"
+// @Success 200 {object} map[string]interface{} "Success revoke role"
+// @Failure 400 {object} apperror.Response "Invalid user or role id"
+// @Failure 403 {object} apperror.Response "Permission role:write required"
+// @Failure 404 {object} apperror.Response "User or role not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /admin/users/{user_id}/roles/{role_id} [delete]
+// @Security Bearer
+func (handler *RbacHandlerImpl) RevokeRole(c *fiber.Ctx) error {
+
+	userId, errUser := handler.findUserId(c)
+	if errUser != nil {
+		return errUser
+	}
+
+	roleId, errConv := strconv.ParseUint(c.Params("role_id"), 10, 32)
+	if errConv != nil {
+		return apperror.Validation("Invalid role id")
+	}
+
+	role, errRole := handler.RbacRepository.FindRoleById(c.UserContext(), uint(roleId))
+	if errRole != nil {
+		return errRole
+	}
+
+	// Keep at least the caller able to manage roles
+	if role.Name == model.RoleAdmin && userId == helper.AuthUserId(c) {
+		return apperror.Validation("You cannot revoke your own admin role")
+	}
+
+	err := handler.RbacRepository.RevokeRole(c.UserContext(), userId, uint(roleId))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully revoke role",
+	})
+}
+
+// findUserId reads the user id from the path and makes sure the user exists.
+func (handler *RbacHandlerImpl) findUserId(c *fiber.Ctx) (uint, error) {
+
+	userId, err := strconv.ParseUint(c.Params("user_id"), 10, 32)
+	if err != nil {
+		return 0, apperror.Validation("Invalid user id")
+	}
+
+	_, errUser := handler.UsersRepository.FindById(c.UserContext(), uint(userId))
+	if errUser != nil {
+		return 0, errUser
+	}
+
+	return uint(userId), nil
+}
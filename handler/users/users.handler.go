@@ -1,9 +1,12 @@
 package users
 
 import (
-	"fmt"
+	"project-app/apperror"
+	"project-app/config"
 	"project-app/helper"
-	userRepository "project-app/repository/users"
+	"project-app/mailer"
+	"project-app/pagination"
+	usersService "project-app/service/users"
 	"strconv"
 
 	"project-app/model"
@@ -16,21 +19,25 @@ import (
 type UsersHandler interface {
 	Login(c *fiber.Ctx) error
 	Register(c *fiber.Ctx) error
+	RefreshToken(c *fiber.Ctx) error
+	Logout(c *fiber.Ctx) error
+	ForgotPassword(c *fiber.Ctx) error
+	VerifyEmail(c *fiber.Ctx) error
+	ResendVerification(c *fiber.Ctx) error
+	ResetPassword(c *fiber.Ctx) error
 	FindUserProfileById(c *fiber.Ctx) error
 	UpdateProfileById(c *fiber.Ctx) error
 	GetProfileById(c *fiber.Ctx) error
+	FindFollowers(c *fiber.Ctx) error
 }
 
 type UsersHandlerImpl struct {
-	UsersRepository userRepository.UsersRepository
-	Validate        *validator.Validate
+	UsersService usersService.UsersService
 }
 
-func NewUsersHandler(db *gorm.DB, validate *validator.Validate) UsersHandler {
-	user := userRepository.NewUsersRepository(db)
+func NewUsersHandler(db *gorm.DB, validate *validator.Validate, cfg *config.Config, mail mailer.Mailer) UsersHandler {
 	return &UsersHandlerImpl{
-		UsersRepository: user,
-		Validate:        validate,
+		UsersService: usersService.NewUsersService(db, validate, cfg, mail),
 	}
 }
 
@@ -42,63 +49,32 @@ func NewUsersHandler(db *gorm.DB, validate *validator.Validate) UsersHandler {
 // @Produce json
 // @Param body body model.LoginRequest true "Login"
 // @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 401 {object} apperror.Response "Wrong email or password"
+// @Failure 403 {object} apperror.Response "Email not verified"
+// @Failure 429 {object} apperror.Response "Too many logins or account locked, see Retry-After"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /user/login [post]
 func (handler *UsersHandlerImpl) Login(c *fiber.Ctx) error {
 
 	// Read body request
 	var request model.LoginRequest
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+		return apperror.InvalidBody(err)
 	}
 
-	// Validate incoming request
-	errValidate := handler.Validate.Struct(request)
-	if errValidate != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": errValidate.Error(),
-		})
-	}
-
-	// Get user by email
-	userResult, errUserFindByEmail := handler.UsersRepository.FindByEmail(c, request.Email)
-	if errUserFindByEmail != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": errUserFindByEmail.Error(),
-		})
-	}
-
-	fmt.Println(userResult)
-
-	// compare password from body request and from database
-	errComparePassword := helper.CheckPasswordHash(request.Password, userResult.Password)
-	if !errComparePassword {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": "Wrong password!",
-		})
-	}
-
-	// generate jwt token
-	token, errGenerateToken := helper.GenerateToken(userResult.ID)
-	if errGenerateToken != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": errGenerateToken.Error(),
-		})
+	user, tokens, err := handler.UsersService.Login(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"code":     fiber.StatusOK,
-		"message":  "Login successfully",
-		"token":    token,
-		"username": userResult.Username,
+		"code":         fiber.StatusOK,
+		"message":      "Login successfully",
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    int(tokens.ExpiresIn.Seconds()),
+		"username":     user.Username,
 	})
 }
 
@@ -110,85 +86,203 @@ func (handler *UsersHandlerImpl) Login(c *fiber.Ctx) error {
 // @Produce json
 // @Param body body model.RegisterRequest true "Login"
 // @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 409 {object} apperror.Response "User already exist"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /user/register [post]
 func (handler *UsersHandlerImpl) Register(c *fiber.Ctx) error {
 
 	// 1. Parser body request
 	var request model.RegisterRequest
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+		return apperror.InvalidBody(err)
 	}
 
-	// 2. Validasi json yang dikirim
-	errValidate := handler.Validate.Struct(request)
-	if errValidate != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": errValidate.Error(),
-		})
+	// 2. Register the user, with a profile, the default role and a verification email
+	_, err := handler.UsersService.Register(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
 
-	// 3. Cek apakah user dengan email yang dikirim sudah ada di database
-	result, err := handler.UsersRepository.FindByEmail(c, request.Email)
-	if result.Email == request.Email {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": "User already exist",
-		})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully register user",
+	})
+}
+
+// Refresh token
+// @Summary Refresh token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Using a refresh token twice revokes every token of its login.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body model.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{} "Success refresh token"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 401 {object} apperror.Response "Refresh token invalid, expired or reused"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/token/refresh [post]
+func (handler *UsersHandlerImpl) RefreshToken(c *fiber.Ctx) error {
+
+	// 1. Parser body request
+	var request model.RefreshTokenRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	// 2. Rotate the refresh token
+	tokens, err := handler.UsersService.RefreshToken(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
 
-	// 4. Hash user password
-	hashResult, errHash := helper.HashPassword(request.Password)
-	if errHash != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": "Error hashing password",
-		})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":         fiber.StatusOK,
+		"message":      "Successfully refresh token",
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    int(tokens.ExpiresIn.Seconds()),
+	})
+}
+
+// Logout user
+// @Summary Logout user
+// @Description Revoke the refresh token and every token rotated from the same login
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body model.LogoutRequest true "Logout"
+// @Success 200 {object} map[string]interface{} "Success logout"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 401 {object} apperror.Response "Refresh token invalid"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/logout [post]
+func (handler *UsersHandlerImpl) Logout(c *fiber.Ctx) error {
+
+	var request model.LogoutRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	err := handler.UsersService.Logout(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Logout successfully",
+	})
+}
+
+// Forgot password
+// @Summary Forgot password
+// @Description Send a password reset link to the email. The response is the same whether the email is registered or not.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body model.ForgotPasswordRequest true "Forgot password"
+// @Success 200 {object} map[string]interface{} "Reset link sent when the email is registered"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/password/forgot [post]
+func (handler *UsersHandlerImpl) ForgotPassword(c *fiber.Ctx) error {
+
+	var request model.ForgotPasswordRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	err := handler.UsersService.ForgotPassword(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
-	request.Password = hashResult
 
-	// 5. Save user to database
-	req := model.User{
-		Username: request.Username,
-		Email:    request.Email,
-		Password: request.Password,
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "If the email is registered a reset link has been sent",
+	})
+}
+
+// Reset password
+// @Summary Reset password
+// @Description Set a new password with the token from the reset link. The token can only be used once, and every refresh token of the user is revoked.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body model.ResetPasswordRequest true "Reset password"
+// @Success 200 {object} map[string]interface{} "Success reset password"
+// @Failure 400 {object} apperror.Response "Invalid, expired or used token, or invalid request body"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/password/reset [post]
+func (handler *UsersHandlerImpl) ResetPassword(c *fiber.Ctx) error {
+
+	var request model.ResetPasswordRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
 	}
 
-	userId, errRegister := handler.UsersRepository.Register(c, &req)
-	if errRegister != nil && userId == nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+	err := handler.UsersService.ResetPassword(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
 
-	defaultDataToProfile := model.ProfileCreateRequest{
-		UserId:    req.ID,
-		Bio:       "",
-		Role:      "",
-		Facebook:  "",
-		Instagram: "",
-		Linkedin:  "",
-		Twitter:   "",
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully reset password",
+	})
+}
+
+// Verify email
+// @Summary Verify email
+// @Description Verify the email of the user with the token from the verification link
+// @Tags Users
+// @Produce json
+// @Param token query string true "verification token"
+// @Success 200 {object} map[string]interface{} "Success verify email"
+// @Failure 400 {object} apperror.Response "Verification token invalid or expired"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/verify [get]
+func (handler *UsersHandlerImpl) VerifyEmail(c *fiber.Ctx) error {
+
+	err := handler.UsersService.VerifyEmail(c.UserContext(), c.Query("token"))
+	if err != nil {
+		return err
 	}
 
-	errProfile := handler.UsersRepository.CreatUserProfileById(c, &defaultDataToProfile)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully verify email",
+	})
+}
 
-	if errProfile != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": errProfile.Error(),
-		})
+// Resend verification email
+// @Summary Resend verification email
+// @Description Send a new verification link. A new link can be asked once a minute.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body model.ResendVerificationRequest true "Resend verification email"
+// @Success 200 {object} map[string]interface{} "Verification link sent when the email is registered and not verified"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 429 {object} apperror.Response "Verification link sent too recently"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/verify/resend [post]
+func (handler *UsersHandlerImpl) ResendVerification(c *fiber.Ctx) error {
+
+	var request model.ResendVerificationRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.InvalidBody(err)
+	}
+
+	err := handler.UsersService.ResendVerification(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"code":    fiber.StatusOK,
-		"message": "Successfully register user",
+		"message": "If the email is registered and not verified a verification link has been sent",
 	})
 }
 
@@ -200,8 +294,8 @@ func (handler *UsersHandlerImpl) Register(c *fiber.Ctx) error {
 // @Produce json
 // @Param user_id path string true "user_id"
 // @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /user/following [get]
 func (handler *UsersHandlerImpl) FindUserProfileById(c *fiber.Ctx) error {
 
@@ -220,37 +314,21 @@ func (handler *UsersHandlerImpl) FindUserProfileById(c *fiber.Ctx) error {
 // @Produce json
 // @Param body body model.ProfileUpdateRequestBody true "Update profile"
 // @Success 200 {object} map[string]interface{} "Success update category"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 500 {object} apperror.Response "Internal server error"
 // @Router /user/profile [put]
 func (handler *UsersHandlerImpl) UpdateProfileById(c *fiber.Ctx) error {
 
 	var request model.ProfileUpdateRequestBody
-	userId := helper.UserId
+	userId := helper.AuthUserId(c)
 
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
-	}
-
-	reqData := model.ProfileUpdateRequest{
-		Bio:       request.Bio,
-		Role:      request.Role,
-		Facebook:  request.Facebook,
-		Instagram: request.Instagram,
-		Linkedin:  request.LinkedIn,
-		Twitter:   request.Twitter,
+		return apperror.InvalidBody(err)
 	}
 
-	err := handler.UsersRepository.UpdateProfileById(c, userId, reqData)
-
+	err := handler.UsersService.UpdateProfile(c.UserContext(), userId, request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -265,35 +343,26 @@ func (handler *UsersHandlerImpl) UpdateProfileById(c *fiber.Ctx) error {
 // @Tags Users
 // @Produce json
 // @Security Bearer
-// @Param userId path string true "userId"
+// @Param user_id path string true "user_id"
 // @Success 200 {object} map[string]interface{} "Success get profile by id"
-// @Failure 400 {object} map[string]interface{} "Invalid request body or missing required fields"
-// @Failure 500 {object} map[string]interface{} "Internal server error"
-// @Router /user/profile/:userId [get]
+// @Failure 400 {object} apperror.Response "Invalid request body or missing required fields"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/profile/{user_id} [get]
 func (handler *UsersHandlerImpl) GetProfileById(c *fiber.Ctx) error {
-	userId := c.Params("userId", "")
+	userId := c.Params("user_id", "")
 
 	if userId == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": "Invalid user id",
-		})
+		return apperror.Validation("Invalid user id")
 	}
 
 	userIdVal, err := strconv.ParseUint(userId, 10, 32) // basis 10, 32-bit
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"code":    fiber.StatusBadRequest,
-			"message": "Invalid user id",
-		})
+		return apperror.Validation("Invalid user id")
 	}
 
-	result, err := handler.UsersRepository.GetProfileById(c, uint(userIdVal))
+	result, err := handler.UsersService.FindProfile(c.UserContext(), uint(userIdVal))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -302,3 +371,45 @@ func (handler *UsersHandlerImpl) GetProfileById(c *fiber.Ctx) error {
 		"data":    result,
 	})
 }
+
+// Find followers
+// @Summary Find followers
+// @Description Get the users following a user by page, or after a cursor of an earlier page. The Link header has the next, prev, first and last pages.
+// @Tags Users
+// @Produce json
+// @Security Bearer
+// @Param user_id path string true "user_id"
+// @Param page query int false "page, from 1"
+// @Param pageSize query int false "pageSize, at most 100"
+// @Param cursor query string false "nextCursor or prevCursor of an earlier page"
+// @Param username query string false "username"
+// @Success 200 {object} map[string]interface{} "Success get followers"
+// @Failure 400 {object} apperror.Response "Invalid user id or pagination"
+// @Failure 404 {object} apperror.Response "User not found"
+// @Failure 500 {object} apperror.Response "Internal server error"
+// @Router /user/{user_id}/followers [get]
+func (handler *UsersHandlerImpl) FindFollowers(c *fiber.Ctx) error {
+
+	userId, err := strconv.ParseUint(c.Params("user_id"), 10, 32)
+	if err != nil {
+		return apperror.Validation("Invalid user id")
+	}
+
+	params, errParams := pagination.FromQuery(c)
+	if errParams != nil {
+		return errParams
+	}
+
+	followers, page, errResult := handler.UsersService.FindFollowers(c.UserContext(), uint(userId), params, c.Query("username"))
+	if errResult != nil {
+		return errResult
+	}
+
+	page.SetLinks(c)
+
+	return c.Status(fiber.StatusOK).JSON(page.Fields(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "Successfully get followers",
+		"data":    followers,
+	}))
+}
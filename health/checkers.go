@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"net"
+
+	"project-app/migration"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the database through the connection pool of gorm.
+func Database(db *gorm.DB) Checker {
+
+	return NewChecker("database", func(ctx context.Context) error {
+		sqlDb, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		return sqlDb.PingContext(ctx)
+	})
+}
+
+// Migrations is down while a migration of this build is not applied. The
+// check only reads schema_migrations.
+func Migrations(db *gorm.DB) Checker {
+
+	migrator, err := migration.NewMigrator(db)
+
+	return NewChecker("migrations", func(ctx context.Context) error {
+		if err != nil {
+			return err
+		}
+
+		probe := *migrator
+		probe.Db = db.WithContext(ctx)
+
+		return probe.EnsureUpToDate()
+	})
+}
+
+// Dial checks that a TCP connection to address can be opened.
+func Dial(name string, address string) Checker {
+
+	return NewChecker(name, func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	})
+}
@@ -0,0 +1,201 @@
+// Package health checks the dependencies of the app for the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"project-app/logger"
+)
+
+// DefaultTimeout is how long a single check can take.
+const DefaultTimeout = 2 * time.Second
+
+type Status string
+
+const (
+	StatusUp Status = "up"
+	// StatusDegraded is only reported for the whole app, when an optional
+	// check is down.
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// The error of a down check is only logged, the probe is not authenticated
+// and answers with one of these.
+const (
+	ErrorTimeout     = "timeout"
+	ErrorUnavailable = "unavailable"
+)
+
+// Checker checks one dependency. Check returns nil when it is usable.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (checker checkerFunc) Name() string {
+	return checker.name
+}
+
+func (checker checkerFunc) Check(ctx context.Context) error {
+	return checker.check(ctx)
+}
+
+// NewChecker makes a Checker from a function.
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, check: check}
+}
+
+type Result struct {
+	Name       string  `json:"name"`
+	Status     Status  `json:"status"`
+	Optional   bool    `json:"optional,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     Status   `json:"status"`
+	DurationMs float64  `json:"durationMs"`
+	Checks     []Result `json:"checks"`
+}
+
+// Ready reports whether every required check is up.
+func (report Report) Ready() bool {
+	return report.Status != StatusDown
+}
+
+type registered struct {
+	checker  Checker
+	optional bool
+}
+
+// Registry holds the checks of the readiness probe. Subsystems register
+// their checks when they are set up.
+type Registry struct {
+	mu       sync.Mutex
+	checkers []registered
+	Timeout  time.Duration
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		Timeout: timeout,
+	}
+}
+
+// Register adds a check the app cannot run without.
+func (registry *Registry) Register(checker Checker) {
+	registry.add(registered{checker: checker})
+}
+
+// RegisterOptional adds a check that only degrades the app when it is down,
+// for example a mail server.
+func (registry *Registry) RegisterOptional(checker Checker) {
+	registry.add(registered{checker: checker, optional: true})
+}
+
+func (registry *Registry) add(check registered) {
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.checkers = append(registry.checkers, check)
+}
+
+// Check runs every check at the same time, each with the timeout of the
+// registry, and reports them in the order they were registered.
+func (registry *Registry) Check(ctx context.Context) Report {
+
+	registry.mu.Lock()
+	checkers := append([]registered(nil), registry.checkers...)
+	registry.mu.Unlock()
+
+	start := time.Now()
+	results := make([]Result, len(checkers))
+
+	var wg sync.WaitGroup
+	for i, check := range checkers {
+		wg.Add(1)
+		go func(i int, check registered) {
+			defer wg.Done()
+			results[i] = registry.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:     StatusUp,
+		DurationMs: milliseconds(time.Since(start)),
+		Checks:     results,
+	}
+
+	for _, result := range results {
+		if result.Status == StatusUp {
+			continue
+		}
+
+		if !result.Optional {
+			report.Status = StatusDown
+			break
+		}
+		report.Status = StatusDegraded
+	}
+
+	return report
+}
+
+func (registry *Registry) run(ctx context.Context, check registered) Result {
+
+	if registry.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, registry.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+
+	// A check that ignores ctx cannot hold up the probe
+	done := make(chan error, 1)
+	go func() {
+		done <- check.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Name:       check.checker.Name(),
+		Status:     StatusUp,
+		Optional:   check.optional,
+		DurationMs: milliseconds(time.Since(start)),
+	}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = ErrorUnavailable
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = ErrorTimeout
+		}
+
+		logger.FromContext(ctx).Warn("health check failed", "check", result.Name, "optional", result.Optional, "error", err)
+	}
+
+	return result
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func up(name string) Checker {
+	return NewChecker(name, func(context.Context) error { return nil })
+}
+
+func down(name string) Checker {
+	return NewChecker(name, func(context.Context) error { return errors.New("connection refused") })
+}
+
+func TestRegistryStatus(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		required []Checker
+		optional []Checker
+		want     Status
+		ready    bool
+	}{
+		{"no checks", nil, nil, StatusUp, true},
+		{"all up", []Checker{up("database")}, []Checker{up("smtp")}, StatusUp, true},
+		{"optional down", []Checker{up("database")}, []Checker{down("smtp")}, StatusDegraded, true},
+		{"required down", []Checker{down("database")}, []Checker{up("smtp")}, StatusDown, false},
+		{"both down", []Checker{down("database")}, []Checker{down("smtp")}, StatusDown, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := NewRegistry(DefaultTimeout)
+			for _, checker := range test.required {
+				registry.Register(checker)
+			}
+			for _, checker := range test.optional {
+				registry.RegisterOptional(checker)
+			}
+
+			report := registry.Check(context.Background())
+			if report.Status != test.want {
+				t.Errorf("Status = %q, want %q", report.Status, test.want)
+			}
+			if report.Ready() != test.ready {
+				t.Errorf("Ready() = %v, want %v", report.Ready(), test.ready)
+			}
+			if len(report.Checks) != len(test.required)+len(test.optional) {
+				t.Errorf("got %d results, want %d", len(report.Checks), len(test.required)+len(test.optional))
+			}
+		})
+	}
+}
+
+func TestRegistryResults(t *testing.T) {
+
+	registry := NewRegistry(DefaultTimeout)
+	registry.Register(up("database"))
+	registry.RegisterOptional(down("smtp"))
+
+	report := registry.Check(context.Background())
+
+	// The results keep the order of registration
+	database, smtp := report.Checks[0], report.Checks[1]
+	if database.Name != "database" || database.Status != StatusUp || database.Error != "" {
+		t.Errorf("database = %+v", database)
+	}
+	if smtp.Name != "smtp" || smtp.Status != StatusDown || !smtp.Optional || smtp.Error != ErrorUnavailable {
+		t.Errorf("smtp = %+v", smtp)
+	}
+}
+
+func TestRegistryTimeout(t *testing.T) {
+
+	registry := NewRegistry(20 * time.Millisecond)
+
+	// A check that ignores its context is still cut off
+	registry.Register(NewChecker("stuck", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Check took %v, want it cut off by the timeout", elapsed)
+	}
+	if report.Status != StatusDown {
+		t.Errorf("Status = %q, want %q", report.Status, StatusDown)
+	}
+	if report.Checks[0].Error != ErrorTimeout {
+		t.Errorf("Error = %q, want %q", report.Checks[0].Error, ErrorTimeout)
+	}
+}
@@ -0,0 +1,34 @@
+package helper
+
+import "github.com/gofiber/fiber/v2"
+
+// AuthPrincipal is the identity of the user that sent the request. It is
+// stored in the request locals by VerifyToken.
+type AuthPrincipal struct {
+	UserID  uint
+	Roles   []string
+	TokenID string
+}
+
+type authPrincipalKey struct{}
+
+func SetAuthPrincipal(c *fiber.Ctx, principal *AuthPrincipal) {
+	c.Locals(authPrincipalKey{}, principal)
+}
+
+// GetAuthPrincipal returns the principal of the request, ok is false when the
+// route is not behind VerifyToken.
+func GetAuthPrincipal(c *fiber.Ctx) (*AuthPrincipal, bool) {
+	principal, ok := c.Locals(authPrincipalKey{}).(*AuthPrincipal)
+	return principal, ok && principal != nil
+}
+
+// AuthUserId returns the id of the authenticated user, or 0 when there is none.
+func AuthUserId(c *fiber.Ctx) uint {
+	principal, ok := GetAuthPrincipal(c)
+	if !ok {
+		return 0
+	}
+
+	return principal.UserID
+}
@@ -0,0 +1,94 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestVerifyTokenConcurrentUsers sends requests as different users at the same
+// time and checks that every handler only sees its own user. Run it with -race.
+func TestVerifyTokenConcurrentUsers(t *testing.T) {
+	app := fiber.New()
+	app.Get("/whoami", VerifyToken, func(c *fiber.Ctx) error {
+		principal, ok := GetAuthPrincipal(c)
+		if !ok {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		return c.SendString(strconv.FormatUint(uint64(principal.UserID), 10))
+	})
+
+	const users = 20
+	const requestsPerUser = 10
+
+	tokens := make([]string, users+1)
+	for userId := 1; userId <= users; userId++ {
+		token, err := GenerateToken(uint(userId), []string{"user"})
+		if err != nil {
+			t.Fatalf("generate token: %v", err)
+		}
+		tokens[userId] = token
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, users*requestsPerUser)
+
+	for userId := 1; userId <= users; userId++ {
+		for i := 0; i < requestsPerUser; i++ {
+			wg.Add(1)
+			go func(userId int) {
+				defer wg.Done()
+
+				req := httptest.NewRequest(fiber.MethodGet, "/whoami", nil)
+				req.Header.Set("Authorization", "Bearer "+tokens[userId])
+
+				res, err := app.Test(req, -1)
+				if err != nil {
+					errs <- err
+					return
+				}
+				defer res.Body.Close()
+
+				body, _ := io.ReadAll(res.Body)
+				if res.StatusCode != fiber.StatusOK || string(body) != strconv.Itoa(userId) {
+					errs <- fmt.Errorf("user %d got status %d body %q", userId, res.StatusCode, body)
+				}
+			}(userId)
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestGetAuthPrincipalWithoutToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if _, ok := GetAuthPrincipal(c); ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendString(strconv.FormatUint(uint64(AuthUserId(c)), 10))
+	})
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != fiber.StatusOK || string(body) != "0" {
+		t.Fatalf("got status %d body %q, want 200 \"0\"", res.StatusCode, body)
+	}
+}
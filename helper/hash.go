@@ -2,8 +2,12 @@ package helper
 
 import "golang.org/x/crypto/bcrypt"
 
+// PasswordHashCost is the bcrypt cost of new password hashes. Tests lower it
+// to bcrypt.MinCost, a hash of cost 14 takes about a second.
+var PasswordHashCost = 14
+
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordHashCost)
 	return string(bytes), err
 }
 
@@ -1,22 +1,42 @@
 package helper
 
 import (
-	"os"
+	"project-app/apperror"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var secretKey = []byte(os.Getenv("JWT_SECRECT_KEY"))
-var UserId uint
+var secretKey []byte
 
-func GenerateToken(userId uint) (string, error) {
+// SetJwtSecret sets the key that signs and verifies the tokens. It is called
+// once on startup, before the app serves requests.
+func SetJwtSecret(secret string) {
+	secretKey = []byte(secret)
+}
+
+const (
+	AccessTokenTTL  = time.Minute * 15
+	RefreshTokenTTL = time.Hour * 24 * 30
+
+	PasswordResetTokenTTL = time.Hour
+)
+
+func GenerateToken(userId uint, roles []string) (string, error) {
+
+	if roles == nil {
+		roles = []string{}
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
 		jwt.MapClaims{
 			"userId": userId,
-			"exp":    time.Now().Add(time.Hour * 24).Unix(),
+			"roles":  roles,
+			"jti":    uuid.NewString(),
+			"exp":    time.Now().Add(AccessTokenTTL).Unix(),
 		})
 
 	tokenString, err := token.SignedString(secretKey)
@@ -28,43 +48,49 @@ func GenerateToken(userId uint) (string, error) {
 }
 
 func VerifyToken(c *fiber.Ctx) error {
-	tokenHeader := c.Get("Authorization", "")
+	tokenHeader := strings.TrimPrefix(c.Get("Authorization", ""), "Bearer ")
 
 	if tokenHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"code":    fiber.StatusUnauthorized,
-			"message": "Token not provided",
-		})
+		return apperror.Unauthorized("Token not provided")
 	}
 
 	claims := jwt.MapClaims{}
 
+	// Only HS256, a token must not pick the algorithm it is checked with
 	token, err := jwt.ParseWithClaims(tokenHeader, claims, func(token *jwt.Token) (interface{}, error) {
 		return secretKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"code":    fiber.StatusInternalServerError,
-			"message": "Failed to parse token",
-		})
+		return apperror.Unauthorized("Token invalid or expired")
 	}
 
 	if !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"code":    fiber.StatusUnauthorized,
-			"message": "Token invalid",
-		})
+		return apperror.Unauthorized("Token invalid")
 	}
 
-	if val, ok := claims["userId"].(float64); ok {
-		UserId = uint(val)
-	} else {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"code":    fiber.StatusUnauthorized,
-			"message": "User id not found in token",
-		})
+	userId, ok := claims["userId"].(float64)
+	if !ok {
+		return apperror.Unauthorized("User id not found in token")
 	}
 
+	principal := &AuthPrincipal{
+		UserID: uint(userId),
+	}
+
+	if tokenId, ok := claims["jti"].(string); ok {
+		principal.TokenID = tokenId
+	}
+
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, name)
+			}
+		}
+	}
+
+	SetAuthPrincipal(c, principal)
+
 	return c.Next()
 }
@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyTokenSigningMethod(t *testing.T) {
+
+	SetJwtSecret("test")
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler})
+	app.Get("/", VerifyToken, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	sign := func(method jwt.SigningMethod, key interface{}, exp time.Time) string {
+		token, err := jwt.NewWithClaims(method, jwt.MapClaims{
+			"userId": 1,
+			"roles":  []string{"user"},
+			"exp":    exp.Unix(),
+		}).SignedString(key)
+		if err != nil {
+			t.Fatalf("sign %s: %v", method.Alg(), err)
+		}
+		return token
+	}
+
+	valid, err := GenerateToken(1, []string{"user"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "HS256", token: valid, wantStatus: fiber.StatusOK},
+		{name: "HS512 with the same secret", token: sign(jwt.SigningMethodHS512, []byte("test"), time.Now().Add(time.Minute)), wantStatus: fiber.StatusUnauthorized},
+		{name: "none", token: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, time.Now().Add(time.Minute)), wantStatus: fiber.StatusUnauthorized},
+		{name: "expired", token: sign(jwt.SigningMethodHS256, []byte("test"), time.Now().Add(-time.Minute)), wantStatus: fiber.StatusUnauthorized},
+		{name: "other secret", token: sign(jwt.SigningMethodHS256, []byte("other"), time.Now().Add(time.Minute)), wantStatus: fiber.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+test.token)
+
+			res, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != test.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, test.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	EmailVerificationTTL         = time.Hour * 24
+	EmailVerificationResendDelay = time.Minute
+)
+
+const emailVerificationPurpose = "verify_email"
+
+var ErrInvalidVerificationToken = errors.New("verification token invalid or expired")
+
+// GenerateEmailVerificationToken signs the user id and email for the link of
+// the verification email. It has no userId claim, so VerifyToken never
+// accepts it as an access token.
+func GenerateEmailVerificationToken(userId uint, email string) (string, error) {
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
+		jwt.MapClaims{
+			"sub":     strconv.FormatUint(uint64(userId), 10),
+			"email":   email,
+			"purpose": emailVerificationPurpose,
+			"exp":     time.Now().Add(EmailVerificationTTL).Unix(),
+		})
+
+	return token.SignedString(secretKey)
+}
+
+// ParseEmailVerificationToken returns the user id and email signed in token.
+func ParseEmailVerificationToken(tokenString string) (uint, string, error) {
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secretKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil || !token.Valid || claims["purpose"] != emailVerificationPurpose {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	subject, _ := claims["sub"].(string)
+	userId, errConv := strconv.ParseUint(subject, 10, 32)
+	email, ok := claims["email"].(string)
+	if errConv != nil || !ok {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	return uint(userId), email, nil
+}
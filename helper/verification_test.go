@@ -0,0 +1,72 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseEmailVerificationToken(t *testing.T) {
+
+	SetJwtSecret("test")
+
+	valid, err := GenerateEmailVerificationToken(7, "ana@example.com")
+	if err != nil {
+		t.Fatalf("GenerateEmailVerificationToken: %v", err)
+	}
+
+	accessToken, err := GenerateToken(7, []string{"user"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, key interface{}, exp time.Time) string {
+		token, err := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub":     "7",
+			"email":   "ana@example.com",
+			"purpose": emailVerificationPurpose,
+			"exp":     exp.Unix(),
+		}).SignedString(key)
+		if err != nil {
+			t.Fatalf("sign %s: %v", method.Alg(), err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: valid},
+		{name: "expired", token: sign(jwt.SigningMethodHS256, []byte("test"), time.Now().Add(-time.Minute)), wantErr: true},
+		{name: "HS512 with the same secret", token: sign(jwt.SigningMethodHS512, []byte("test"), time.Now().Add(time.Hour)), wantErr: true},
+		{name: "none", token: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, time.Now().Add(time.Hour)), wantErr: true},
+		{name: "other secret", token: sign(jwt.SigningMethodHS256, []byte("other"), time.Now().Add(time.Hour)), wantErr: true},
+		{name: "access token", token: accessToken, wantErr: true},
+		{name: "not a token", token: "unknown", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			userId, email, err := ParseEmailVerificationToken(test.token)
+
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidVerificationToken) {
+					t.Errorf("err = %v, want %v", err, ErrInvalidVerificationToken)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseEmailVerificationToken: %v", err)
+			}
+			if userId != 7 || email != "ana@example.com" {
+				t.Errorf("got user %d email %q, want 7 ana@example.com", userId, email)
+			}
+		})
+	}
+}
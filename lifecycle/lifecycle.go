@@ -0,0 +1,123 @@
+// Package lifecycle starts and stops the subsystems of the app in order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hook is a subsystem of the app. Start and Stop can be nil. Start must not
+// block, a subsystem that runs until it is stopped starts a goroutine or is
+// registered with Go.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Lifecycle starts the hooks in the order they were appended and stops them
+// in reverse, so a hook can use the ones appended before it until it stops.
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+}
+
+func New() *Lifecycle {
+	return &Lifecycle{}
+}
+
+func (lifecycle *Lifecycle) Append(hook Hook) {
+
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+
+	lifecycle.hooks = append(lifecycle.hooks, hook)
+}
+
+// Go appends a background worker. It runs from Start with a context that is
+// cancelled on Stop, and Stop waits until it returns.
+func (lifecycle *Lifecycle) Go(name string, worker func(ctx context.Context)) {
+
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	lifecycle.Append(Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+
+			go func() {
+				defer close(done)
+				worker(ctx)
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("worker did not stop: %w", ctx.Err())
+			}
+		},
+	})
+}
+
+// Start starts the hooks. When one fails, the hooks started before it are
+// stopped again.
+func (lifecycle *Lifecycle) Start(ctx context.Context) error {
+
+	lifecycle.mu.Lock()
+	hooks := lifecycle.hooks
+	lifecycle.mu.Unlock()
+
+	for i, hook := range hooks {
+		if hook.Start != nil {
+			if err := hook.Start(ctx); err != nil {
+				errStart := fmt.Errorf("start %s: %w", hook.Name, err)
+				return errors.Join(errStart, stop(ctx, hooks[:i]))
+			}
+		}
+
+		lifecycle.mu.Lock()
+		lifecycle.started = i + 1
+		lifecycle.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Stop stops the started hooks in reverse order. Every hook is stopped, also
+// after ctx expired, so the later ones still release their resources.
+func (lifecycle *Lifecycle) Stop(ctx context.Context) error {
+
+	lifecycle.mu.Lock()
+	hooks := lifecycle.hooks[:lifecycle.started]
+	lifecycle.started = 0
+	lifecycle.mu.Unlock()
+
+	return stop(ctx, hooks)
+}
+
+func stop(ctx context.Context, hooks []Hook) error {
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hooks[i].Stop == nil {
+			continue
+		}
+
+		if err := hooks[i].Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", hooks[i].Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recorder appends hooks that record when they start and stop.
+type recorder struct {
+	events []string
+}
+
+func (recorder *recorder) hook(name string, errStart error) Hook {
+
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			recorder.events = append(recorder.events, "start "+name)
+			return errStart
+		},
+		Stop: func(context.Context) error {
+			recorder.events = append(recorder.events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLifecycleOrder(t *testing.T) {
+
+	recorder := &recorder{}
+	lifecycle := New()
+	lifecycle.Append(recorder.hook("database", nil))
+	lifecycle.Append(Hook{Name: "no callbacks"})
+	lifecycle.Append(recorder.hook("server", nil))
+
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if err := lifecycle.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	// A second Stop has nothing left to stop
+	if err := lifecycle.Stop(context.Background()); err != nil {
+		t.Fatalf("second Stop: %v", err)
+	}
+
+	want := []string{"start database", "start server", "stop server", "stop database"}
+	if !reflect.DeepEqual(recorder.events, want) {
+		t.Errorf("events = %q, want %q", recorder.events, want)
+	}
+}
+
+func TestLifecycleStartFailure(t *testing.T) {
+
+	recorder := &recorder{}
+	errPort := errors.New("port in use")
+
+	lifecycle := New()
+	lifecycle.Append(recorder.hook("database", nil))
+	lifecycle.Append(recorder.hook("server", errPort))
+	lifecycle.Append(recorder.hook("metrics", nil))
+
+	err := lifecycle.Start(context.Background())
+	if !errors.Is(err, errPort) {
+		t.Fatalf("Start: err = %v, want %v", err, errPort)
+	}
+
+	// Only the hooks that started are stopped
+	want := []string{"start database", "start server", "stop database"}
+	if !reflect.DeepEqual(recorder.events, want) {
+		t.Errorf("events = %q, want %q", recorder.events, want)
+	}
+}
+
+func TestLifecycleStopsEveryHook(t *testing.T) {
+
+	recorder := &recorder{}
+	errClose := errors.New("close failed")
+
+	lifecycle := New()
+	lifecycle.Append(recorder.hook("database", nil))
+	lifecycle.Append(Hook{
+		Name: "server",
+		Stop: func(context.Context) error { return errClose },
+	})
+
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	err := lifecycle.Stop(context.Background())
+	if !errors.Is(err, errClose) {
+		t.Errorf("Stop: err = %v, want %v", err, errClose)
+	}
+
+	if recorder.events[len(recorder.events)-1] != "stop database" {
+		t.Errorf("database was not stopped after the server failed to stop: %q", recorder.events)
+	}
+}
+
+func TestLifecycleGo(t *testing.T) {
+
+	drained := false
+
+	lifecycle := New()
+	lifecycle.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		drained = true
+	})
+
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if err := lifecycle.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if !drained {
+		t.Errorf("Stop returned before the worker finished")
+	}
+
+	// A worker that does not stop in time is reported
+	lifecycle = New()
+	lifecycle.Go("stuck", func(ctx context.Context) {
+		time.Sleep(time.Second)
+	})
+
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := lifecycle.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop of stuck worker: err = %v, want deadline exceeded", err)
+	}
+}
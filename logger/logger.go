@@ -0,0 +1,80 @@
+// Package logger builds the structured logger of the app and carries the
+// logger of a request, tagged with its request ID, in the context.
+package logger
+
+import (
+	"context"
+	"io"
+	"project-app/config"
+
+	"golang.org/x/exp/slog"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIdKey
+)
+
+// New writes JSON, or text when cfg.Format is text, and redacts the
+// sensitive attributes, see Redact.
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+
+	options := &slog.HandlerOptions{
+		Level:       level(cfg.Level),
+		ReplaceAttr: replaceAttr,
+	}
+
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+func level(name string) slog.Level {
+
+	switch name {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithContext returns a copy of ctx that carries logger.
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger of ctx, or the default logger when ctx has
+// none. Code that runs for a request logs with the logger of its context, so
+// the lines carry the request ID.
+func FromContext(ctx context.Context) *slog.Logger {
+
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// WithRequestId returns a copy of ctx that carries the request ID, and a
+// logger that adds it to every line.
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+
+	ctx = context.WithValue(ctx, requestIdKey, requestId)
+	return WithContext(ctx, FromContext(ctx).With("requestId", requestId))
+}
+
+// RequestId returns the request ID of ctx, or "" outside of a request.
+func RequestId(ctx context.Context) string {
+
+	requestId, _ := ctx.Value(requestIdKey).(string)
+	return requestId
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"project-app/config"
+	"project-app/model"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"gorm.io/gorm"
+)
+
+// decode returns the only line written to buffer.
+func decode(t *testing.T, buffer *bytes.Buffer) map[string]any {
+
+	t.Helper()
+
+	var line map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &line); err != nil {
+		t.Fatalf("decode %q: %v", buffer.String(), err)
+	}
+
+	return line
+}
+
+func TestRedact(t *testing.T) {
+
+	var buffer bytes.Buffer
+	log := New(config.LogConfig{Level: "info", Format: "json"}, &buffer)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	user := &model.User{
+		Model:    &gorm.Model{ID: 7, CreatedAt: createdAt},
+		Username: "alice",
+		Password: "$2a$14$hash",
+	}
+
+	log.Info("login",
+		"user", user,
+		"request", model.LoginRequest{Email: "alice@example.com", Password: "password123"},
+		"body", map[string]any{"refreshToken": "abc", "name": "work"},
+		"Authorization", "Bearer abc",
+		"error", errors.New("wrong password"),
+	)
+
+	if strings.Contains(buffer.String(), "$2a$14$hash") || strings.Contains(buffer.String(), "password123") ||
+		strings.Contains(buffer.String(), "abc") {
+		t.Fatalf("secret in the log: %s", buffer.String())
+	}
+
+	line := decode(t, &buffer)
+
+	logged := line["user"].(map[string]any)
+	if logged["Password"] != Redacted || logged["Username"] != "alice" {
+		t.Errorf("user = %v", logged)
+	}
+
+	// Values that format themselves are kept
+	if createdAt := logged["Model"].(map[string]any)["CreatedAt"]; createdAt != "2024-05-01T10:00:00Z" {
+		t.Errorf("CreatedAt = %v", createdAt)
+	}
+	if line["error"] != "wrong password" {
+		t.Errorf("error = %v", line["error"])
+	}
+
+	// Fields are named like in JSON
+	if request := line["request"].(map[string]any); request["email"] != "alice@example.com" || request["password"] != Redacted {
+		t.Errorf("request = %v", request)
+	}
+	if body := line["body"].(map[string]any); body["refreshToken"] != Redacted || body["name"] != "work" {
+		t.Errorf("body = %v", body)
+	}
+	if line["Authorization"] != Redacted {
+		t.Errorf("Authorization = %v", line["Authorization"])
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+
+	tests := map[string]string{
+		"":                         "",
+		"page=1&pageSize=10":       "page=1&pageSize=10",
+		"token=abc":                "token=" + Redacted,
+		"page=1&access%5Ftoken=ab": "page=1&access%5Ftoken=" + Redacted,
+		"search":                   "search",
+	}
+
+	for query, want := range tests {
+		if got := RedactQuery(query); got != want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestRequestId(t *testing.T) {
+
+	var buffer bytes.Buffer
+	ctx := WithContext(context.Background(), New(config.LogConfig{Level: "warn"}, &buffer))
+
+	if RequestId(ctx) != "" {
+		t.Errorf("RequestId outside of a request = %q", RequestId(ctx))
+	}
+
+	ctx = WithRequestId(ctx, "req-1")
+	if RequestId(ctx) != "req-1" {
+		t.Errorf("RequestId = %q, want req-1", RequestId(ctx))
+	}
+
+	// Below the level
+	FromContext(ctx).Info("ignored")
+	if buffer.Len() > 0 {
+		t.Fatalf("info written at level warn: %s", buffer.String())
+	}
+
+	FromContext(ctx).Warn("slow query")
+	if line := decode(t, &buffer); line["requestId"] != "req-1" || line["msg"] != "slow query" {
+		t.Errorf("line = %v", line)
+	}
+
+	if FromContext(context.Background()) != slog.Default() {
+		t.Errorf("FromContext without a logger is not the default logger")
+	}
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// Redacted replaces the value of a sensitive attribute.
+const Redacted = "[REDACTED]"
+
+// maxDepth bounds how deep nested structs are logged, a value that refers to
+// itself would never end.
+const maxDepth = 5
+
+// sensitive are the parts of a key, in lower case, that mark its value as a
+// secret: passwords and their hashes, tokens and credentials sent in headers.
+var sensitive = []string{"password", "secret", "token", "authorization", "cookie", "apikey"}
+
+// IsSensitive reports whether the value of key must not be logged, the match
+// ignores case.
+func IsSensitive(key string) bool {
+
+	key = strings.ToLower(key)
+	for _, part := range sensitive {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func replaceAttr(groups []string, attr slog.Attr) slog.Attr {
+
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	if attr.Value.Kind() == slog.KindAny && len(groups) < maxDepth {
+		attr.Value = Redact(attr.Value.Any())
+	}
+
+	return attr
+}
+
+// Redact turns a struct, or a map with string keys, into a group of its
+// fields. The handler calls replaceAttr on each of them, so sensitive fields
+// are redacted like attributes, where the handler would otherwise encode the
+// whole value, the password hash of a user included. Values that format
+// themselves, like time.Time or errors, are kept.
+func Redact(value any) slog.Value {
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return slog.AnyValue(value)
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || formatsItself(value) || formatsItself(v.Interface()) {
+		return slog.AnyValue(value)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		attrs := make([]slog.Attr, 0, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			attrs = append(attrs, slog.Any(fieldName(field), v.Field(i).Interface()))
+		}
+		return slog.GroupValue(attrs...)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return slog.AnyValue(value)
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		attrs := make([]slog.Attr, 0, len(keys))
+		for _, key := range keys {
+			attrs = append(attrs, slog.Any(key.String(), v.MapIndex(key).Interface()))
+		}
+		return slog.GroupValue(attrs...)
+	}
+
+	return slog.AnyValue(value)
+}
+
+// RedactQuery replaces the values of the sensitive parameters of a query
+// string, like the token of a verification link.
+func RedactQuery(query string) string {
+
+	if query == "" {
+		return ""
+	}
+
+	pairs := strings.Split(query, "&")
+	for i, pair := range pairs {
+		name, _, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+
+		if unescaped, err := url.QueryUnescape(name); err == nil && IsSensitive(unescaped) {
+			pairs[i] = name + "=" + Redacted
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func formatsItself(value any) bool {
+
+	switch value.(type) {
+	case error, fmt.Stringer, json.Marshaler, encoding.TextMarshaler:
+		return true
+	}
+
+	return false
+}
+
+// fieldName is the name of the field in JSON, so the logs read like the API.
+func fieldName(field reflect.StructField) string {
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer writes emails to a file instead of sending them.
+type LogMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func NewLogMailer(path string, from string) Mailer {
+	return &LogMailer{
+		Path: path,
+		From: from,
+	}
+}
+
+func (mailer *LogMailer) Send(ctx context.Context, message Message) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	var out io.Writer = os.Stdout
+	if mailer.Path != "" {
+		file, err := os.OpenFile(mailer.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	_, err := fmt.Fprintf(out, "----- %s -----\r\n%s\r\n", time.Now().Format(time.RFC3339), buildMessage(mailer.From, message))
+	return err
+}
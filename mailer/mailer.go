@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"project-app/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer picks the mailer from the mail driver. "smtp" sends real emails,
+// "log" writes them to the log file, or to the standard output when it is
+// empty, so the flows can be tested without a mail server.
+func NewMailer(cfg config.MailConfig) Mailer {
+
+	if cfg.Driver == "smtp" {
+		return NewSmtpMailer(SmtpConfig{
+			Host:     cfg.Smtp.Host,
+			Port:     cfg.Smtp.Port,
+			Username: cfg.Smtp.Username,
+			Password: cfg.Smtp.Password,
+			From:     cfg.From,
+		})
+	}
+
+	return NewLogMailer(cfg.LogFile, cfg.From)
+}
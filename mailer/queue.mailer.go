@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"project-app/logger"
+
+	"golang.org/x/exp/slog"
+)
+
+// queueSize is how many emails can wait for the worker before Send fails.
+const queueSize = 100
+
+// ErrQueueFull is returned by QueueMailer.Send when the worker is too far
+// behind.
+var ErrQueueFull = errors.New("mail queue is full")
+
+type queuedMessage struct {
+	message Message
+	// logger keeps the request id of the request that sent the email
+	logger *slog.Logger
+}
+
+// QueueMailer sends the emails of Mailer in the background. A request does
+// not wait for the mail server, it takes as long whether it sends an email
+// or not. Run must be running, see lifecycle.Go.
+type QueueMailer struct {
+	Mailer Mailer
+	// Timeout limits the sending of one email
+	Timeout time.Duration
+	queue   chan queuedMessage
+}
+
+func NewQueueMailer(mailer Mailer) *QueueMailer {
+	return &QueueMailer{
+		Mailer:  mailer,
+		Timeout: smtpTimeout,
+		queue:   make(chan queuedMessage, queueSize),
+	}
+}
+
+// Send queues the email. A failure of the mail server is only logged.
+func (mailer *QueueMailer) Send(ctx context.Context, message Message) error {
+
+	select {
+	case mailer.queue <- queuedMessage{message: message, logger: logger.FromContext(ctx)}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run sends the queued emails until ctx is cancelled, then the ones still
+// queued.
+func (mailer *QueueMailer) Run(ctx context.Context) {
+
+	for {
+		select {
+		case queued := <-mailer.queue:
+			mailer.send(queued)
+		case <-ctx.Done():
+			for {
+				select {
+				case queued := <-mailer.queue:
+					mailer.send(queued)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send does not use the context of Run, an email that is being sent on
+// shutdown still gets its timeout.
+func (mailer *QueueMailer) send(queued queuedMessage) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailer.Timeout)
+	defer cancel()
+
+	if err := mailer.Mailer.Send(ctx, queued.message); err != nil {
+		queued.logger.Error("send email", "subject", queued.message.Subject, "error", err)
+	}
+}
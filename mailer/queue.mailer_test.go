@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingMailer keeps the emails and waits for release before each send.
+type blockingMailer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []Message
+}
+
+func (mailer *blockingMailer) Send(ctx context.Context, message Message) error {
+
+	select {
+	case <-mailer.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	mailer.sent = append(mailer.sent, message)
+
+	return nil
+}
+
+func TestQueueMailer(t *testing.T) {
+
+	slow := &blockingMailer{release: make(chan struct{})}
+	queue := NewQueueMailer(slow)
+
+	// 1. Send does not wait for the mail server
+	start := time.Now()
+	for _, to := range []string{"ana@example.com", "bob@example.com"} {
+		if err := queue.Send(context.Background(), Message{To: to}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Send took %s, want it to return at once", elapsed)
+	}
+
+	// 2. Run sends the queued emails, also the ones left when it is stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Run(ctx)
+	}()
+
+	cancel()
+	close(slow.release)
+	<-done
+
+	if len(slow.sent) != 2 || slow.sent[0].To != "ana@example.com" || slow.sent[1].To != "bob@example.com" {
+		t.Errorf("sent = %+v, want both emails in order", slow.sent)
+	}
+}
+
+func TestQueueMailerFull(t *testing.T) {
+
+	queue := NewQueueMailer(&blockingMailer{release: make(chan struct{})})
+
+	for i := 0; i < queueSize; i++ {
+		if err := queue.Send(context.Background(), Message{}); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+
+	if err := queue.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Send to a full queue: err = %v, want %v", err, ErrQueueFull)
+	}
+}
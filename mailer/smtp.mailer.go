@@ -0,0 +1,143 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout is the longest an email can take, from the dial to the QUIT.
+const smtpTimeout = 30 * time.Second
+
+type SmtpConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SmtpMailer struct {
+	Config SmtpConfig
+}
+
+func NewSmtpMailer(config SmtpConfig) Mailer {
+	return &SmtpMailer{
+		Config: config,
+	}
+}
+
+// Send dials with ctx and gives the whole conversation with the server until
+// the deadline of ctx, or smtpTimeout when it is sooner, so a slow server
+// cannot hold the caller.
+func (mailer *SmtpMailer) Send(ctx context.Context, message Message) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(smtpTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	// The dial and every read and write share the deadline
+	address := net.JoinHostPort(mailer.Config.Host, mailer.Config.Port)
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// A cancelled ctx ends the conversation right away
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = mailer.send(conn, message)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// send talks to the server over conn, like smtp.SendMail: TLS when the
+// server offers it, then the login and the email.
+func (mailer *SmtpMailer) send(conn net.Conn, message Message) error {
+
+	client, err := smtp.NewClient(conn, mailer.Config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	// 1. Secure the connection and login
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: mailer.Config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if mailer.Config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		auth := smtp.PlainAuth("", mailer.Config.Username, mailer.Config.Password, mailer.Config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	// 2. Send
+	if err := client.Mail(mailer.Config.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(buildMessage(mailer.Config.From, message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func buildMessage(from string, message Message) []byte {
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", message.Subject)
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(message.Body)
+
+	return []byte(builder.String())
+}
@@ -0,0 +1,136 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSmtp accepts one connection on a local port and hands it to handle.
+func serveSmtp(t *testing.T, handle func(conn net.Conn)) SmtpConfig {
+
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return SmtpConfig{Host: host, Port: port, From: "app@example.com"}
+}
+
+func TestSmtpMailerSend(t *testing.T) {
+
+	received := make(chan string, 1)
+
+	// A server without TLS nor AUTH that accepts every email
+	config := serveSmtp(t, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ready")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 unknown command")
+			}
+		}
+	})
+
+	err := NewSmtpMailer(config).Send(context.Background(), Message{To: "ana@example.com", Subject: "Hello", Body: "Hi Ana"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if data := <-received; !strings.Contains(data, "To: ana@example.com\r\n") || !strings.Contains(data, "Hi Ana") {
+		t.Errorf("data = %q", data)
+	}
+}
+
+func TestSmtpMailerSlowServer(t *testing.T) {
+
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		// wantErr is the error of ctx, the deadline of the connection can
+		// also be noticed first
+		wantErr error
+	}{
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "cancel",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(100*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			// The server accepts the connection and never greets
+			release := make(chan struct{})
+			defer close(release)
+			config := serveSmtp(t, func(conn net.Conn) { <-release })
+
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			start := time.Now()
+			err := NewSmtpMailer(config).Send(ctx, Message{To: "ana@example.com"})
+			if !errors.Is(err, test.wantErr) && !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Errorf("err = %v, want %v", err, test.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Send took %s, want it to stop with ctx", elapsed)
+			}
+		})
+	}
+}
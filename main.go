@@ -1,16 +1,29 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
 	"project-app/app"
+	"project-app/config"
+	"project-app/health"
 	"project-app/helper"
+	"project-app/lifecycle"
+	"project-app/logger"
+	"project-app/mailer"
+	"project-app/metrics"
+	"project-app/migration"
 	"project-app/routes"
+	"project-app/tracing"
+	"project-app/validation"
+	"syscall"
 
 	_ "project-app/docs"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	_ "github.com/joho/godotenv/autoload"
+	"golang.org/x/exp/slog"
+	"gorm.io/gorm"
 )
 
 // @title           Project APP API
@@ -30,13 +43,125 @@ import (
 
 func main() {
 
-	newApp := fiber.New()
-	db := app.DbConnection()
-	validate := validator.New(validator.WithRequiredStructEnabled())
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	routes.SetupRoutes(newApp, db, validate)
+	// go run . migrate up|down|status|create
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		connect := func() *gorm.DB {
+			return app.OpenDatabase(cfg.Database)
+		}
 
-	port := os.Getenv("APP_PORT")
-	err := newApp.Listen(port)
-	helper.PanicIfError(err)
+		err := migration.Run(os.Args[2:], cfg.Database.MigrationsDir, connect, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// The std log package, used by gorm, writes through it too
+	slog.SetDefault(logger.New(cfg.Log, os.Stdout))
+
+	helper.SetJwtSecret(cfg.Jwt.Secret)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, cfg.App.Name, os.Stdout)
+	if err != nil {
+		slog.Error("set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	newApp := fiber.New(app.FiberConfig(cfg))
+	db := app.DbConnection(cfg)
+	validate := validation.Validator()
+
+	if err := metrics.RegisterDatabase(db); err != nil {
+		slog.Error("register database metrics", "error", err)
+		os.Exit(1)
+	}
+
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		slog.Error("register database tracing", "error", err)
+		os.Exit(1)
+	}
+
+	healthRegistry := health.NewRegistry(health.DefaultTimeout)
+	app.RegisterHealthChecks(healthRegistry, db, cfg)
+
+	routes.SetupHealthRoutes(newApp, healthRegistry)
+	routes.SetupMetricsRoutes(newApp)
+
+	// Emails are sent in the background, requests do not wait for the server
+	mails := mailer.NewQueueMailer(mailer.NewMailer(cfg.Mail))
+	routes.SetupRoutes(newApp, db, validate, cfg, mails)
+
+	// Hooks stop in reverse order: the server first, then the mailer sends
+	// the queued emails, then the database, and the tracing last to export
+	// the spans of the last requests
+	lc := lifecycle.New()
+	lc.Append(lifecycle.Hook{Name: "tracing", Stop: shutdownTracing})
+	lc.Append(app.DatabaseHook(db))
+	lc.Go("mailer", mails.Run)
+
+	errListen := make(chan error, 1)
+	lc.Append(lifecycle.Hook{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			go func() {
+				errListen <- newApp.Listen(cfg.App.Port)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			// Running requests get until the grace to finish, then their
+			// context is cancelled and they answer before the deadline of ctx
+			return newApp.ShutdownWithContext(ctx)
+		},
+	})
+
+	os.Exit(serve(lc, errListen, cfg.App))
+}
+
+// serve runs the app until SIGINT or SIGTERM, or until the server fails, and
+// returns the exit code.
+func serve(lc *lifecycle.Lifecycle, errListen <-chan error, appConfig config.AppConfig) int {
+
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	if err := lc.Start(ctx); err != nil {
+		slog.Error("start failed", "error", err)
+		return 1
+	}
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down", "grace", appConfig.ShutdownGrace().String(), "timeout", appConfig.ShutdownTimeout.String())
+	case err := <-errListen:
+		slog.Error("server stopped", "error", err)
+		exitCode = 1
+	}
+
+	// A second signal kills the process
+	stopSignals()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appConfig.ShutdownTimeout)
+	defer cancel()
+
+	if err := lc.Stop(shutdownCtx); err != nil {
+		slog.Error("shutdown failed", "error", err)
+		exitCode = 1
+	}
+
+	return exitCode
 }
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// GormPlugin times every query of GORM and counts the ones that fail, see
+// DbQueryDuration and DbQueryErrors.
+type GormPlugin struct{}
+
+func (plugin GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (plugin GormPlugin) Initialize(db *gorm.DB) error {
+
+	callback := db.Callback()
+
+	// The timing wraps every other callback of the operation
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", start),
+		callback.Create().After("*").Register("metrics:after_create", observe("create")),
+		callback.Query().Before("*").Register("metrics:before_query", start),
+		callback.Query().After("*").Register("metrics:after_query", observe("query")),
+		callback.Update().Before("*").Register("metrics:before_update", start),
+		callback.Update().After("*").Register("metrics:after_update", observe("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", start),
+		callback.Delete().After("*").Register("metrics:after_delete", observe("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", start),
+		callback.Row().After("*").Register("metrics:after_row", observe("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", start),
+		callback.Raw().After("*").Register("metrics:after_raw", observe("raw")),
+	)
+}
+
+func start(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func observe(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+
+		started, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		// Raw SQL has no table
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		DbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(started).Seconds())
+
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// RegisterDatabase adds the GORM plugin to db and exports the stats of its
+// connection pool, from sql.DB.Stats. Call it once, for the database of the
+// server.
+func RegisterDatabase(db *gorm.DB) error {
+
+	if err := db.Use(GormPlugin{}); err != nil {
+		return err
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDb, db.Dialector.Name()))
+}
@@ -0,0 +1,87 @@
+// Package metrics holds the Prometheus collectors of the app. They are
+// registered on Registry, which /metrics serves, and updated by the HTTP
+// middleware, the GORM plugin and the services.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Login failure reasons, see LoginFailures.
+const (
+	// Unknown email or wrong password, they are not told apart.
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginEmailNotVerified   = "email_not_verified"
+	// The account is locked after too many wrong passwords.
+	LoginLocked = "locked"
+)
+
+// Registry is used instead of the default registry of Prometheus, so only
+// the collectors of the app are served.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the HTTP requests by method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of the queries of GORM by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	DbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Queries of GORM that failed, by operation and table. A record that is not found is not an error.",
+	}, []string{"operation", "table"})
+
+	UsersRegistered = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "users_registered_total",
+		Help: "Users that registered.",
+	})
+
+	Logins = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "logins_total",
+		Help: "Successful logins.",
+	})
+
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "login_failures_total",
+		Help: "Refused logins by reason.",
+	}, []string{"reason"})
+
+	ProjectsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "projects_created_total",
+		Help: "Projects that were created.",
+	})
+)
+
+func init() {
+
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequests,
+		HttpRequestDuration,
+		DbQueryDuration,
+		DbQueryErrors,
+		UsersRegistered,
+		Logins,
+		LoginFailures,
+		ProjectsCreated,
+	)
+
+	// Every reason is exported from the start, a rate needs a first sample
+	LoginFailures.WithLabelValues(LoginInvalidCredentials)
+	LoginFailures.WithLabelValues(LoginEmailNotVerified)
+	LoginFailures.WithLabelValues(LoginLocked)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"errors"
+	"project-app/model"
+	"project-app/testdb"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+)
+
+// queries returns how many queries were timed for the labels.
+func queries(t *testing.T, operation string, table string) uint64 {
+
+	t.Helper()
+
+	var count uint64
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "db_query_duration_seconds" {
+			continue
+		}
+		for _, sample := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range sample.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["operation"] == operation && labels["table"] == table {
+				count = sample.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	return count
+}
+
+func TestGormPlugin(t *testing.T) {
+
+	db := testdb.Open(t)
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+
+	createBefore := queries(t, "create", "categories")
+	queryBefore := queries(t, "query", "categories")
+	errorsBefore := testutil.ToFloat64(DbQueryErrors.WithLabelValues("raw", "unknown"))
+	notFoundBefore := testutil.ToFloat64(DbQueryErrors.WithLabelValues("query", "categories"))
+
+	category := model.Category{Name: "Work", UserID: 1}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// A record that is not found is not an error
+	var found model.Category
+	if err := db.First(&found, category.ID+1).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("First: err = %v, want not found", err)
+	}
+
+	if err := db.Exec("SELECT * FROM missing_table").Error; err == nil {
+		t.Fatalf("query of a missing table did not fail")
+	}
+
+	if got := queries(t, "create", "categories") - createBefore; got != 1 {
+		t.Errorf("create queries = %d, want 1", got)
+	}
+	if got := queries(t, "query", "categories") - queryBefore; got != 1 {
+		t.Errorf("queries = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(DbQueryErrors.WithLabelValues("query", "categories")) - notFoundBefore; got != 0 {
+		t.Errorf("not found counted as %v errors", got)
+	}
+	if got := testutil.ToFloat64(DbQueryErrors.WithLabelValues("raw", "unknown")) - errorsBefore; got != 1 {
+		t.Errorf("raw errors = %v, want 1", got)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"project-app/logger"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// AccessLog logs a line for every request with its status and latency, at
+// warn for client errors and at error for server errors. The error of the
+// handler is passed to the error handler of the app first, so the line has
+// the status the client gets. It must run after RequestId.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		start := time.Now()
+
+		err := c.Next()
+		route := routeTemplate(c, err)
+		handleError(c, err)
+
+		status := c.Response().StatusCode()
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Float64("latencyMs", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", len(c.Response().Body())),
+			slog.String("ip", c.IP()),
+			slog.String("userAgent", c.Get(fiber.HeaderUserAgent)),
+		}
+
+		// Tokens of links, like the email verification, stay out of the logs
+		if query := c.Request().URI().QueryString(); len(query) > 0 {
+			attrs = append(attrs, slog.String("query", logger.RedactQuery(string(query))))
+		}
+
+		ctx := c.UserContext()
+		logger.FromContext(ctx).LogAttrs(ctx, level, "request", attrs...)
+
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// disconnectPollInterval is how often the connection of a running request is
+// checked for a client that went away.
+const disconnectPollInterval = 200 * time.Millisecond
+
+// RequestContext sets the context that handlers pass to the services with
+// c.UserContext(). It is cancelled after timeout, when the client closes the
+// connection or shutdownGrace after the server started to shut down, so the
+// queries still running for the request are aborted. A timeout of 0
+// disables the deadline.
+func RequestContext(timeout time.Duration, shutdownGrace time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		ctx, cancel := context.WithCancel(c.UserContext())
+		defer cancel()
+
+		if timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+			defer cancelTimeout()
+		}
+
+		stop := watchClient(c.Context().Conn(), c.Context().Done(), shutdownGrace, cancel)
+		defer stop()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// watchClient calls cancel when the client disconnects or shutdownGrace after
+// shutdown is closed. stop must be called before the request ends, the
+// connection is reused by the next request.
+func watchClient(conn net.Conn, shutdown <-chan struct{}, shutdownGrace time.Duration, cancel context.CancelFunc) (stop func()) {
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		ticks := ticker.C
+
+		var graceExpired <-chan time.Time
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-shutdown:
+				// The request can still finish during the grace period
+				shutdown = nil
+				grace := time.NewTimer(shutdownGrace)
+				defer grace.Stop()
+				graceExpired = grace.C
+			case <-graceExpired:
+				cancel()
+				return
+			case <-ticks:
+				gone, supported := clientGone(conn)
+				if !supported {
+					// Only the shutdown can end the request early
+					ticks = nil
+				}
+
+				if gone {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// handled is what the handler of a test saw when its context ended.
+type handled struct {
+	err     error
+	elapsed time.Duration
+}
+
+// waitForContext returns a handler that signals started and waits until its
+// context ends, or gives up after 5 seconds so a broken watcher cannot hang the
+// test.
+func waitForContext(started chan<- struct{}, result chan<- handled) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		start := time.Now()
+		close(started)
+
+		select {
+		case <-c.UserContext().Done():
+			result <- handled{err: c.UserContext().Err(), elapsed: time.Since(start)}
+			return c.UserContext().Err()
+		case <-time.After(5 * time.Second):
+			result <- handled{elapsed: time.Since(start)}
+			return c.SendStatus(fiber.StatusOK)
+		}
+	}
+}
+
+// listen serves app on a real socket, app.Test has no file descriptor to
+// watch and no shutdown.
+func listen(t *testing.T, app *fiber.App) string {
+
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	go app.Listener(ln)
+	t.Cleanup(func() {
+		app.ShutdownWithTimeout(time.Second)
+	})
+
+	return ln.Addr().String()
+}
+
+func TestRequestContextTimeout(t *testing.T) {
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler})
+	app.Use(RequestContext(50*time.Millisecond, time.Second))
+
+	started := make(chan struct{})
+	result := make(chan handled, 1)
+	app.Get("/", waitForContext(started, result))
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil), -1)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	if got := <-result; !errors.Is(got.err, context.DeadlineExceeded) {
+		t.Errorf("context err = %v, want %v", got.err, context.DeadlineExceeded)
+	}
+
+	if res.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", res.StatusCode, fiber.StatusServiceUnavailable)
+	}
+	if res.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Errorf("Retry-After is missing")
+	}
+}
+
+func TestRequestContextClientGone(t *testing.T) {
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("clientGone cannot tell on " + runtime.GOOS)
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler, DisableStartupMessage: true})
+	app.Use(RequestContext(0, time.Second))
+
+	started := make(chan struct{})
+	result := make(chan handled, 1)
+	app.Get("/", waitForContext(started, result))
+
+	conn, err := net.Dial("tcp", listen(t, app))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-started
+	conn.Close()
+
+	// The watcher peeks at the socket every disconnectPollInterval
+	got := <-result
+	if !errors.Is(got.err, context.Canceled) {
+		t.Fatalf("context err = %v, want %v", got.err, context.Canceled)
+	}
+	if got.elapsed > 5*disconnectPollInterval {
+		t.Errorf("cancelled after %s, want within a few polls of %s", got.elapsed, disconnectPollInterval)
+	}
+}
+
+func TestRequestContextShutdown(t *testing.T) {
+
+	const grace = 300 * time.Millisecond
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler, DisableStartupMessage: true})
+	app.Use(RequestContext(0, grace))
+
+	started := make(chan struct{})
+	result := make(chan handled, 1)
+	app.Get("/", waitForContext(started, result))
+
+	conn, err := net.Dial("tcp", listen(t, app))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-started
+
+	// The shutdown waits for the request, which is cancelled after the grace
+	shutdownStart := time.Now()
+	errShutdown := make(chan error, 1)
+	go func() {
+		errShutdown <- app.ShutdownWithTimeout(5 * time.Second)
+	}()
+
+	got := <-result
+	if !errors.Is(got.err, context.Canceled) {
+		t.Fatalf("context err = %v, want %v", got.err, context.Canceled)
+	}
+	if cancelledAfter := time.Since(shutdownStart); cancelledAfter < grace {
+		t.Errorf("cancelled %s after the shutdown, want after the grace of %s", cancelledAfter, grace)
+	}
+
+	// The request still got its answer before the server stopped
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", res.StatusCode, fiber.StatusServiceUnavailable)
+	}
+
+	if err := <-errShutdown; err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
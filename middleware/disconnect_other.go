@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package middleware
+
+import "net"
+
+// clientGone cannot tell on this platform, requests only end early on the
+// timeout and the shutdown.
+func clientGone(conn net.Conn) (gone bool, supported bool) {
+	return false, false
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin
+
+package middleware
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// clientGone peeks at the socket without consuming the next request. A read
+// of 0 bytes means the client closed the connection. supported is false for
+// connections without a file descriptor, like TLS or app.Test.
+func clientGone(conn net.Conn) (gone bool, supported bool) {
+
+	syscallConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return false, false
+	}
+
+	raw, err := syscallConn.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+
+	err = raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, errPeek := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+
+		switch {
+		case errors.Is(errPeek, syscall.EAGAIN), errors.Is(errPeek, syscall.EINTR):
+			gone = false
+		case errPeek != nil:
+			gone = true
+		default:
+			gone = n == 0
+		}
+
+		// Never wait for the socket to become readable
+		return true
+	})
+	if err != nil {
+		return true, true
+	}
+
+	return gone, true
+}
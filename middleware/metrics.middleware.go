@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"project-app/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Metrics counts and times the requests, see metrics.HttpRequests. Like
+// AccessLog it passes the error of the handler to the error handler of the
+// app.
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		start := time.Now()
+
+		err := c.Next()
+		route := routeTemplate(c, err)
+		handleError(c, err)
+
+		// The labels outlive the request, the method points into its buffer
+		labels := []string{utils.CopyString(c.Method()), route, strconv.Itoa(c.Response().StatusCode())}
+
+		metrics.HttpRequests.WithLabelValues(labels...).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
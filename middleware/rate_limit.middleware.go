@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"project-app/apperror"
+	"project-app/logger"
+	"project-app/model"
+	"project-app/ratelimit"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// RateLimit answers 429 with Retry-After once the key of a request used up
+// its limit. Requests without a key are not limited, and neither are any
+// when the limit has no burst. The name keeps the buckets of different
+// limits apart in a shared store. When the store fails the request goes
+// through, a broken store should not lock everyone out.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key func(c *fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		if limit.Burst <= 0 {
+			return c.Next()
+		}
+
+		value := key(c)
+		if value == "" {
+			return c.Next()
+		}
+
+		decision, err := store.Take(c.UserContext(), name+":"+value, limit)
+		if err != nil {
+			logger.FromContext(c.UserContext()).Error("rate limit store failed", "limit", name, "error", err)
+			return c.Next()
+		}
+
+		if !decision.Allowed {
+			return apperror.TooManyRequests("Too many requests, try again later").RetryIn(decision.RetryAfter)
+		}
+
+		return c.Next()
+	}
+}
+
+// KeyByIp limits each client IP. Behind a reverse proxy it is the IP in the
+// proxy header, see config.AppConfig.TrustedProxies.
+func KeyByIp(c *fiber.Ctx) string {
+	return utils.CopyString(c.IP())
+}
+
+// KeyByLoginEmail limits each account a login is tried for, whatever the IP.
+func KeyByLoginEmail(c *fiber.Ctx) string {
+
+	var request model.LoginRequest
+	if err := c.BodyParser(&request); err != nil {
+		return ""
+	}
+
+	return utils.CopyString(strings.ToLower(strings.TrimSpace(request.Email)))
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"project-app/apperror"
+	"project-app/helper"
+	rbacRepository "project-app/repository/rbac"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type RbacMiddleware struct {
+	RbacRepository rbacRepository.RbacRepository
+}
+
+func NewRbacMiddleware(db *gorm.DB) *RbacMiddleware {
+	return &RbacMiddleware{
+		RbacRepository: rbacRepository.NewRbacRepository(db),
+	}
+}
+
+// RequirePermission only lets the request through when one of the roles of
+// the user grants permission. It must run after helper.VerifyToken.
+// Permissions are read from the database, so a role change applies on the
+// next request.
+func (middleware *RbacMiddleware) RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		principal, ok := helper.GetAuthPrincipal(c)
+		if !ok {
+			return apperror.Unauthorized("Token not provided")
+		}
+
+		permissions, err := middleware.RbacRepository.FindPermissionsByUserId(c.UserContext(), principal.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, granted := range permissions {
+			if granted == permission {
+				return c.Next()
+			}
+		}
+
+		return apperror.Forbidden("Permission " + permission + " required")
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"project-app/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+)
+
+// maxRequestIdLength bounds the request IDs accepted from clients.
+const maxRequestIdLength = 128
+
+// RequestId takes the X-Request-ID of the request, or generates one when it
+// is missing or invalid, and returns it in the response. The ID and a logger
+// that adds it to every line are put in the context of c.UserContext(), see
+// logger.FromContext, so it must run before the middlewares that log.
+func RequestId() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		// The context can outlive the request, the header points into its buffer
+		requestId := utils.CopyString(c.Get(fiber.HeaderXRequestID))
+		if !validRequestId(requestId) {
+			requestId = uuid.NewString()
+		}
+
+		c.Set(fiber.HeaderXRequestID, requestId)
+		c.SetUserContext(logger.WithRequestId(c.UserContext(), requestId))
+
+		return c.Next()
+	}
+}
+
+// validRequestId only accepts printable ASCII without spaces, an ID from a
+// client cannot forge lines in the logs.
+func validRequestId(requestId string) bool {
+
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for i := 0; i < len(requestId); i++ {
+		if requestId[i] <= ' ' || requestId[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
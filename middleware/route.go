@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// routeUnmatched is the route of the requests that no route matched.
+const routeUnmatched = "unmatched"
+
+// localsUnmatched marks a request that no route matched, for the middlewares
+// that only see the response the error handler wrote.
+const localsUnmatched = "middleware:unmatched"
+
+// handleError writes the response of err with the error handler of the app,
+// for the middlewares that need the status of the response.
+func handleError(c *fiber.Ctx, err error) {
+
+	if err == nil {
+		return
+	}
+
+	if errHandler := c.App().ErrorHandler(c, err); errHandler != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+}
+
+// routeTemplate is the path of the last route that ran, like
+// /api/v1/project/:id, so the requests of every ID share a label. A request
+// refused by the middleware of a group gets the prefix of the group. Paths
+// that no route matched get routeUnmatched, they would make a label for
+// every URL scanned. Call it with the error of c.Next(), before handleError.
+func routeTemplate(c *fiber.Ctx, err error) string {
+
+	// The app returns apperror, only the router returns these
+	var fiberError *fiber.Error
+	if errors.As(err, &fiberError) {
+		switch fiberError.Code {
+		case fiber.StatusNotFound, fiber.StatusMethodNotAllowed:
+			c.Locals(localsUnmatched, true)
+		}
+	}
+
+	if unmatched, _ := c.Locals(localsUnmatched).(bool); unmatched {
+		return routeUnmatched
+	}
+
+	return c.Route().Path
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"project-app/logger"
+	"project-app/tracing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts the span of the request, in the trace of the traceparent
+// header when the client sent one. The span travels in c.UserContext(), so
+// the spans of the services, repositories and queries are its children, and
+// the logger of the request adds the trace ID to its lines. It must run after
+// RequestId.
+func Tracing() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+
+		// The span outlives the request, its strings point into its buffer
+		method := utils.CopyString(c.Method())
+		ctx, span := tracing.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(utils.CopyString(c.Path())),
+				semconv.ClientAddress(c.IP()),
+				semconv.UserAgentOriginal(utils.CopyString(c.Get(fiber.HeaderUserAgent))),
+			),
+		)
+		defer span.End()
+
+		if spanContext := span.SpanContext(); spanContext.IsValid() {
+			ctx = logger.WithContext(ctx, logger.FromContext(ctx).With("traceId", spanContext.TraceID().String()))
+		}
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		route := routeTemplate(c, err)
+		handleError(c, err)
+
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		if route != routeUnmatched {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		// Client errors are not errors of the server
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return nil
+	}
+}
+
+// headerCarrier gives the propagator the headers of the request.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (carrier headerCarrier) Get(key string) string {
+	return utils.CopyString(carrier.c.Get(key))
+}
+
+func (carrier headerCarrier) Set(key string, value string) {
+	carrier.c.Request().Header.Set(key, value)
+}
+
+func (carrier headerCarrier) Keys() []string {
+
+	var keys []string
+	carrier.c.Request().Header.VisitAll(func(key []byte, _ []byte) {
+		keys = append(keys, string(key))
+	})
+
+	return keys
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"project-app/apperror"
+	"project-app/config"
+	"project-app/helper"
+	usersRepository "project-app/repository/users"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationMiddleware struct {
+	UsersRepository usersRepository.UsersRepository
+	Policy          string
+}
+
+func NewEmailVerificationMiddleware(db *gorm.DB, policy string) *EmailVerificationMiddleware {
+	return &EmailVerificationMiddleware{
+		UsersRepository: usersRepository.NewUsersRepository(db),
+		Policy:          policy,
+	}
+}
+
+// RequireVerifiedEmail blocks writes of users that did not verify their email
+// when the policy is "write". Reads are always allowed. It must run after
+// helper.VerifyToken.
+func (middleware *EmailVerificationMiddleware) RequireVerifiedEmail(c *fiber.Ctx) error {
+
+	if middleware.Policy != config.EmailVerificationWrite {
+		return c.Next()
+	}
+
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+
+	principal, ok := helper.GetAuthPrincipal(c)
+	if !ok {
+		return apperror.Unauthorized("Token not provided")
+	}
+
+	user, err := middleware.UsersRepository.FindById(c.UserContext(), principal.UserID)
+	if apperror.Is(err, apperror.KindNotFound) {
+		return apperror.Unauthorized("User not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return apperror.Forbidden("Email not verified")
+	}
+
+	return c.Next()
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+	"project-app/config"
+	"project-app/helper"
+	"project-app/model"
+	"project-app/repository/fake"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+
+	db := fake.NewDatabase()
+	users := fake.NewUsersRepository(db)
+	verifiedAt := time.Now()
+
+	// User 1 is not verified, user 2 is
+	for _, user := range []*model.User{
+		{Username: "ana", Email: "ana@example.com"},
+		{Username: "bob", Email: "bob@example.com", EmailVerifiedAt: &verifiedAt},
+	} {
+		if _, err := users.Register(context.Background(), user); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		policy     string
+		method     string
+		userId     uint
+		wantStatus int
+	}{
+		{name: "policy off", policy: config.EmailVerificationOff, method: fiber.MethodPost, userId: 1, wantStatus: fiber.StatusOK},
+		{name: "policy login", policy: config.EmailVerificationLogin, method: fiber.MethodPost, userId: 1, wantStatus: fiber.StatusOK},
+		{name: "write by unverified user", policy: config.EmailVerificationWrite, method: fiber.MethodPost, userId: 1, wantStatus: fiber.StatusForbidden},
+		{name: "delete by unverified user", policy: config.EmailVerificationWrite, method: fiber.MethodDelete, userId: 1, wantStatus: fiber.StatusForbidden},
+		{name: "read by unverified user", policy: config.EmailVerificationWrite, method: fiber.MethodGet, userId: 1, wantStatus: fiber.StatusOK},
+		{name: "write by verified user", policy: config.EmailVerificationWrite, method: fiber.MethodPost, userId: 2, wantStatus: fiber.StatusOK},
+		{name: "write by deleted user", policy: config.EmailVerificationWrite, method: fiber.MethodPost, userId: 100, wantStatus: fiber.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			verified := &EmailVerificationMiddleware{UsersRepository: users, Policy: test.policy}
+
+			app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler})
+			app.Use(func(c *fiber.Ctx) error {
+				helper.SetAuthPrincipal(c, &helper.AuthPrincipal{UserID: test.userId})
+				return c.Next()
+			})
+			app.All("/", verified.RequireVerifiedEmail, func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			res, err := app.Test(httptest.NewRequest(test.method, "/", nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != test.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, test.wantStatus)
+			}
+		})
+	}
+}
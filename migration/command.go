@@ -0,0 +1,150 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up [n]         apply all pending migrations, or the next n
+  down [n]       revert the last migration, or the last n
+  status         list migrations and when they were applied
+  create <name>  add an empty migration to the migrations directory`
+
+var invalidName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Run executes the migrate command in args. New migrations are created in dir,
+// connect is only called by the commands that need the database.
+func Run(args []string, dir string, connect func() *gorm.DB, out io.Writer) error {
+
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+
+		up, down, err := Create(dir, args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "created %s\ncreated %s\nrebuild the app to embed the new migration\n", up, down)
+		return nil
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid step count %q", args[1])
+		}
+		steps = n
+	}
+
+	switch args[0] {
+	case "up", "down", "status":
+	default:
+		return errors.New(usage)
+	}
+
+	migrator, err := NewMigrator(connect())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(steps)
+		printMigrations(out, "applied", done)
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "nothing to apply")
+		}
+		return err
+
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+
+		done, err := migrator.Down(steps)
+		printMigrations(out, "reverted", done)
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "nothing to revert")
+		}
+		return err
+
+	default:
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Missing {
+				state += " (missing files)"
+			}
+			fmt.Fprintf(out, "%06d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	}
+}
+
+// Create writes empty up and down files for the next version in dir.
+func Create(dir string, name string) (string, string, error) {
+
+	name = strings.Trim(invalidName.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("migration name is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+
+	migrations, err := Load(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", err
+	}
+
+	var version uint64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%06d_%s", version, name))
+	up := base + ".up.sql"
+	down := base + ".down.sql"
+
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+
+	if err := os.WriteFile(down, []byte("-- revert "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+
+	return up, down, nil
+}
+
+func printMigrations(out io.Writer, action string, migrations []Migration) {
+	for _, migration := range migrations {
+		fmt.Fprintf(out, "%s %06d_%s\n", action, migration.Version, migration.Name)
+	}
+}
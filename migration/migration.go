@@ -0,0 +1,368 @@
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+const tableMigrations = "schema_migrations"
+
+// lockKey is the key of the Postgres advisory lock held by Up, any number
+// works as long as every build uses the same one.
+const lockKey = 4917230561
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrSchemaBehind is returned by EnsureUpToDate when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind, run `migrate up`")
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   uint64
+	Name      string
+	AppliedAt *time.Time
+	// Missing is set when the version is applied but its files are not known
+	// by this build.
+	Missing bool
+}
+
+type appliedMigration struct {
+	Version   uint64
+	Name      string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	Db         *gorm.DB
+	Migrations []Migration
+}
+
+// NewMigrator returns a migrator for the migrations embedded in the binary.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+
+	migrations, err := Load(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		Db:         db,
+		Migrations: migrations,
+	}, nil
+}
+
+// Load reads <version>_<name>.up.sql and <version>_<name>.down.sql files from
+// dir and returns them sorted by version. Every version needs both files.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %06d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (migrator *Migrator) ensureTable() error {
+
+	return migrator.Db.Exec(`CREATE TABLE IF NOT EXISTS ` + tableMigrations + ` (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+}
+
+func (migrator *Migrator) applied() ([]appliedMigration, error) {
+
+	if err := migrator.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var result []appliedMigration
+	err := migrator.Db.
+		Table(tableMigrations).
+		Order("version").
+		Find(&result).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Pending returns the migrations that are not applied yet, oldest first.
+func (migrator *Migrator) Pending() ([]Migration, error) {
+
+	applied, err := migrator.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[uint64]bool, len(applied))
+	for _, migration := range applied {
+		done[migration.Version] = true
+	}
+
+	return migrator.pending(done), nil
+}
+
+func (migrator *Migrator) pending(done map[uint64]bool) []Migration {
+
+	var pending []Migration
+	for _, migration := range migrator.Migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending
+}
+
+// appliedVersions reads the applied versions without creating
+// schema_migrations, a database without the table has none applied.
+func (migrator *Migrator) appliedVersions() (map[uint64]bool, error) {
+
+	var versions []uint64
+	err := migrator.Db.Raw(`SELECT version FROM ` + tableMigrations).Scan(&versions).Error
+	if err != nil {
+		if !migrator.Db.Migrator().HasTable(tableMigrations) {
+			return map[uint64]bool{}, nil
+		}
+		return nil, err
+	}
+
+	done := make(map[uint64]bool, len(versions))
+	for _, version := range versions {
+		done[version] = true
+	}
+
+	return done, nil
+}
+
+// Up applies up to steps pending migrations, all of them when steps is 0.
+// Every migration runs in its own transaction together with its record in
+// schema_migrations. On Postgres it holds an advisory lock, a second Up waits
+// and then finds the migrations applied.
+func (migrator *Migrator) Up(steps int) ([]Migration, error) {
+
+	if migrator.Db.Dialector.Name() != "postgres" {
+		return migrator.up(steps)
+	}
+
+	// The lock belongs to the session, so it is taken, used and released on
+	// one connection of the pool
+	var done []Migration
+	err := migrator.Db.Connection(func(conn *gorm.DB) error {
+
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		locked := *migrator
+		locked.Db = conn
+
+		var err error
+		done, err = locked.up(steps)
+		return err
+	})
+
+	return done, err
+}
+
+func (migrator *Migrator) up(steps int) ([]Migration, error) {
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		err := migrator.Db.Transaction(func(tx *gorm.DB) error {
+
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+
+			return tx.Table(tableMigrations).Create(&appliedMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+
+		if err != nil {
+			return done, fmt.Errorf("migration %06d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (migrator *Migrator) Down(steps int) ([]Migration, error) {
+
+	applied, err := migrator.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[uint64]Migration, len(migrator.Migrations))
+	for _, migration := range migrator.Migrations {
+		known[migration.Version] = migration
+	}
+
+	var done []Migration
+	for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+		migration, ok := known[applied[i].Version]
+		if !ok {
+			return done, fmt.Errorf("migration %06d_%s is applied but its files are missing", applied[i].Version, applied[i].Name)
+		}
+
+		err := migrator.Db.Transaction(func(tx *gorm.DB) error {
+
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+
+			return tx.Table(tableMigrations).
+				Where("version = ?", migration.Version).
+				Delete(&appliedMigration{}).
+				Error
+		})
+
+		if err != nil {
+			return done, fmt.Errorf("migration %06d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status lists every known and every applied migration by version.
+func (migrator *Migrator) Status() ([]Status, error) {
+
+	applied, err := migrator.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Status{}
+	for _, migration := range migrator.Migrations {
+		byVersion[migration.Version] = &Status{Version: migration.Version, Name: migration.Name}
+	}
+
+	for _, migration := range applied {
+		appliedAt := migration.AppliedAt
+		status, ok := byVersion[migration.Version]
+		if !ok {
+			status = &Status{Version: migration.Version, Name: migration.Name, Missing: true}
+			byVersion[migration.Version] = status
+		}
+		status.AppliedAt = &appliedAt
+	}
+
+	result := make([]Status, 0, len(byVersion))
+	for _, status := range byVersion {
+		result = append(result, *status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// EnsureUpToDate returns ErrSchemaBehind when a migration is not applied yet.
+// It only reads, so the readiness probe can run it as often as it likes.
+func (migrator *Migrator) EnsureUpToDate() error {
+
+	done, err := migrator.appliedVersions()
+	if err != nil {
+		return err
+	}
+
+	pending := migrator.pending(done)
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending, first is %06d_%s", ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	return nil
+}
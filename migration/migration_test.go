@@ -0,0 +1,504 @@
+// The tests use testdb, which imports migration.
+package migration_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"project-app/migration"
+	"project-app/testdb"
+
+	"gorm.io/gorm"
+)
+
+const tableMigrations = "schema_migrations"
+
+// testFiles are migrations that run on SQLite and Postgres.
+var testFiles = fstest.MapFS{
+	"sql/000001_create_widgets.up.sql":    {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)")},
+	"sql/000001_create_widgets.down.sql":  {Data: []byte("DROP TABLE widgets")},
+	"sql/000002_add_widget_size.up.sql":   {Data: []byte("ALTER TABLE widgets ADD COLUMN size INTEGER")},
+	"sql/000002_add_widget_size.down.sql": {Data: []byte("ALTER TABLE widgets DROP COLUMN size")},
+	"sql/000003_create_gadgets.up.sql":    {Data: []byte("CREATE TABLE gadgets (id INTEGER PRIMARY KEY)")},
+	"sql/000003_create_gadgets.down.sql":  {Data: []byte("DROP TABLE gadgets")},
+	"sql/README.md":                       {Data: []byte("not a migration")},
+}
+
+func newTestMigrator(t *testing.T) *migration.Migrator {
+
+	t.Helper()
+
+	migrations, err := migration.Load(testFiles, "sql")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	return &migration.Migrator{Db: testdb.Open(t), Migrations: migrations}
+}
+
+// appliedVersions reads schema_migrations.
+func appliedVersions(t *testing.T, db *gorm.DB) []uint64 {
+
+	t.Helper()
+
+	versions := []uint64{}
+	if err := db.Table(tableMigrations).Order("version").Pluck("version", &versions).Error; err != nil {
+		t.Fatalf("read %s: %v", tableMigrations, err)
+	}
+
+	return versions
+}
+
+func versions(migrations []migration.Migration) []uint64 {
+
+	result := []uint64{}
+	for _, applied := range migrations {
+		result = append(result, applied.Version)
+	}
+
+	return result
+}
+
+func TestLoad(t *testing.T) {
+
+	migrations, err := migration.Load(testFiles, "sql")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if got := versions(migrations); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Errorf("versions = %v, want [1 2 3]", got)
+	}
+	if migrations[1].Name != "add_widget_size" || !strings.HasPrefix(migrations[1].Up, "ALTER TABLE") || !strings.HasPrefix(migrations[1].Down, "ALTER TABLE") {
+		t.Errorf("migration 2 = %+v", migrations[1])
+	}
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"sql/000001_create_widgets.up.sql": {Data: []byte("CREATE TABLE widgets (id INTEGER)")},
+			},
+		},
+		{
+			name: "two names for a version",
+			files: fstest.MapFS{
+				"sql/000001_create_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INTEGER)")},
+				"sql/000001_create_gadgets.down.sql": {Data: []byte("DROP TABLE gadgets")},
+			},
+		},
+		{
+			name:  "missing directory",
+			files: fstest.MapFS{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := migration.Load(test.files, "sql"); err == nil {
+				t.Errorf("Load: err = nil, want an error")
+			}
+		})
+	}
+}
+
+// TestLoadEmbedded checks the migrations of the binary, a missing file only
+// shows up on startup otherwise.
+func TestLoadEmbedded(t *testing.T) {
+
+	migrator, err := migration.NewMigrator(testdb.Open(t))
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	for i, embedded := range migrator.Migrations {
+		if embedded.Version != uint64(i+1) {
+			t.Errorf("migration %d has version %d, versions must follow each other", i+1, embedded.Version)
+		}
+	}
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+
+	migrator := newTestMigrator(t)
+	db := migrator.Db
+
+	// 1. Apply one step, then the rest
+	done, err := migrator.Up(1)
+	if err != nil {
+		t.Fatalf("Up(1): %v", err)
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("Up(1) applied %v, want [1]", got)
+	}
+	if got := appliedVersions(t, db); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("applied = %v, want [1]", got)
+	}
+
+	done, err = migrator.Up(0)
+	if err != nil {
+		t.Fatalf("Up(0): %v", err)
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []uint64{2, 3}) {
+		t.Errorf("Up(0) applied %v, want [2 3]", got)
+	}
+	if got := appliedVersions(t, db); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Errorf("applied = %v, want [1 2 3]", got)
+	}
+	if !db.Migrator().HasColumn("widgets", "size") || !db.Migrator().HasTable("gadgets") {
+		t.Errorf("schema of the migrations is missing")
+	}
+
+	// 2. Nothing is left to apply
+	done, err = migrator.Up(0)
+	if err != nil || len(done) != 0 {
+		t.Errorf("Up again applied %v, err %v, want nothing", versions(done), err)
+	}
+
+	// 3. Revert newest first
+	done, err = migrator.Down(2)
+	if err != nil {
+		t.Fatalf("Down(2): %v", err)
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []uint64{3, 2}) {
+		t.Errorf("Down(2) reverted %v, want [3 2]", got)
+	}
+	if got := appliedVersions(t, db); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("applied = %v, want [1]", got)
+	}
+	if db.Migrator().HasColumn("widgets", "size") || db.Migrator().HasTable("gadgets") {
+		t.Errorf("schema of the reverted migrations is still there")
+	}
+
+	done, err = migrator.Down(5)
+	if err != nil {
+		t.Fatalf("Down(5): %v", err)
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("Down(5) reverted %v, want [1]", got)
+	}
+	if got := appliedVersions(t, db); len(got) != 0 {
+		t.Errorf("applied = %v, want none", got)
+	}
+}
+
+func TestMigratorUpFails(t *testing.T) {
+
+	migrator := newTestMigrator(t)
+	migrator.Migrations[1].Up = "ALTER TABLE missing ADD COLUMN size INTEGER"
+
+	done, err := migrator.Up(0)
+	if err == nil || !strings.Contains(err.Error(), "000002_add_widget_size") {
+		t.Errorf("err = %v, want the error of migration 2", err)
+	}
+
+	// The failed migration is not recorded, the one before it is
+	if got := versions(done); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("applied %v, want [1]", got)
+	}
+	if got := appliedVersions(t, migrator.Db); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("applied = %v, want [1]", got)
+	}
+}
+
+func TestMigratorStatus(t *testing.T) {
+
+	migrator := newTestMigrator(t)
+
+	if _, err := migrator.Up(2); err != nil {
+		t.Fatalf("Up(2): %v", err)
+	}
+
+	// A version applied by a newer build
+	if err := migrator.Db.Exec("INSERT INTO " + tableMigrations + " (version, name, applied_at) VALUES (9, 'from_newer_build', CURRENT_TIMESTAMP)").Error; err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+
+	type state struct {
+		Version uint64
+		Applied bool
+		Missing bool
+	}
+	got := []state{}
+	for _, status := range statuses {
+		got = append(got, state{Version: status.Version, Applied: status.AppliedAt != nil, Missing: status.Missing})
+	}
+
+	want := []state{
+		{Version: 1, Applied: true},
+		{Version: 2, Applied: true},
+		{Version: 3},
+		{Version: 9, Applied: true, Missing: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("status = %+v, want %+v", got, want)
+	}
+
+	// Down cannot revert a version it has no files for
+	if _, err := migrator.Down(1); err == nil {
+		t.Errorf("Down of a missing migration: err = nil, want an error")
+	}
+}
+
+func TestMigratorEnsureUpToDate(t *testing.T) {
+
+	migrator := newTestMigrator(t)
+
+	if err := migrator.EnsureUpToDate(); !errors.Is(err, migration.ErrSchemaBehind) {
+		t.Errorf("empty database: err = %v, want %v", err, migration.ErrSchemaBehind)
+	}
+	if migrator.Db.Migrator().HasTable(tableMigrations) {
+		t.Errorf("EnsureUpToDate created %s, want it to only read", tableMigrations)
+	}
+
+	if _, err := migrator.Up(2); err != nil {
+		t.Fatalf("Up(2): %v", err)
+	}
+	err := migrator.EnsureUpToDate()
+	if !errors.Is(err, migration.ErrSchemaBehind) || !strings.Contains(err.Error(), "000003_create_gadgets") {
+		t.Errorf("one pending: err = %v, want %v naming 000003_create_gadgets", err, migration.ErrSchemaBehind)
+	}
+
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up(0): %v", err)
+	}
+	if err := migrator.EnsureUpToDate(); err != nil {
+		t.Errorf("up to date: %v", err)
+	}
+}
+
+// TestMigratorEmbedded runs the migrations of the binary down and up again.
+// They are written for Postgres, so it only runs with testdb.DsnEnv.
+func TestMigratorEmbedded(t *testing.T) {
+
+	if os.Getenv(testdb.DsnEnv) == "" {
+		t.Skip(testdb.DsnEnv + " is not set")
+	}
+
+	// testdb applied every migration already
+	migrator, err := migration.NewMigrator(testdb.Open(t))
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	all := versions(migrator.Migrations)
+
+	if got := appliedVersions(t, migrator.Db); !reflect.DeepEqual(got, all) {
+		t.Fatalf("applied = %v, want %v", got, all)
+	}
+
+	if _, err := migrator.Down(len(all)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if got := appliedVersions(t, migrator.Db); len(got) != 0 {
+		t.Errorf("applied after Down = %v, want none", got)
+	}
+	if migrator.Db.Migrator().HasTable("users") {
+		t.Errorf("users is still there after Down")
+	}
+
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got := appliedVersions(t, migrator.Db); !reflect.DeepEqual(got, all) {
+		t.Errorf("applied after Up = %v, want %v", got, all)
+	}
+	if err := migrator.EnsureUpToDate(); err != nil {
+		t.Errorf("EnsureUpToDate: %v", err)
+	}
+}
+
+// TestMigratorUpConcurrent runs two Up at the same time, the advisory lock
+// lets only one of them apply the migrations.
+func TestMigratorUpConcurrent(t *testing.T) {
+
+	if os.Getenv(testdb.DsnEnv) == "" {
+		t.Skip(testdb.DsnEnv + " is not set")
+	}
+
+	// testdb applied every migration already
+	migrator, err := migration.NewMigrator(testdb.Open(t))
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Down(len(migrator.Migrations)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	second := *migrator
+
+	results := make(chan []migration.Migration, 2)
+	errs := make(chan error, 2)
+	for _, current := range []*migration.Migrator{migrator, &second} {
+		go func(current *migration.Migrator) {
+			done, err := current.Up(0)
+			results <- done
+			errs <- err
+		}(current)
+	}
+
+	applied := 0
+	for i := 0; i < 2; i++ {
+		applied += len(<-results)
+		if err := <-errs; err != nil {
+			t.Errorf("Up: %v", err)
+		}
+	}
+
+	if applied != len(migrator.Migrations) {
+		t.Errorf("applied %d migrations in total, want %d", applied, len(migrator.Migrations))
+	}
+	if got := appliedVersions(t, migrator.Db); !reflect.DeepEqual(got, versions(migrator.Migrations)) {
+		t.Errorf("applied = %v, want %v", got, versions(migrator.Migrations))
+	}
+}
+
+// TestMigratorAdoptsAutoMigrate applies the migrations to the tables that
+// AutoMigrate created in the first release, before the columns of the later
+// features existed.
+func TestMigratorAdoptsAutoMigrate(t *testing.T) {
+
+	if os.Getenv(testdb.DsnEnv) == "" {
+		t.Skip(testdb.DsnEnv + " is not set")
+	}
+
+	migrator, err := migration.NewMigrator(testdb.Open(t))
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	db := migrator.Db
+
+	if _, err := migrator.Down(len(migrator.Migrations)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+
+	for _, statement := range []string{
+		"CREATE TABLE users (id BIGSERIAL PRIMARY KEY, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ, deleted_at TIMESTAMPTZ, username TEXT, email TEXT, password TEXT)",
+		"CREATE TABLE categories (id BIGSERIAL PRIMARY KEY, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ, deleted_at TIMESTAMPTZ, name TEXT)",
+		"CREATE TABLE projects (id BIGSERIAL PRIMARY KEY, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ, deleted_at TIMESTAMPTZ, category_id BIGINT, name VARCHAR(100), description TEXT, budget BIGINT)",
+		"CREATE TABLE project_items (id BIGSERIAL PRIMARY KEY, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ, deleted_at TIMESTAMPTZ, project_id BIGINT, name TEXT, budget_item BIGINT, status BOOLEAN)",
+		"INSERT INTO users (username, email, password) VALUES ('ana', 'Ana@Example.com ', 'hash')",
+		// 000001 indexes the owners, the README has them added before `migrate up`
+		"ALTER TABLE categories ADD COLUMN user_id BIGINT",
+		"ALTER TABLE projects ADD COLUMN user_id BIGINT",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("create the tables of AutoMigrate: %v", err)
+		}
+	}
+
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	for table, columns := range map[string][]string{
+		"users":         {"email_verified_at", "verification_sent_at", "failed_logins", "locked_until"},
+		"categories":    {"user_id"},
+		"projects":      {"user_id"},
+		"project_items": {"position"},
+	} {
+		for _, column := range columns {
+			if !db.Migrator().HasColumn(table, column) {
+				t.Errorf("%s.%s is missing", table, column)
+			}
+		}
+	}
+
+	var email string
+	if err := db.Table("users").Select("email").Take(&email).Error; err != nil {
+		t.Fatalf("read user: %v", err)
+	}
+	if email != "ana@example.com" {
+		t.Errorf("email = %q, want it normalized", email)
+	}
+}
+
+func TestCreate(t *testing.T) {
+
+	dir := filepath.Join(t.TempDir(), "sql")
+
+	up, down, err := migration.Create(dir, "Add Widgets!")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if filepath.Base(up) != "000001_add_widgets.up.sql" || filepath.Base(down) != "000001_add_widgets.down.sql" {
+		t.Errorf("created %s and %s", up, down)
+	}
+
+	// The next one gets the next version
+	up, _, err = migration.Create(dir, "add_gadgets")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if filepath.Base(up) != "000002_add_gadgets.up.sql" {
+		t.Errorf("created %s, want 000002_add_gadgets.up.sql", up)
+	}
+
+	migrations, err := migration.Load(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := versions(migrations); !reflect.DeepEqual(got, []uint64{1, 2}) {
+		t.Errorf("versions = %v, want [1 2]", got)
+	}
+
+	if _, _, err := migration.Create(dir, "!!!"); err == nil {
+		t.Errorf("Create with an empty name: err = nil, want an error")
+	}
+}
+
+func TestRun(t *testing.T) {
+
+	dir := t.TempDir()
+	connect := func() *gorm.DB {
+		return testdb.Open(t)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+		wantOut string
+	}{
+		{name: "no command", args: nil, wantErr: true},
+		{name: "unknown command", args: []string{"sideways"}, wantErr: true},
+		{name: "invalid step count", args: []string{"up", "zero"}, wantErr: true},
+		{name: "create without name", args: []string{"create"}, wantErr: true},
+		{name: "create", args: []string{"create", "add widgets"}, wantOut: "000001_add_widgets.up.sql"},
+		{name: "status", args: []string{"status"}, wantOut: "000001_init_schema\tpending"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			var out bytes.Buffer
+			err := migration.Run(test.args, dir, connect, &out)
+
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("err = nil, want an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if !strings.Contains(out.String(), test.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), test.wantOut)
+			}
+		})
+	}
+}
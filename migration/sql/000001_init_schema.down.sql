@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS project_items;
+DROP TABLE IF EXISTS projects;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS follow_users;
+DROP TABLE IF EXISTS user_profiles;
+DROP TABLE IF EXISTS users;
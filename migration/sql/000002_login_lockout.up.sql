@@ -0,0 +1,4 @@
+-- Wrong passwords in a row and the end of the lock they caused, see the
+-- lockout of the login.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
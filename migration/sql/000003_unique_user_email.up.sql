@@ -0,0 +1,6 @@
+-- Emails are stored lowercased and trimmed by the users service, older rows
+-- are brought in line first. The index fails when two live accounts share an
+-- email that only differs in case, merge or delete one of them and run the
+-- migration again.
+UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- The columns belong to the tables of 000001, they are dropped with them.
+SELECT 1;
@@ -0,0 +1,9 @@
+-- Columns added since the first release. 000001 creates them on new
+-- databases, tables kept from AutoMigrate get them here. Rows that existed
+-- before categories and projects had an owner keep a NULL user_id, see the
+-- README for the upgrade.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMPTZ;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS user_id BIGINT;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS user_id BIGINT;
+ALTER TABLE project_items ADD COLUMN IF NOT EXISTS position BIGINT;
@@ -4,6 +4,7 @@ import "gorm.io/gorm"
 
 type Category struct {
 	*gorm.Model
+	UserID   uint   `gorm:"index"`
 	Name     string `gorm:"type:varchar(100)"`
 	Projects []Project
 }
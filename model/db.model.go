@@ -1,9 +0,0 @@
-package model
-
-type DbConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-	Database string
-}
@@ -6,6 +6,7 @@ import (
 
 type Project struct {
 	*gorm.Model
+	UserID       uint `gorm:"index"`
 	CategoryID   uint
 	Category     Category `gorm:"foreignKey:CategoryID"`
 	Name         string   `gorm:"type:varchar(100)"`
@@ -13,3 +14,17 @@ type Project struct {
 	Budget       int
 	ProjectItems []ProjectItem
 }
+
+type ProjectCreateRequest struct {
+	CategoryID  uint   `json:"categoryId" validate:"required,number"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description"`
+	Budget      int    `json:"budget" validate:"gte=0"`
+}
+
+type ProjectUpdateRequest struct {
+	CategoryID  uint   `json:"categoryId" validate:"required,number"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description"`
+	Budget      int    `json:"budget" validate:"gte=0"`
+}
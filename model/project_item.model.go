@@ -5,8 +5,25 @@ import "gorm.io/gorm"
 type ProjectItem struct {
 	*gorm.Model
 	ProjectID  uint
-	Project    Project `gorm:"foreignKey:ProjectID"`
+	Project    Project `gorm:"foreignKey:ProjectID" json:"-"`
 	Name       string
 	BudgetItem int
 	Status     bool
+	Position   int
+}
+
+type ProjectItemCreateRequest struct {
+	Name       string `json:"name" validate:"required"`
+	BudgetItem int    `json:"budgetItem" validate:"gte=0"`
+	Status     bool   `json:"status"`
+}
+
+type ProjectItemUpdateRequest struct {
+	Name       string `json:"name" validate:"required"`
+	BudgetItem int    `json:"budgetItem" validate:"gte=0"`
+	Status     bool   `json:"status"`
+}
+
+type ProjectItemReorderRequest struct {
+	ItemIds []uint `json:"itemIds" validate:"required,min=1,dive,required"`
 }
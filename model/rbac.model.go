@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+const (
+	PermissionCategoryRead  = "category:read"
+	PermissionCategoryWrite = "category:write"
+	PermissionProjectRead   = "project:read"
+	PermissionProjectWrite  = "project:write"
+	PermissionRoleRead      = "role:read"
+	PermissionRoleWrite     = "role:write"
+)
+
+// DefaultRoles are created on startup, with the permissions every role gets.
+var DefaultRoles = map[string][]string{
+	RoleAdmin: {
+		PermissionCategoryRead,
+		PermissionCategoryWrite,
+		PermissionProjectRead,
+		PermissionProjectWrite,
+		PermissionRoleRead,
+		PermissionRoleWrite,
+	},
+	RoleUser: {
+		PermissionCategoryRead,
+		PermissionCategoryWrite,
+		PermissionProjectRead,
+		PermissionProjectWrite,
+	},
+}
+
+type Role struct {
+	*gorm.Model
+	Name        string
+	Description string
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+}
+
+type Permission struct {
+	*gorm.Model
+	Name        string
+	Description string
+}
+
+type UserRole struct {
+	UserID    uint
+	RoleID    uint
+	CreatedAt time.Time
+}
+
+type AssignRoleRequest struct {
+	RoleId uint `json:"roleId" validate:"required"`
+}
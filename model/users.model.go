@@ -1,23 +1,31 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	*gorm.Model
-	Username string
-	Email    string
-	Password string
+	Username           string
+	Email              string
+	Password           string
+	EmailVerifiedAt    *time.Time
+	VerificationSentAt *time.Time
+	FailedLogins       int
+	LockedUntil        *time.Time
 }
 
 type RegisterRequest struct {
-	Username string
-	Email    string
-	Password string
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
 }
 
 type LoginRequest struct {
-	Email    string
-	Password string
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 type Profile struct {
@@ -32,6 +40,8 @@ type Profile struct {
 }
 
 type UserWithProfile struct {
+	// FollowId is the id of the follow, the key of the pages of followers.
+	FollowId       uint
 	UserId         int
 	FollowedUserId int
 	Role           string
@@ -39,12 +49,12 @@ type UserWithProfile struct {
 }
 
 type ProfileUpdateRequestBody struct {
-	Bio       string
-	Role      string
-	Facebook  string
-	Instagram string
-	LinkedIn  string
-	Twitter   string
+	Bio       string `json:"bio" validate:"max=500"`
+	Role      string `json:"role" validate:"max=100"`
+	Facebook  string `json:"facebook" validate:"omitempty,url,max=255"`
+	Instagram string `json:"instagram" validate:"omitempty,url,max=255"`
+	LinkedIn  string `json:"linkedIn" validate:"omitempty,url,max=255"`
+	Twitter   string `json:"twitter" validate:"omitempty,url,max=255"`
 }
 
 type ProfileUpdateRequest struct {
@@ -65,3 +75,7 @@ type ProfileCreateRequest struct {
 	Linkedin  string
 	Twitter   string
 }
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
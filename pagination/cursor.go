@@ -0,0 +1,51 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+type Direction string
+
+const (
+	// Next selects the rows after the key.
+	Next Direction = "next"
+	// Prev selects the rows before the key.
+	Prev Direction = "prev"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the sort key of the row a page starts after, or ends before.
+// Clients get it encoded and send it back as is.
+type Cursor struct {
+	Key       []int64   `json:"k"`
+	Direction Direction `json:"d"`
+}
+
+func (cursor Cursor) Encode() string {
+
+	// A slice of numbers and a string always encode
+	content, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(content, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if len(cursor.Key) == 0 || (cursor.Direction != Next && cursor.Direction != Prev) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,139 @@
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"project-app/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FromQuery reads page, pageSize and cursor from the query string, a cursor
+// wins over page. A page size above MaxPageSize is refused rather than cut,
+// so a client never takes a short page for the last one.
+func FromQuery(c *fiber.Ctx) (Params, error) {
+
+	params := Params{Page: 1, PageSize: DefaultPageSize}
+	var fields []apperror.FieldError
+
+	if value := c.Query("pageSize"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		switch {
+		case err != nil || pageSize < 1:
+			fields = append(fields, apperror.FieldError{Field: "pageSize", Rule: "min", Param: "1", Message: "pageSize must be a number of at least 1"})
+		case pageSize > MaxPageSize:
+			fields = append(fields, apperror.FieldError{Field: "pageSize", Rule: "max", Param: strconv.Itoa(MaxPageSize), Message: "pageSize must be at most " + strconv.Itoa(MaxPageSize)})
+		default:
+			params.PageSize = pageSize
+		}
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			fields = append(fields, apperror.FieldError{Field: "page", Rule: "min", Param: "1", Message: "page must be a number of at least 1"})
+		} else {
+			params.Page = page
+		}
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := DecodeCursor(value)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: "cursor", Rule: "cursor", Message: "cursor must be a nextCursor or prevCursor of an earlier page"})
+		} else {
+			params = After(*cursor, params.PageSize)
+		}
+	}
+
+	if len(fields) > 0 {
+		return Params{}, apperror.Validation("Invalid pagination", fields...)
+	}
+
+	return params, nil
+}
+
+// Fields adds the page to the body of a list response, next to its data.
+func (page Page) Fields(body fiber.Map) fiber.Map {
+
+	if page.Page > 0 {
+		body["page"] = page.Page
+	}
+	body["pageSize"] = page.PageSize
+	body["totalPages"] = page.TotalPages
+	body["totalEntries"] = page.TotalEntries
+	if page.NextCursor != "" {
+		body["nextCursor"] = page.NextCursor
+	}
+	if page.PrevCursor != "" {
+		body["prevCursor"] = page.PrevCursor
+	}
+
+	return body
+}
+
+// SetLinks sets the Link header of RFC 5988 with the next and prev pages,
+// and the first and last pages counted from the start. The links keep the
+// other parameters of the query, like a search.
+func (page Page) SetLinks(c *fiber.Ctx) {
+
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		query = url.Values{}
+	}
+	base := c.BaseURL() + c.Path()
+
+	link := func(rel string, change func(query url.Values)) string {
+		linkQuery := url.Values{}
+		for key, values := range query {
+			linkQuery[key] = values
+		}
+		linkQuery.Del("page")
+		linkQuery.Del("cursor")
+		linkQuery.Set("pageSize", strconv.Itoa(page.PageSize))
+		change(linkQuery)
+		return "<" + base + "?" + linkQuery.Encode() + `>; rel="` + rel + `"`
+	}
+
+	pageNumber := func(number int) func(query url.Values) {
+		return func(query url.Values) { query.Set("page", strconv.Itoa(number)) }
+	}
+
+	cursor := func(value string) func(query url.Values) {
+		return func(query url.Values) { query.Set("cursor", value) }
+	}
+
+	// 1. Pages counted from the start link by number, pages of a cursor by cursor
+	var links []string
+	switch {
+	case page.Page > 0 && page.hasNext:
+		links = append(links, link("next", pageNumber(page.Page+1)))
+	case page.Page == 0 && page.NextCursor != "":
+		links = append(links, link("next", cursor(page.NextCursor)))
+	}
+
+	// A page past the end goes back to the last page
+	prev := page.Page - 1
+	if prev > page.TotalPages {
+		prev = page.TotalPages
+	}
+
+	switch {
+	case page.Page > 0 && page.hasPrev && prev >= 1:
+		links = append(links, link("prev", pageNumber(prev)))
+	case page.Page == 0 && page.PrevCursor != "":
+		links = append(links, link("prev", cursor(page.PrevCursor)))
+	}
+
+	// 2. The last page of an empty list is the first one
+	last := page.TotalPages
+	if last < 1 {
+		last = 1
+	}
+	links = append(links, link("first", pageNumber(1)))
+	links = append(links, link("last", pageNumber(last)))
+
+	c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
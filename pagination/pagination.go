@@ -0,0 +1,137 @@
+// Package pagination selects the pages of the list endpoints, counted from
+// the start with page and pageSize, or after a cursor of an earlier page.
+// Cursors stay right when rows are added or removed in front of the page,
+// page numbers are what the client can jump with.
+package pagination
+
+import (
+	"fmt"
+	"project-app/apperror"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// Params select a page. With a Cursor, Page is ignored.
+type Params struct {
+	Page     int
+	PageSize int
+	Cursor   *Cursor
+}
+
+// Offset selects the page counted from 1.
+func Offset(page int, pageSize int) Params {
+	return Params{Page: page, PageSize: pageSize}
+}
+
+// After selects the page of a cursor.
+func After(cursor Cursor, pageSize int) Params {
+	return Params{PageSize: pageSize, Cursor: &cursor}
+}
+
+// Page describes the page that was selected.
+type Page struct {
+	// Page is 0 for the page of a cursor, its number is not known.
+	Page         int
+	PageSize     int
+	TotalPages   int
+	TotalEntries int64
+	// NextCursor and PrevCursor are empty when there is no such page.
+	NextCursor string
+	PrevCursor string
+	hasNext    bool
+	hasPrev    bool
+}
+
+// Scope selects the page of a query sorted by columns, which together must
+// be unique. It fetches a row more than the page to know whether another
+// page follows, Finish removes it. Count the rows before, the scope limits
+// the query.
+func (params Params) Scope(columns ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+
+		if params.Cursor == nil {
+			return db.
+				Order(strings.Join(columns, ", ")).
+				Offset((params.Page - 1) * params.PageSize).
+				Limit(params.PageSize + 1)
+		}
+
+		// The cursor of another list does not fit
+		if len(params.Cursor.Key) != len(columns) {
+			_ = db.AddError(apperror.Validation("Invalid cursor").Wrap(ErrInvalidCursor))
+			return db
+		}
+
+		// A previous page is read backwards from the cursor, Finish turns it
+		operator, order := ">", ""
+		if params.Cursor.Direction == Prev {
+			operator, order = "<", " DESC"
+		}
+
+		orders := make([]string, len(columns))
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			orders[i] = column + order
+			values[i] = params.Cursor.Key[i]
+		}
+
+		condition := fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+		return db.
+			Where(condition, values...).
+			Order(strings.Join(orders, ", ")).
+			Limit(params.PageSize + 1)
+	}
+}
+
+// Finish takes the rows of a query with Scope and returns the rows of the
+// page in order, with the page they are. key returns the values of the
+// columns of Scope for a row.
+func Finish[T any](params Params, rows []T, totalEntries int64, key func(row T) []int64) ([]T, Page) {
+
+	page := Page{
+		PageSize:     params.PageSize,
+		TotalEntries: totalEntries,
+		TotalPages:   int((totalEntries + int64(params.PageSize) - 1) / int64(params.PageSize)),
+	}
+
+	// 1. The extra row tells whether there are more in the direction read
+	more := len(rows) > params.PageSize
+	if more {
+		rows = rows[:params.PageSize]
+	}
+
+	switch {
+	case params.Cursor == nil:
+		page.Page = params.Page
+		page.hasNext = more
+		page.hasPrev = params.Page > 1
+	case params.Cursor.Direction == Prev:
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+		page.hasNext = true
+		page.hasPrev = more
+	default:
+		page.hasNext = more
+		page.hasPrev = true
+	}
+
+	// 2. The cursors point at the first and the last row
+	if len(rows) > 0 {
+		if page.hasNext {
+			page.NextCursor = Cursor{Key: key(rows[len(rows)-1]), Direction: Next}.Encode()
+		}
+		if page.hasPrev {
+			page.PrevCursor = Cursor{Key: key(rows[0]), Direction: Prev}.Encode()
+		}
+	}
+
+	return rows, page
+}
@@ -0,0 +1,169 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"project-app/apperror"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCursor(t *testing.T) {
+
+	cursor := Cursor{Key: []int64{3, 42}, Direction: Prev}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil || !reflect.DeepEqual(*decoded, cursor) {
+		t.Fatalf("DecodeCursor(Encode()) = %+v, %v", decoded, err)
+	}
+
+	for _, value := range []string{"not base64!", "bm90IGpzb24", Cursor{Direction: Next}.Encode(), Cursor{Key: []int64{1}, Direction: "up"}.Encode()} {
+		if _, err := DecodeCursor(value); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) err = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
+
+func TestFinish(t *testing.T) {
+
+	key := func(row int) []int64 { return []int64{int64(row)} }
+
+	tests := []struct {
+		name     string
+		params   Params
+		rows     []int
+		wantRows []int
+		wantNext bool
+		wantPrev bool
+	}{
+		{name: "first page", params: Offset(1, 2), rows: []int{1, 2, 3}, wantRows: []int{1, 2}, wantNext: true},
+		{name: "last page", params: Offset(3, 2), rows: []int{5}, wantRows: []int{5}, wantPrev: true},
+		{name: "after a cursor", params: After(Cursor{Key: []int64{2}, Direction: Next}, 2), rows: []int{3, 4, 5}, wantRows: []int{3, 4}, wantNext: true, wantPrev: true},
+		{name: "before a cursor is read backwards", params: After(Cursor{Key: []int64{5}, Direction: Prev}, 2), rows: []int{4, 3, 2}, wantRows: []int{3, 4}, wantNext: true, wantPrev: true},
+		{name: "before the first page", params: After(Cursor{Key: []int64{3}, Direction: Prev}, 2), rows: []int{2, 1}, wantRows: []int{1, 2}, wantNext: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			rows, page := Finish(test.params, test.rows, 5, key)
+
+			if !reflect.DeepEqual(rows, test.wantRows) {
+				t.Errorf("rows = %v, want %v", rows, test.wantRows)
+			}
+			if (page.NextCursor != "") != test.wantNext || (page.PrevCursor != "") != test.wantPrev {
+				t.Errorf("next = %q, prev = %q, want %v and %v", page.NextCursor, page.PrevCursor, test.wantNext, test.wantPrev)
+			}
+			if page.TotalPages != 3 || page.TotalEntries != 5 {
+				t.Errorf("total pages = %d, total entries = %d, want 3 and 5", page.TotalPages, page.TotalEntries)
+			}
+		})
+	}
+}
+
+// serve is an app that answers GET /items with handler.
+func serve(handler fiber.Handler) *fiber.App {
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler})
+	app.Get("/items", handler)
+
+	return app
+}
+
+func TestFromQuery(t *testing.T) {
+
+	tests := []struct {
+		query      string
+		want       Params
+		wantFields []string
+	}{
+		{query: "", want: Offset(1, DefaultPageSize)},
+		{query: "page=3&pageSize=25", want: Offset(3, 25)},
+		{query: "cursor=" + Cursor{Key: []int64{7}, Direction: Next}.Encode(), want: After(Cursor{Key: []int64{7}, Direction: Next}, DefaultPageSize)},
+		{query: "page=0&pageSize=0", wantFields: []string{"pageSize", "page"}},
+		{query: "page=two&pageSize=101&cursor=bad", wantFields: []string{"pageSize", "page", "cursor"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+
+			var params Params
+			var err error
+			app := serve(func(c *fiber.Ctx) error {
+				params, err = FromQuery(c)
+				return nil
+			})
+			if _, errTest := app.Test(httptest.NewRequest(fiber.MethodGet, "/items?"+test.query, nil), -1); errTest != nil {
+				t.Fatal(errTest)
+			}
+
+			if test.wantFields == nil {
+				if err != nil || !reflect.DeepEqual(params, test.want) {
+					t.Errorf("FromQuery = %+v, %v, want %+v", params, err, test.want)
+				}
+				return
+			}
+
+			var appError *apperror.AppError
+			if !apperror.Is(err, apperror.KindValidation) || !errors.As(err, &appError) {
+				t.Fatalf("err = %v, want a validation error", err)
+			}
+
+			var fields []string
+			for _, field := range appError.Fields {
+				fields = append(fields, field.Field)
+			}
+			if !reflect.DeepEqual(fields, test.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, test.wantFields)
+			}
+		})
+	}
+}
+
+func TestSetLinks(t *testing.T) {
+
+	link := func(target string, page Page) string {
+		t.Helper()
+
+		app := serve(func(c *fiber.Ctx) error {
+			page.SetLinks(c)
+			return nil
+		})
+		res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "http://example.com"+target, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.Header.Get(fiber.HeaderLink)
+	}
+
+	// 1. Pages counted from the start keep the search
+	_, page := Finish(Offset(2, 2), []int{3, 4, 5}, 5, func(row int) []int64 { return []int64{int64(row)} })
+	got := link("/items?name=a+b&page=2&pageSize=2", page)
+	want := strings.Join([]string{
+		`<http://example.com/items?name=a+b&page=3&pageSize=2>; rel="next"`,
+		`<http://example.com/items?name=a+b&page=1&pageSize=2>; rel="prev"`,
+		`<http://example.com/items?name=a+b&page=1&pageSize=2>; rel="first"`,
+		`<http://example.com/items?name=a+b&page=3&pageSize=2>; rel="last"`,
+	}, ", ")
+	if got != want {
+		t.Errorf("Link =\n%s\nwant\n%s", got, want)
+	}
+
+	// 2. Pages of a cursor link by cursor
+	_, page = Finish(After(Cursor{Key: []int64{2}, Direction: Next}, 2), []int{3, 4, 5}, 5, func(row int) []int64 { return []int64{int64(row)} })
+	got = link("/items?cursor=abc", page)
+	if !strings.Contains(got, "cursor="+page.NextCursor+`&pageSize=2>; rel="next"`) || !strings.Contains(got, "cursor="+page.PrevCursor+`&pageSize=2>; rel="prev"`) {
+		t.Errorf("Link = %s", got)
+	}
+
+	// 3. An empty list has a single page
+	_, page = Finish(Offset(1, 10), []int{}, 0, func(row int) []int64 { return []int64{int64(row)} })
+	got = link("/items", page)
+	if got != `<http://example.com/items?page=1&pageSize=10>; rel="first", <http://example.com/items?page=1&pageSize=10>; rel="last"` {
+		t.Errorf("Link of an empty list = %s", got)
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets that are full again are dropped.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// refill adds the tokens earned since the last take.
+func (bucket *bucket) refill(now time.Time) {
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(float64(bucket.limit.Burst), bucket.tokens+elapsed*bucket.limit.Rate)
+	bucket.last = now
+}
+
+// MemoryStore keeps the buckets in the memory of the process.
+type MemoryStore struct {
+	// Now is the clock of the buckets, time.Now unless a test replaces it.
+	Now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+
+	if err := ctx.Err(); err != nil {
+		return Decision{}, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.Now()
+	store.sweep(now)
+
+	// 1. A new key starts with a full bucket
+	current, ok := store.buckets[key]
+	if !ok {
+		current = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		store.buckets[key] = current
+	}
+	current.limit = limit
+	current.refill(now)
+
+	// 2. Take a token, or tell when the next one is earned
+	if current.tokens >= 1 {
+		current.tokens--
+		return Decision{Allowed: true, Remaining: int(current.tokens)}, nil
+	}
+
+	if limit.Rate <= 0 {
+		return Decision{RetryAfter: time.Duration(math.MaxInt64)}, nil
+	}
+
+	wait := time.Duration((1 - current.tokens) / limit.Rate * float64(time.Second))
+	return Decision{RetryAfter: wait}, nil
+}
+
+// sweep drops the buckets that are full, a new bucket of their key would be
+// the same. It keeps the map from growing with every IP that ever came.
+func (store *MemoryStore) sweep(now time.Time) {
+
+	if now.Sub(store.lastSweep) < sweepInterval {
+		return
+	}
+	store.lastSweep = now
+
+	for key, current := range store.buckets {
+		current.refill(now)
+		if current.tokens >= float64(current.limit.Burst) {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package ratelimit limits how often a key, like a client IP, can do
+// something, with a token bucket per key.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit lets Burst requests through at once, then Rate requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute lets n requests through at once, then refills the bucket over a
+// minute.
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+type Decision struct {
+	Allowed bool
+	// Remaining is how many requests can follow right away.
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, when this one
+	// was not.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. The MemoryStore is enough for a single instance,
+// instances that share their limits need a Store on a shared database like
+// Redis.
+type Store interface {
+	// Take takes a token from the bucket of key, if there is one.
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.Now = func() time.Time { return now }
+
+	ctx := context.Background()
+	limit := Limit{Rate: 1, Burst: 2}
+
+	take := func(key string) Decision {
+		t.Helper()
+		decision, err := store.Take(ctx, key, limit)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		return decision
+	}
+
+	// 1. The burst goes through, then the bucket is empty
+	if decision := take("a"); !decision.Allowed || decision.Remaining != 1 {
+		t.Fatalf("first take = %+v", decision)
+	}
+	if decision := take("a"); !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("second take = %+v", decision)
+	}
+	if decision := take("a"); decision.Allowed || decision.RetryAfter != time.Second {
+		t.Fatalf("third take = %+v, want denied for 1s", decision)
+	}
+
+	// 2. Other keys have their own bucket
+	if decision := take("b"); !decision.Allowed {
+		t.Fatalf("take of another key = %+v", decision)
+	}
+
+	// 3. The bucket refills with time
+	now = now.Add(500 * time.Millisecond)
+	if decision := take("a"); decision.Allowed || decision.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take after 0.5s = %+v, want denied for 0.5s", decision)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if decision := take("a"); !decision.Allowed {
+		t.Fatalf("take after 1s = %+v", decision)
+	}
+
+	// 4. Full buckets are swept
+	now = now.Add(time.Hour)
+	take("c")
+	if _, ok := store.buckets["a"]; ok {
+		t.Errorf("the full bucket of a was not swept")
+	}
+}
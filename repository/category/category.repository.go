@@ -1,19 +1,24 @@
 package category
 
 import (
-	"project-app/helper"
+	"context"
+	"errors"
+	"project-app/apperror"
 	"project-app/model"
 	categoryModel "project-app/model"
+	"project-app/pagination"
+	"project-app/tracing"
+	"project-app/transaction"
 
-	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
 type CategoryRepository interface {
-	Create(ctx *fiber.Ctx, req *model.Category) error
-	Update(ctx *fiber.Ctx, id int, req *model.Category) error
-	Delete(ctx *fiber.Ctx, id int) error
-	FindAll(ctx *fiber.Ctx, page int, pageSize int, searchQuery string) ([]categoryModel.Category, int64, error)
+	Create(ctx context.Context, req *model.Category) error
+	Update(ctx context.Context, userId uint, id int, req *model.Category) error
+	Delete(ctx context.Context, userId uint, id int) error
+	FindById(ctx context.Context, userId uint, id int) (*model.Category, error)
+	FindAll(ctx context.Context, userId uint, params pagination.Params, searchQuery string) ([]categoryModel.Category, pagination.Page, error)
 }
 
 type CategoryRepositoryImpl struct {
@@ -28,13 +33,14 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 
 var tableName = "categories"
 
-func (repository *CategoryRepositoryImpl) Create(ctx *fiber.Ctx, req *model.Category) error {
+func (repository *CategoryRepositoryImpl) Create(ctx context.Context, req *model.Category) error {
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	ctx, span := tracing.Start(ctx, "CategoryRepository.Create")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
 
 	err := tx.
-		WithContext(ctx.Context()).
 		Table(tableName).
 		Create(req).
 		Error
@@ -46,14 +52,16 @@ func (repository *CategoryRepositoryImpl) Create(ctx *fiber.Ctx, req *model.Cate
 	return nil
 }
 
-func (repository *CategoryRepositoryImpl) Update(ctx *fiber.Ctx, id int, req *model.Category) error {
+func (repository *CategoryRepositoryImpl) Update(ctx context.Context, userId uint, id int, req *model.Category) error {
+
+	ctx, span := tracing.Start(ctx, "CategoryRepository.Update")
+	defer span.End()
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	tx := transaction.DB(ctx, repository.Db)
 
-	err := tx.WithContext(ctx.Context()).
+	err := tx.
 		Table(tableName).
-		Where("id = ? ", id).
+		Where("id = ? AND user_id = ?", id, userId).
 		Updates(req).
 		Error
 
@@ -64,13 +72,16 @@ func (repository *CategoryRepositoryImpl) Update(ctx *fiber.Ctx, id int, req *mo
 	return nil
 }
 
-func (repository *CategoryRepositoryImpl) Delete(ctx *fiber.Ctx, id int) error {
+func (repository *CategoryRepositoryImpl) Delete(ctx context.Context, userId uint, id int) error {
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	ctx, span := tracing.Start(ctx, "CategoryRepository.Delete")
+	defer span.End()
 
-	err := tx.WithContext(ctx.Context()).
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
 		Table(tableName).
+		Where("user_id = ?", userId).
 		Delete(&categoryModel.Category{}, id).Error
 
 	if err != nil {
@@ -80,38 +91,65 @@ func (repository *CategoryRepositoryImpl) Delete(ctx *fiber.Ctx, id int) error {
 	return nil
 }
 
-func (repository *CategoryRepositoryImpl) FindAll(ctx *fiber.Ctx, page int, pageSize int, searchQuery string) ([]categoryModel.Category, int64, error) {
+func (repository *CategoryRepositoryImpl) FindById(ctx context.Context, userId uint, id int) (*model.Category, error) {
+
+	ctx, span := tracing.Start(ctx, "CategoryRepository.FindById")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.Category
+	err := tx.
+		Table(tableName).
+		Where("id = ? AND user_id = ?", id, userId).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Category not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *CategoryRepositoryImpl) FindAll(ctx context.Context, userId uint, params pagination.Params, searchQuery string) ([]categoryModel.Category, pagination.Page, error) {
+
+	ctx, span := tracing.Start(ctx, "CategoryRepository.FindAll")
+	defer span.End()
 
 	var category []categoryModel.Category
 	var totalCount int64
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
-
-	// Offset
-	offset := (page - 1) * pageSize
+	tx := transaction.DB(ctx, repository.Db)
 
 	// Query
-	query := tx.WithContext(ctx.Context()).Table(tableName)
+	query := tx.Table(tableName).Where("user_id = ? AND deleted_at IS NULL", userId)
 
 	if searchQuery != "" {
-		query = query.Where("category LIKE ? ", "%"+searchQuery+"%")
+		query = query.Where("name LIKE ? ", "%"+searchQuery+"%")
 	}
 
 	err := query.Count(&totalCount).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, pagination.Page{}, err
 	}
 
 	errResult := query.
-		Offset(offset).
-		Limit(pageSize).
+		Scopes(params.Scope("id")).
 		Find(&category).
 		Error
 
 	if errResult != nil {
-		return nil, 0, errResult
+		return nil, pagination.Page{}, errResult
 	}
 
-	return category, totalCount, nil
+	category, page := pagination.Finish(params, category, totalCount, func(row categoryModel.Category) []int64 {
+		return []int64{int64(row.ID)}
+	})
+
+	return category, page, nil
 }
@@ -0,0 +1,313 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/schema"
+	"project-app/testdb"
+
+	"gorm.io/gorm"
+)
+
+// seedCategories gives user 1 the categories Design 1 to Design 5 and
+// Marketing, and user 2 one category of its own.
+func seedCategories(t *testing.T, db *gorm.DB) {
+
+	t.Helper()
+
+	for _, name := range []string{"Design 1", "Design 2", "Design 3", "Design 4", "Design 5", "Marketing"} {
+		testdb.Seed(t, db, &schema.Category{UserID: 1, Name: name})
+	}
+	testdb.Seed(t, db, &schema.Category{UserID: 2, Name: "Design of user 2"})
+}
+
+func categoryNames(categories []model.Category) []string {
+
+	names := []string{}
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+
+	return names
+}
+
+func TestCategoryRepositoryFindAll(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedCategories(t, db)
+	repository := NewCategoryRepository(db)
+
+	tests := []struct {
+		name        string
+		userId      uint
+		page        int
+		pageSize    int
+		searchQuery string
+		wantNames   []string
+		wantTotal   int64
+	}{
+		{
+			name:      "first page",
+			userId:    1,
+			page:      1,
+			pageSize:  4,
+			wantNames: []string{"Design 1", "Design 2", "Design 3", "Design 4"},
+			wantTotal: 6,
+		},
+		{
+			name:      "last page is partial",
+			userId:    1,
+			page:      2,
+			pageSize:  4,
+			wantNames: []string{"Design 5", "Marketing"},
+			wantTotal: 6,
+		},
+		{
+			name:      "page past the end",
+			userId:    1,
+			page:      3,
+			pageSize:  4,
+			wantNames: []string{},
+			wantTotal: 6,
+		},
+		{
+			name:        "search by name",
+			userId:      1,
+			page:        1,
+			pageSize:    10,
+			searchQuery: "Design",
+			wantNames:   []string{"Design 1", "Design 2", "Design 3", "Design 4", "Design 5"},
+			wantTotal:   5,
+		},
+		{
+			name:        "search is paginated",
+			userId:      1,
+			page:        2,
+			pageSize:    2,
+			searchQuery: "Design",
+			wantNames:   []string{"Design 3", "Design 4"},
+			wantTotal:   5,
+		},
+		{
+			name:        "search in the middle of the name",
+			userId:      1,
+			page:        1,
+			pageSize:    10,
+			searchQuery: "keti",
+			wantNames:   []string{"Marketing"},
+			wantTotal:   1,
+		},
+		{
+			name:        "search without matches",
+			userId:      1,
+			page:        1,
+			pageSize:    10,
+			searchQuery: "Finance",
+			wantNames:   []string{},
+			wantTotal:   0,
+		},
+		{
+			name:      "only the categories of the user",
+			userId:    2,
+			page:      1,
+			pageSize:  10,
+			wantNames: []string{"Design of user 2"},
+			wantTotal: 1,
+		},
+		{
+			name:      "user without categories",
+			userId:    3,
+			page:      1,
+			pageSize:  10,
+			wantNames: []string{},
+			wantTotal: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			categories, page, err := repository.FindAll(context.Background(), test.userId, pagination.Offset(test.page, test.pageSize), test.searchQuery)
+			if err != nil {
+				t.Fatalf("FindAll: %v", err)
+			}
+
+			if names := categoryNames(categories); !reflect.DeepEqual(names, test.wantNames) {
+				t.Errorf("names = %q, want %q", names, test.wantNames)
+			}
+
+			if page.TotalEntries != test.wantTotal {
+				t.Errorf("total = %d, want %d", page.TotalEntries, test.wantTotal)
+			}
+		})
+	}
+}
+
+func TestCategoryRepositoryFindAllSkipsDeleted(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedCategories(t, db)
+	repository := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	if err := repository.Delete(ctx, 1, 1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	categories, page, err := repository.FindAll(ctx, 1, pagination.Offset(1, 10), "")
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+
+	if len(categories) != 5 || page.TotalEntries != 5 {
+		t.Errorf("got %d categories and total %d, want 5 and 5", len(categories), page.TotalEntries)
+	}
+}
+
+func TestCategoryRepositoryFindAllCursor(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedCategories(t, db)
+	repository := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	find := func(params pagination.Params) ([]string, pagination.Page) {
+		t.Helper()
+		categories, page, err := repository.FindAll(ctx, 1, params, "")
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		return categoryNames(categories), page
+	}
+
+	cursor := func(value string) pagination.Cursor {
+		t.Helper()
+		decoded, err := pagination.DecodeCursor(value)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", value, err)
+		}
+		return *decoded
+	}
+
+	// 1. Forward from the first page to the last
+	_, first := find(pagination.Offset(1, 4))
+	if first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	names, last := find(pagination.After(cursor(first.NextCursor), 4))
+	if !reflect.DeepEqual(names, []string{"Design 5", "Marketing"}) {
+		t.Errorf("names after the first page = %q", names)
+	}
+	if last.NextCursor != "" || last.PrevCursor == "" || last.TotalEntries != 6 || last.TotalPages != 2 {
+		t.Errorf("last page = %+v", last)
+	}
+
+	// 2. Back from the last page, in order
+	names, back := find(pagination.After(cursor(last.PrevCursor), 4))
+	if !reflect.DeepEqual(names, []string{"Design 1", "Design 2", "Design 3", "Design 4"}) {
+		t.Errorf("names before the last page = %q", names)
+	}
+	if back.PrevCursor != "" || back.NextCursor == "" {
+		t.Errorf("page before the last = %+v", back)
+	}
+
+	// 3. A cursor of a list sorted otherwise does not fit
+	_, _, err := repository.FindAll(ctx, 1, pagination.After(pagination.Cursor{Key: []int64{1, 2}, Direction: pagination.Next}, 4), "")
+	if !apperror.Is(err, apperror.KindValidation) || !errors.Is(err, pagination.ErrInvalidCursor) {
+		t.Errorf("err = %v, want an invalid cursor", err)
+	}
+}
+
+func TestCategoryRepositoryFindById(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedCategories(t, db)
+	repository := NewCategoryRepository(db)
+
+	tests := []struct {
+		name     string
+		userId   uint
+		id       int
+		wantName string
+		wantKind apperror.Kind
+	}{
+		{name: "own category", userId: 1, id: 6, wantName: "Marketing"},
+		{name: "category of another user", userId: 2, id: 6, wantKind: apperror.KindNotFound},
+		{name: "missing category", userId: 1, id: 100, wantKind: apperror.KindNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			category, err := repository.FindById(context.Background(), test.userId, test.id)
+
+			if test.wantKind != "" {
+				if !apperror.Is(err, test.wantKind) {
+					t.Fatalf("err = %v, want kind %s", err, test.wantKind)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FindById: %v", err)
+			}
+
+			if category.Name != test.wantName {
+				t.Errorf("name = %q, want %q", category.Name, test.wantName)
+			}
+		})
+	}
+}
+
+func TestCategoryRepositoryUpdateAndDelete(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedCategories(t, db)
+	repository := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	// 1. Another user can neither rename nor delete the category
+	if err := repository.Update(ctx, 2, 6, &model.Category{Name: "Sales"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repository.Delete(ctx, 2, 6); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	category, err := repository.FindById(ctx, 1, 6)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if category.Name != "Marketing" {
+		t.Errorf("name = %q after update by another user, want %q", category.Name, "Marketing")
+	}
+
+	// 2. The owner can
+	if err := repository.Update(ctx, 1, 6, &model.Category{Name: "Sales"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	category, err = repository.FindById(ctx, 1, 6)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if category.Name != "Sales" {
+		t.Errorf("name = %q, want %q", category.Name, "Sales")
+	}
+
+	if err := repository.Delete(ctx, 1, 6); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err = repository.FindById(ctx, 1, 6)
+	if !apperror.Is(err, apperror.KindNotFound) {
+		t.Errorf("err = %v after delete, want not found", err)
+	}
+}
@@ -0,0 +1,126 @@
+package fake
+
+import (
+	"context"
+	"strings"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	categoryRepository "project-app/repository/category"
+)
+
+var _ categoryRepository.CategoryRepository = (*CategoryRepository)(nil)
+
+type CategoryRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewCategoryRepository(db *Database) *CategoryRepository {
+	return &CategoryRepository{
+		Db: db,
+	}
+}
+
+func (repository *CategoryRepository) Create(ctx context.Context, req *model.Category) error {
+
+	if err := repository.call(ctx, "Create"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row := *req
+	row.Model = repository.Db.newModel("categories")
+	row.Projects = nil
+	repository.Db.tables.categories[row.ID] = row
+
+	req.Model = cloneModel(row.Model)
+	return nil
+}
+
+func (repository *CategoryRepository) Update(ctx context.Context, userId uint, id int, req *model.Category) error {
+
+	if err := repository.call(ctx, "Update"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.categories[uint(id)]
+	if !ok || row.UserID != userId {
+		return nil
+	}
+
+	// Updates with a struct only writes the fields that are set
+	if req.Name != "" {
+		row.Name = req.Name
+	}
+	row.Model = touch(row.Model)
+	repository.Db.tables.categories[row.ID] = row
+
+	return nil
+}
+
+func (repository *CategoryRepository) Delete(ctx context.Context, userId uint, id int) error {
+
+	if err := repository.call(ctx, "Delete"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.categories[uint(id)]
+	if ok && row.UserID == userId {
+		delete(repository.Db.tables.categories, row.ID)
+	}
+
+	return nil
+}
+
+func (repository *CategoryRepository) FindById(ctx context.Context, userId uint, id int) (*model.Category, error) {
+
+	if err := repository.call(ctx, "FindById"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.categories[uint(id)]
+	if !ok || row.UserID != userId {
+		return nil, apperror.NotFound("Category not found")
+	}
+
+	row.Model = cloneModel(row.Model)
+	return &row, nil
+}
+
+func (repository *CategoryRepository) FindAll(ctx context.Context, userId uint, params pagination.Params, searchQuery string) ([]model.Category, pagination.Page, error) {
+
+	if err := repository.call(ctx, "FindAll"); err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	matches := []model.Category{}
+	for _, id := range sortedIds(repository.Db.tables.categories) {
+		row := repository.Db.tables.categories[id]
+		if row.UserID != userId || !strings.Contains(row.Name, searchQuery) {
+			continue
+		}
+
+		row.Model = cloneModel(row.Model)
+		matches = append(matches, row)
+	}
+
+	return paginate(matches, params, func(row model.Category) []int64 {
+		return []int64{int64(row.ID)}
+	})
+}
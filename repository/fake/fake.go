@@ -0,0 +1,370 @@
+// Package fake has in-memory implementations of the repository interfaces,
+// so services and handlers can be tested without a database. They are safe
+// for concurrent use and follow the rules of the real repositories: rows
+// belong to a user, missing rows are apperror.NotFound and lists are ordered
+// by id.
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/transaction"
+
+	"gorm.io/gorm"
+)
+
+// Database holds the rows of the fakes. Repositories made from the same
+// Database see each other's rows, like the tables of one database.
+type Database struct {
+	mu     sync.Mutex
+	tables tables
+}
+
+type userRoleKey struct {
+	UserID uint
+	RoleID uint
+}
+
+// follow is a row of follow_users, UserID follows FollowingUserID.
+type follow struct {
+	ID              uint
+	UserID          uint
+	FollowingUserID uint
+}
+
+// tables stores rows by value and never changes the pointers inside a row,
+// a write replaces them. A copy of the maps is then a snapshot.
+type tables struct {
+	lastIds        map[string]uint
+	users          map[uint]model.User
+	profiles       map[uint]model.Profile
+	categories     map[uint]model.Category
+	projects       map[uint]model.Project
+	projectItems   map[uint]model.ProjectItem
+	roles          map[uint]model.Role
+	permissions    map[uint]model.Permission
+	userRoles      map[userRoleKey]model.UserRole
+	follows        map[uint]follow
+	refreshTokens  map[uint]model.RefreshToken
+	passwordResets map[uint]model.PasswordReset
+}
+
+func NewDatabase() *Database {
+
+	return &Database{
+		tables: tables{
+			lastIds:        map[string]uint{},
+			users:          map[uint]model.User{},
+			profiles:       map[uint]model.Profile{},
+			categories:     map[uint]model.Category{},
+			projects:       map[uint]model.Project{},
+			projectItems:   map[uint]model.ProjectItem{},
+			roles:          map[uint]model.Role{},
+			permissions:    map[uint]model.Permission{},
+			userRoles:      map[userRoleKey]model.UserRole{},
+			follows:        map[uint]follow{},
+			refreshTokens:  map[uint]model.RefreshToken{},
+			passwordResets: map[uint]model.PasswordReset{},
+		},
+	}
+}
+
+func (current tables) copy() tables {
+
+	return tables{
+		lastIds:        copyMap(current.lastIds),
+		users:          copyMap(current.users),
+		profiles:       copyMap(current.profiles),
+		categories:     copyMap(current.categories),
+		projects:       copyMap(current.projects),
+		projectItems:   copyMap(current.projectItems),
+		roles:          copyMap(current.roles),
+		permissions:    copyMap(current.permissions),
+		userRoles:      copyMap(current.userRoles),
+		follows:        copyMap(current.follows),
+		refreshTokens:  copyMap(current.refreshTokens),
+		passwordResets: copyMap(current.passwordResets),
+	}
+}
+
+// SeedRoles creates model.DefaultRoles, as the app does on startup.
+func (db *Database) SeedRoles() {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	names := make([]string, 0, len(model.DefaultRoles))
+	for name := range model.DefaultRoles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	permissionIds := map[string]uint{}
+	for _, name := range names {
+		role := model.Role{Model: db.newModel("roles"), Name: name}
+
+		for _, permissionName := range model.DefaultRoles[name] {
+			id, ok := permissionIds[permissionName]
+			if !ok {
+				permission := model.Permission{Model: db.newModel("permissions"), Name: permissionName}
+				db.tables.permissions[permission.ID] = permission
+				permissionIds[permissionName] = permission.ID
+				id = permission.ID
+			}
+			role.Permissions = append(role.Permissions, db.tables.permissions[id])
+		}
+
+		db.tables.roles[role.ID] = role
+	}
+}
+
+// Follow makes userId follow followingUserId. The app has no route to follow
+// a user yet, the rows come from elsewhere.
+func (db *Database) Follow(userId uint, followingUserId uint) {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	id := db.newModel("follow_users").ID
+	db.tables.follows[id] = follow{ID: id, UserID: userId, FollowingUserID: followingUserId}
+}
+
+// newModel returns the model of a new row in table, with the next id.
+func (db *Database) newModel(table string) *gorm.Model {
+
+	db.tables.lastIds[table]++
+	now := time.Now()
+
+	return &gorm.Model{
+		ID:        db.tables.lastIds[table],
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// touch returns a copy of the model with a new UpdatedAt.
+func touch(model *gorm.Model) *gorm.Model {
+
+	updated := *model
+	updated.UpdatedAt = time.Now()
+
+	return &updated
+}
+
+// cloneModel keeps callers from changing a stored row through the pointer.
+func cloneModel(model *gorm.Model) *gorm.Model {
+
+	if model == nil {
+		return nil
+	}
+
+	clone := *model
+	return &clone
+}
+
+func copyMap[K comparable, V any](source map[K]V) map[K]V {
+
+	result := make(map[K]V, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+
+	return result
+}
+
+func sortedIds[V any](rows map[uint]V) []uint {
+
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// paginate returns the rows of the page and the page, like Scope and Finish
+// of pagination on rows sorted by key.
+func paginate[V any](rows []V, params pagination.Params, key func(row V) []int64) ([]V, pagination.Page, error) {
+
+	selected := rows
+	if params.Cursor == nil {
+		offset := (params.Page - 1) * params.PageSize
+		if offset < 0 || offset > len(rows) {
+			offset = len(rows)
+		}
+		selected = rows[offset:]
+	} else {
+		if len(rows) > 0 && len(params.Cursor.Key) != len(key(rows[0])) {
+			return nil, pagination.Page{}, apperror.Validation("Invalid cursor").Wrap(pagination.ErrInvalidCursor)
+		}
+
+		// A previous page is read backwards from the cursor
+		selected = []V{}
+		for _, row := range rows {
+			order := compareKeys(key(row), params.Cursor.Key)
+			if params.Cursor.Direction == pagination.Next && order > 0 {
+				selected = append(selected, row)
+			}
+			if params.Cursor.Direction == pagination.Prev && order < 0 {
+				selected = append([]V{row}, selected...)
+			}
+		}
+	}
+
+	if len(selected) > params.PageSize+1 {
+		selected = selected[:params.PageSize+1]
+	}
+
+	result, page := pagination.Finish(params, append([]V{}, selected...), int64(len(rows)), key)
+	return result, page, nil
+}
+
+func compareKeys(a []int64, b []int64) int {
+
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Errors makes the methods of a fake fail, to test the error paths of the
+// code that uses it. Methods are named as in the repository interface, for
+// example "FindById".
+type Errors struct {
+	mu    sync.Mutex
+	fail  map[string]error
+	once  map[string][]error
+	calls map[string]int
+}
+
+// Fail makes every call of method return err, until Clear.
+func (errs *Errors) Fail(method string, err error) {
+
+	errs.mu.Lock()
+	defer errs.mu.Unlock()
+
+	if errs.fail == nil {
+		errs.fail = map[string]error{}
+	}
+	errs.fail[method] = err
+}
+
+// FailNext makes only the next call of method return err. Calling it again
+// queues more errors for the calls after that.
+func (errs *Errors) FailNext(method string, err error) {
+
+	errs.mu.Lock()
+	defer errs.mu.Unlock()
+
+	if errs.once == nil {
+		errs.once = map[string][]error{}
+	}
+	errs.once[method] = append(errs.once[method], err)
+}
+
+// Clear removes the errors of Fail and FailNext.
+func (errs *Errors) Clear() {
+
+	errs.mu.Lock()
+	defer errs.mu.Unlock()
+
+	errs.fail = nil
+	errs.once = nil
+}
+
+// Calls returns how often method was called, failed calls included.
+func (errs *Errors) Calls(method string) int {
+
+	errs.mu.Lock()
+	defer errs.mu.Unlock()
+
+	return errs.calls[method]
+}
+
+// call records a call of method and returns the error it has to fail with:
+// an injected error first, then the error of a cancelled context.
+func (errs *Errors) call(ctx context.Context, method string) error {
+
+	errs.mu.Lock()
+	defer errs.mu.Unlock()
+
+	if errs.calls == nil {
+		errs.calls = map[string]int{}
+	}
+	errs.calls[method]++
+
+	if queued := errs.once[method]; len(queued) > 0 {
+		errs.once[method] = queued[1:]
+		return queued[0]
+	}
+
+	if err := errs.fail[method]; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+var _ transaction.Manager = (*TransactionManager)(nil)
+
+// TransactionManager is the transaction.Manager of the fakes. When fn fails
+// the rows of Db are restored as they were before Do, including changes of
+// other goroutines in the meantime, so tests should not share a Database
+// between concurrent transactions.
+type TransactionManager struct {
+	Db *Database
+	Errors
+}
+
+func NewTransactionManager(db *Database) *TransactionManager {
+	return &TransactionManager{
+		Db: db,
+	}
+}
+
+func (manager *TransactionManager) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+
+	if err := manager.call(ctx, "Do"); err != nil {
+		return err
+	}
+
+	manager.Db.mu.Lock()
+	snapshot := manager.Db.tables.copy()
+	manager.Db.mu.Unlock()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			manager.restore(snapshot)
+			panic(recovered)
+		}
+
+		if err != nil {
+			manager.restore(snapshot)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func (manager *TransactionManager) restore(snapshot tables) {
+
+	manager.Db.mu.Lock()
+	defer manager.Db.mu.Unlock()
+
+	// Ids are not reused, like sequences after a rollback
+	snapshot.lastIds = manager.Db.tables.lastIds
+	manager.Db.tables = snapshot
+}
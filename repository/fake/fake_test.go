@@ -0,0 +1,222 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+)
+
+func TestErrors(t *testing.T) {
+
+	repository := NewCategoryRepository(NewDatabase())
+	ctx := context.Background()
+	errDown := errors.New("database is down")
+	errOnce := errors.New("connection reset")
+
+	// 1. FailNext only fails the next call, Fail every call until Clear
+	repository.FailNext("Create", errOnce)
+	repository.Fail("FindAll", errDown)
+
+	if err := repository.Create(ctx, &model.Category{UserID: 1, Name: "Design"}); err != errOnce {
+		t.Errorf("first Create: err = %v, want %v", err, errOnce)
+	}
+	if err := repository.Create(ctx, &model.Category{UserID: 1, Name: "Design"}); err != nil {
+		t.Errorf("second Create: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := repository.FindAll(ctx, 1, pagination.Offset(1, 10), ""); err != errDown {
+			t.Errorf("FindAll: err = %v, want %v", err, errDown)
+		}
+	}
+
+	repository.Clear()
+	categories, page, err := repository.FindAll(ctx, 1, pagination.Offset(1, 10), "")
+	if err != nil || page.TotalEntries != 1 || len(categories) != 1 {
+		t.Errorf("FindAll after Clear: %v %d %v", categories, page.TotalEntries, err)
+	}
+
+	// 2. Failed calls are counted too
+	if calls := repository.Calls("Create"); calls != 2 {
+		t.Errorf("Create calls = %d, want 2", calls)
+	}
+	if calls := repository.Calls("FindAll"); calls != 3 {
+		t.Errorf("FindAll calls = %d, want 3", calls)
+	}
+
+	// 3. A cancelled context fails like a query would
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := repository.FindById(cancelled, 1, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindById with cancelled context: err = %v", err)
+	}
+}
+
+func TestTransactionManagerRollsBack(t *testing.T) {
+
+	db := NewDatabase()
+	manager := NewTransactionManager(db)
+	repository := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	if err := repository.Create(ctx, &model.Category{UserID: 1, Name: "Design"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	errFail := errors.New("fail")
+	err := manager.Do(ctx, func(ctx context.Context) error {
+
+		if err := repository.Update(ctx, 1, 1, &model.Category{Name: "Marketing"}); err != nil {
+			return err
+		}
+
+		if err := repository.Create(ctx, &model.Category{UserID: 1, Name: "Sales"}); err != nil {
+			return err
+		}
+
+		return errFail
+	})
+	if err != errFail {
+		t.Fatalf("Do: err = %v, want %v", err, errFail)
+	}
+
+	categories, page, err := repository.FindAll(ctx, 1, pagination.Offset(1, 10), "")
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if page.TotalEntries != 1 || categories[0].Name != "Design" {
+		t.Errorf("categories after rollback = %+v", categories)
+	}
+
+	// Ids are not reused, like a sequence
+	created := model.Category{UserID: 1, Name: "Sales"}
+	if err := repository.Create(ctx, &created); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID != 3 {
+		t.Errorf("id after rollback = %d, want 3", created.ID)
+	}
+}
+
+func TestProjectItemRepositoryOwnership(t *testing.T) {
+
+	db := NewDatabase()
+	projects := NewProjectRepository(db)
+	items := NewProjectItemRepository(db)
+	ctx := context.Background()
+
+	project := model.Project{UserID: 1, Name: "Website"}
+	if err := projects.Create(ctx, &project); err != nil {
+		t.Fatalf("Create project: %v", err)
+	}
+
+	// 1. Items are only added to projects of the user, at the end
+	err := items.Create(ctx, 2, &model.ProjectItem{ProjectID: project.ID, Name: "Design"})
+	if !apperror.Is(err, apperror.KindNotFound) {
+		t.Errorf("Create in project of another user: err = %v", err)
+	}
+
+	for _, name := range []string{"Design", "Build", "Launch"} {
+		item := model.ProjectItem{ProjectID: project.ID, Name: name}
+		if err := items.Create(ctx, 1, &item); err != nil {
+			t.Fatalf("Create item: %v", err)
+		}
+	}
+
+	// 2. Reorder and read them back through the project
+	if err := items.Reorder(ctx, 1, project.ID, []uint{3, 1}); err == nil {
+		t.Errorf("Reorder with a missing item: no error")
+	}
+	if err := items.Reorder(ctx, 1, project.ID, []uint{3, 1, 2}); err != nil {
+		t.Fatalf("Reorder: %v", err)
+	}
+
+	found, err := projects.FindById(ctx, 1, int(project.ID))
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+
+	names := []string{}
+	for _, item := range found.ProjectItems {
+		names = append(names, item.Name)
+	}
+	if fmt.Sprint(names) != "[Launch Design Build]" {
+		t.Errorf("items = %v, want [Launch Design Build]", names)
+	}
+}
+
+// TestConcurrentUse is meant for -race.
+func TestConcurrentUse(t *testing.T) {
+
+	db := NewDatabase()
+	repository := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for userId := uint(1); userId <= 10; userId++ {
+		wg.Add(1)
+		go func(userId uint) {
+			defer wg.Done()
+
+			for i := 0; i < 20; i++ {
+				repository.Create(ctx, &model.Category{UserID: userId, Name: fmt.Sprintf("Category %d", i)})
+				repository.FindAll(ctx, userId, pagination.Offset(1, 5), "Category")
+			}
+		}(userId)
+	}
+	wg.Wait()
+
+	for userId := uint(1); userId <= 10; userId++ {
+		_, page, err := repository.FindAll(ctx, userId, pagination.Offset(1, 5), "")
+		if err != nil || page.TotalEntries != 20 {
+			t.Errorf("user %d: total = %d, err = %v", userId, page.TotalEntries, err)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+
+	rows := []uint{1, 2, 3, 4, 5}
+	key := func(row uint) []int64 { return []int64{int64(row)} }
+
+	// 1. Offset, then forward and back with the cursors
+	first, page, err := paginate(rows, pagination.Offset(1, 2), key)
+	if err != nil || fmt.Sprint(first) != "[1 2]" || page.PrevCursor != "" {
+		t.Fatalf("first page = %v %+v %v", first, page, err)
+	}
+
+	next, err := pagination.DecodeCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	second, page, _ := paginate(rows, pagination.After(*next, 2), key)
+	if fmt.Sprint(second) != "[3 4]" || page.NextCursor == "" || page.PrevCursor == "" {
+		t.Fatalf("second page = %v %+v", second, page)
+	}
+
+	prev, err := pagination.DecodeCursor(page.PrevCursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	back, page, _ := paginate(rows, pagination.After(*prev, 2), key)
+	if fmt.Sprint(back) != "[1 2]" || page.PrevCursor != "" || page.TotalEntries != 5 {
+		t.Errorf("page before the second = %v %+v", back, page)
+	}
+
+	// 2. A page past the end is empty
+	past, _, _ := paginate(rows, pagination.Offset(4, 2), key)
+	if len(past) != 0 {
+		t.Errorf("page past the end = %v", past)
+	}
+
+	// 3. The rows of the caller are not changed
+	if fmt.Sprint(rows) != "[1 2 3 4 5]" {
+		t.Errorf("rows = %v", rows)
+	}
+}
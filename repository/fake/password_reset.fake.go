@@ -0,0 +1,107 @@
+package fake
+
+import (
+	"context"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	passwordResetRepository "project-app/repository/passwordreset"
+)
+
+var _ passwordResetRepository.PasswordResetRepository = (*PasswordResetRepository)(nil)
+
+type PasswordResetRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewPasswordResetRepository(db *Database) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		Db: db,
+	}
+}
+
+func (repository *PasswordResetRepository) Create(ctx context.Context, req *model.PasswordReset) error {
+
+	if err := repository.call(ctx, "Create"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row := *req
+	row.Model = repository.Db.newModel("password_resets")
+	repository.Db.tables.passwordResets[row.ID] = row
+
+	req.Model = cloneModel(row.Model)
+	return nil
+}
+
+func (repository *PasswordResetRepository) FindByHash(ctx context.Context, tokenHash string) (*model.PasswordReset, error) {
+
+	if err := repository.call(ctx, "FindByHash"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	for _, id := range sortedIds(repository.Db.tables.passwordResets) {
+		row := repository.Db.tables.passwordResets[id]
+		if row.TokenHash == tokenHash {
+			row.Model = cloneModel(row.Model)
+			return &row, nil
+		}
+	}
+
+	return nil, apperror.NotFound("Password reset not found")
+}
+
+// MarkUsed returns passwordreset.ErrPasswordResetUsed when the token was
+// already used.
+func (repository *PasswordResetRepository) MarkUsed(ctx context.Context, id uint) error {
+
+	if err := repository.call(ctx, "MarkUsed"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.passwordResets[id]
+	if !ok || row.UsedAt != nil {
+		return passwordResetRepository.ErrPasswordResetUsed
+	}
+
+	now := time.Now()
+	row.UsedAt = &now
+	row.Model = touch(row.Model)
+	repository.Db.tables.passwordResets[id] = row
+
+	return nil
+}
+
+func (repository *PasswordResetRepository) InvalidateByUserId(ctx context.Context, userId uint) error {
+
+	if err := repository.call(ctx, "InvalidateByUserId"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	now := time.Now()
+	for id, row := range repository.Db.tables.passwordResets {
+		if row.UserID != userId || row.UsedAt != nil {
+			continue
+		}
+
+		row.UsedAt = &now
+		row.Model = touch(row.Model)
+		repository.Db.tables.passwordResets[id] = row
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package fake
+
+import (
+	"context"
+	"strings"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	projectRepository "project-app/repository/project"
+)
+
+var _ projectRepository.ProjectRepository = (*ProjectRepository)(nil)
+
+type ProjectRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewProjectRepository(db *Database) *ProjectRepository {
+	return &ProjectRepository{
+		Db: db,
+	}
+}
+
+func (repository *ProjectRepository) Create(ctx context.Context, req *model.Project) error {
+
+	if err := repository.call(ctx, "Create"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	// Associations are not saved
+	row := *req
+	row.Model = repository.Db.newModel("projects")
+	row.Category = model.Category{}
+	row.ProjectItems = nil
+	repository.Db.tables.projects[row.ID] = row
+
+	req.Model = cloneModel(row.Model)
+	return nil
+}
+
+func (repository *ProjectRepository) Update(ctx context.Context, userId uint, id int, req *model.Project) error {
+
+	if err := repository.call(ctx, "Update"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.projects[uint(id)]
+	if !ok || row.UserID != userId {
+		return nil
+	}
+
+	row.CategoryID = req.CategoryID
+	row.Name = req.Name
+	row.Description = req.Description
+	row.Budget = req.Budget
+	row.Model = touch(row.Model)
+	repository.Db.tables.projects[row.ID] = row
+
+	return nil
+}
+
+func (repository *ProjectRepository) Delete(ctx context.Context, userId uint, id int) error {
+
+	if err := repository.call(ctx, "Delete"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.projects[uint(id)]
+	if ok && row.UserID == userId {
+		delete(repository.Db.tables.projects, row.ID)
+	}
+
+	return nil
+}
+
+func (repository *ProjectRepository) FindById(ctx context.Context, userId uint, id int) (*model.Project, error) {
+
+	if err := repository.call(ctx, "FindById"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.projects[uint(id)]
+	if !ok || row.UserID != userId {
+		return nil, apperror.NotFound("Project not found")
+	}
+
+	result := repository.preload(row)
+	return &result, nil
+}
+
+func (repository *ProjectRepository) FindAll(ctx context.Context, userId uint, params pagination.Params, categoryId int, searchQuery string) ([]model.Project, pagination.Page, error) {
+
+	if err := repository.call(ctx, "FindAll"); err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	matches := []model.Project{}
+	for _, id := range sortedIds(repository.Db.tables.projects) {
+		row := repository.Db.tables.projects[id]
+		if row.UserID != userId || !strings.Contains(row.Name, searchQuery) {
+			continue
+		}
+
+		if categoryId > 0 && row.CategoryID != uint(categoryId) {
+			continue
+		}
+
+		matches = append(matches, row)
+	}
+
+	result, page, err := paginate(matches, params, func(row model.Project) []int64 {
+		return []int64{int64(row.ID)}
+	})
+	for i := range result {
+		result[i] = repository.preload(result[i])
+	}
+
+	return result, page, err
+}
+
+// preload adds the category and the items ordered by position, like
+// Preload. The caller holds the lock.
+func (repository *ProjectRepository) preload(row model.Project) model.Project {
+
+	row.Model = cloneModel(row.Model)
+
+	row.Category = model.Category{}
+	if category, ok := repository.Db.tables.categories[row.CategoryID]; ok {
+		category.Model = cloneModel(category.Model)
+		row.Category = category
+	}
+
+	row.ProjectItems = itemsOfProject(repository.Db, row.ID)
+
+	return row
+}
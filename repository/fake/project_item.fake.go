@@ -0,0 +1,235 @@
+package fake
+
+import (
+	"context"
+	"sort"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	projectItemRepository "project-app/repository/projectitem"
+)
+
+var _ projectItemRepository.ProjectItemRepository = (*ProjectItemRepository)(nil)
+
+// ProjectItemRepository only sees the items of projects of the user, the
+// projects are the ones of ProjectRepository on the same Database.
+type ProjectItemRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewProjectItemRepository(db *Database) *ProjectItemRepository {
+	return &ProjectItemRepository{
+		Db: db,
+	}
+}
+
+// ownedItem returns the item when its project belongs to userId. The caller
+// holds the lock.
+func (repository *ProjectItemRepository) ownedItem(userId uint, projectId uint, itemId uint) (model.ProjectItem, bool) {
+
+	row, ok := repository.Db.tables.projectItems[itemId]
+	if !ok || row.ProjectID != projectId || !repository.ownsProject(userId, projectId) {
+		return model.ProjectItem{}, false
+	}
+
+	return row, true
+}
+
+func (repository *ProjectItemRepository) ownsProject(userId uint, projectId uint) bool {
+	project, ok := repository.Db.tables.projects[projectId]
+	return ok && project.UserID == userId
+}
+
+// itemsOfProject returns the items ordered by position and id. The caller
+// holds the lock.
+func itemsOfProject(db *Database, projectId uint) []model.ProjectItem {
+
+	items := []model.ProjectItem{}
+	for _, id := range sortedIds(db.tables.projectItems) {
+		row := db.tables.projectItems[id]
+		if row.ProjectID == projectId {
+			row.Model = cloneModel(row.Model)
+			items = append(items, row)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Position < items[j].Position
+	})
+
+	return items
+}
+
+func (repository *ProjectItemRepository) Create(ctx context.Context, userId uint, req *model.ProjectItem) error {
+
+	if err := repository.call(ctx, "Create"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	if !repository.ownsProject(userId, req.ProjectID) {
+		return apperror.NotFound("Project not found")
+	}
+
+	// New items are appended after the last item of the project
+	lastPosition := 0
+	for _, row := range repository.Db.tables.projectItems {
+		if row.ProjectID == req.ProjectID && row.Position > lastPosition {
+			lastPosition = row.Position
+		}
+	}
+
+	req.Position = lastPosition + 1
+
+	row := *req
+	row.Model = repository.Db.newModel("project_items")
+	row.Project = model.Project{}
+	repository.Db.tables.projectItems[row.ID] = row
+
+	req.Model = cloneModel(row.Model)
+	return nil
+}
+
+func (repository *ProjectItemRepository) Update(ctx context.Context, userId uint, projectId uint, itemId uint, req *model.ProjectItem) error {
+
+	if err := repository.call(ctx, "Update"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.ownedItem(userId, projectId, itemId)
+	if !ok {
+		return nil
+	}
+
+	row.Name = req.Name
+	row.BudgetItem = req.BudgetItem
+	row.Status = req.Status
+	row.Model = touch(row.Model)
+	repository.Db.tables.projectItems[row.ID] = row
+
+	return nil
+}
+
+func (repository *ProjectItemRepository) ToggleStatus(ctx context.Context, userId uint, projectId uint, itemId uint) error {
+
+	if err := repository.call(ctx, "ToggleStatus"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.ownedItem(userId, projectId, itemId)
+	if !ok {
+		return nil
+	}
+
+	row.Status = !row.Status
+	row.Model = touch(row.Model)
+	repository.Db.tables.projectItems[row.ID] = row
+
+	return nil
+}
+
+// Reorder returns projectitem.ErrReorderMismatch when itemIds is not every
+// item of the project exactly once.
+func (repository *ProjectItemRepository) Reorder(ctx context.Context, userId uint, projectId uint, itemIds []uint) error {
+
+	if err := repository.call(ctx, "Reorder"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	// 1. The new order must be a permutation of the current items
+	current := map[uint]bool{}
+	if repository.ownsProject(userId, projectId) {
+		for id, row := range repository.Db.tables.projectItems {
+			if row.ProjectID == projectId {
+				current[id] = true
+			}
+		}
+	}
+
+	if len(current) != len(itemIds) {
+		return projectItemRepository.ErrReorderMismatch
+	}
+
+	for _, id := range itemIds {
+		if !current[id] {
+			return projectItemRepository.ErrReorderMismatch
+		}
+		delete(current, id)
+	}
+
+	// 2. Save the position of every item
+	for index, id := range itemIds {
+		row := repository.Db.tables.projectItems[id]
+		row.Position = index + 1
+		row.Model = touch(row.Model)
+		repository.Db.tables.projectItems[id] = row
+	}
+
+	return nil
+}
+
+func (repository *ProjectItemRepository) Delete(ctx context.Context, userId uint, projectId uint, itemId uint) error {
+
+	if err := repository.call(ctx, "Delete"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	if row, ok := repository.ownedItem(userId, projectId, itemId); ok {
+		delete(repository.Db.tables.projectItems, row.ID)
+	}
+
+	return nil
+}
+
+func (repository *ProjectItemRepository) FindById(ctx context.Context, userId uint, projectId uint, itemId uint) (*model.ProjectItem, error) {
+
+	if err := repository.call(ctx, "FindById"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.ownedItem(userId, projectId, itemId)
+	if !ok {
+		return nil, apperror.NotFound("Project item not found")
+	}
+
+	row.Model = cloneModel(row.Model)
+	return &row, nil
+}
+
+func (repository *ProjectItemRepository) FindByProjectId(ctx context.Context, userId uint, projectId uint, params pagination.Params) ([]model.ProjectItem, pagination.Page, error) {
+
+	if err := repository.call(ctx, "FindByProjectId"); err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	items := []model.ProjectItem{}
+	if repository.ownsProject(userId, projectId) {
+		items = itemsOfProject(repository.Db, projectId)
+	}
+
+	return paginate(items, params, func(row model.ProjectItem) []int64 {
+		return []int64{int64(row.Position), int64(row.ID)}
+	})
+}
@@ -0,0 +1,186 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	rbacRepository "project-app/repository/rbac"
+)
+
+var _ rbacRepository.RbacRepository = (*RbacRepository)(nil)
+
+// RbacRepository reads the roles of Database.SeedRoles.
+type RbacRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewRbacRepository(db *Database) *RbacRepository {
+	return &RbacRepository{
+		Db: db,
+	}
+}
+
+// role returns a copy of the role, with its permissions when withPermissions
+// is set. The caller holds the lock.
+func (repository *RbacRepository) role(row model.Role, withPermissions bool) model.Role {
+
+	row.Model = cloneModel(row.Model)
+
+	if !withPermissions {
+		row.Permissions = nil
+		return row
+	}
+
+	permissions := make([]model.Permission, len(row.Permissions))
+	for i, permission := range row.Permissions {
+		permission.Model = cloneModel(permission.Model)
+		permissions[i] = permission
+	}
+	row.Permissions = permissions
+
+	return row
+}
+
+func (repository *RbacRepository) FindAllRoles(ctx context.Context) ([]model.Role, error) {
+
+	if err := repository.call(ctx, "FindAllRoles"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	result := []model.Role{}
+	for _, id := range sortedIds(repository.Db.tables.roles) {
+		result = append(result, repository.role(repository.Db.tables.roles[id], true))
+	}
+
+	return result, nil
+}
+
+func (repository *RbacRepository) FindRoleById(ctx context.Context, roleId uint) (*model.Role, error) {
+
+	if err := repository.call(ctx, "FindRoleById"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.roles[roleId]
+	if !ok {
+		return nil, apperror.NotFound("Role not found")
+	}
+
+	result := repository.role(row, false)
+	return &result, nil
+}
+
+func (repository *RbacRepository) FindRoleByName(ctx context.Context, name string) (*model.Role, error) {
+
+	if err := repository.call(ctx, "FindRoleByName"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	for _, id := range sortedIds(repository.Db.tables.roles) {
+		row := repository.Db.tables.roles[id]
+		if row.Name == name {
+			result := repository.role(row, false)
+			return &result, nil
+		}
+	}
+
+	return nil, apperror.NotFound("Role not found")
+}
+
+func (repository *RbacRepository) FindRolesByUserId(ctx context.Context, userId uint) ([]model.Role, error) {
+
+	if err := repository.call(ctx, "FindRolesByUserId"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	result := []model.Role{}
+	for _, id := range sortedIds(repository.Db.tables.roles) {
+		if _, ok := repository.Db.tables.userRoles[userRoleKey{UserID: userId, RoleID: id}]; ok {
+			result = append(result, repository.role(repository.Db.tables.roles[id], false))
+		}
+	}
+
+	return result, nil
+}
+
+// FindPermissionsByUserId returns the permission names sorted.
+func (repository *RbacRepository) FindPermissionsByUserId(ctx context.Context, userId uint) ([]string, error) {
+
+	if err := repository.call(ctx, "FindPermissionsByUserId"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	names := map[string]bool{}
+	for key := range repository.Db.tables.userRoles {
+		if key.UserID != userId {
+			continue
+		}
+
+		for _, permission := range repository.Db.tables.roles[key.RoleID].Permissions {
+			names[permission.Name] = true
+		}
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// AssignRole does nothing when the user already has the role.
+func (repository *RbacRepository) AssignRole(ctx context.Context, userId uint, roleId uint) error {
+
+	if err := repository.call(ctx, "AssignRole"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	key := userRoleKey{UserID: userId, RoleID: roleId}
+	if _, ok := repository.Db.tables.userRoles[key]; !ok {
+		repository.Db.tables.userRoles[key] = model.UserRole{
+			UserID:    userId,
+			RoleID:    roleId,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	return nil
+}
+
+func (repository *RbacRepository) RevokeRole(ctx context.Context, userId uint, roleId uint) error {
+
+	if err := repository.call(ctx, "RevokeRole"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	delete(repository.Db.tables.userRoles, userRoleKey{UserID: userId, RoleID: roleId})
+
+	return nil
+}
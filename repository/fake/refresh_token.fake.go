@@ -0,0 +1,135 @@
+package fake
+
+import (
+	"context"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	refreshTokenRepository "project-app/repository/refreshtoken"
+)
+
+var _ refreshTokenRepository.RefreshTokenRepository = (*RefreshTokenRepository)(nil)
+
+type RefreshTokenRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewRefreshTokenRepository(db *Database) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Db: db,
+	}
+}
+
+func (repository *RefreshTokenRepository) Create(ctx context.Context, req *model.RefreshToken) error {
+
+	if err := repository.call(ctx, "Create"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	repository.insert(req)
+	return nil
+}
+
+// insert saves req with a new id. The caller holds the lock.
+func (repository *RefreshTokenRepository) insert(req *model.RefreshToken) {
+
+	row := *req
+	row.Model = repository.Db.newModel("refresh_tokens")
+	repository.Db.tables.refreshTokens[row.ID] = row
+
+	req.Model = cloneModel(row.Model)
+}
+
+func (repository *RefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+
+	if err := repository.call(ctx, "FindByHash"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	for _, id := range sortedIds(repository.Db.tables.refreshTokens) {
+		row := repository.Db.tables.refreshTokens[id]
+		if row.TokenHash == tokenHash {
+			row.Model = cloneModel(row.Model)
+			return &row, nil
+		}
+	}
+
+	return nil, apperror.NotFound("Refresh token not found")
+}
+
+// Rotate returns refreshtoken.ErrRefreshTokenReused when the used token was
+// already rotated or revoked.
+func (repository *RefreshTokenRepository) Rotate(ctx context.Context, usedId uint, req *model.RefreshToken) error {
+
+	if err := repository.call(ctx, "Rotate"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	// 1. Mark the old token as used
+	used, ok := repository.Db.tables.refreshTokens[usedId]
+	if !ok || used.UsedAt != nil || used.RevokedAt != nil {
+		return refreshTokenRepository.ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+	used.UsedAt = &now
+	used.Model = touch(used.Model)
+	repository.Db.tables.refreshTokens[usedId] = used
+
+	// 2. Insert the new token in the same family
+	repository.insert(req)
+	return nil
+}
+
+func (repository *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyId string) error {
+
+	if err := repository.call(ctx, "RevokeFamily"); err != nil {
+		return err
+	}
+
+	repository.revoke(func(token model.RefreshToken) bool {
+		return token.FamilyID == familyId
+	})
+	return nil
+}
+
+func (repository *RefreshTokenRepository) RevokeByUserId(ctx context.Context, userId uint) error {
+
+	if err := repository.call(ctx, "RevokeByUserId"); err != nil {
+		return err
+	}
+
+	repository.revoke(func(token model.RefreshToken) bool {
+		return token.UserID == userId
+	})
+	return nil
+}
+
+// revoke revokes the tokens that match and are not revoked yet.
+func (repository *RefreshTokenRepository) revoke(match func(token model.RefreshToken) bool) {
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	now := time.Now()
+	for id, row := range repository.Db.tables.refreshTokens {
+		if row.RevokedAt != nil || !match(row) {
+			continue
+		}
+
+		row.RevokedAt = &now
+		row.Model = touch(row.Model)
+		repository.Db.tables.refreshTokens[id] = row
+	}
+}
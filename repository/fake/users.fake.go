@@ -0,0 +1,333 @@
+package fake
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	usersRepository "project-app/repository/users"
+)
+
+var _ usersRepository.UsersRepository = (*UsersRepository)(nil)
+
+type UsersRepository struct {
+	Db *Database
+	Errors
+}
+
+func NewUsersRepository(db *Database) *UsersRepository {
+	return &UsersRepository{
+		Db: db,
+	}
+}
+
+func (repository *UsersRepository) Register(ctx context.Context, req *model.User) (*uint, error) {
+
+	if err := repository.call(ctx, "Register"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	// Like the unique index on lower(email)
+	for _, row := range repository.Db.tables.users {
+		if strings.EqualFold(row.Email, req.Email) {
+			return nil, apperror.Conflict("User already exist")
+		}
+	}
+
+	row := *req
+	row.Model = repository.Db.newModel("users")
+	repository.Db.tables.users[row.ID] = row
+
+	req.Model = cloneModel(row.Model)
+	return &req.ID, nil
+}
+
+// FindByUsernameOrEmail returns an empty user when none matches.
+func (repository *UsersRepository) FindByUsernameOrEmail(ctx context.Context, req string, isEmail bool) (*model.User, error) {
+
+	if err := repository.call(ctx, "FindByUsernameOrEmail"); err != nil {
+		return nil, err
+	}
+
+	return repository.find(func(user model.User) bool {
+		if isEmail {
+			return user.Email == req
+		}
+		return user.Username == req
+	}), nil
+}
+
+func (repository *UsersRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+
+	if err := repository.call(ctx, "FindByEmail"); err != nil {
+		return nil, err
+	}
+
+	email = strings.ToLower(email)
+	user := repository.find(func(user model.User) bool {
+		return user.Email == email
+	})
+	if user.Model == nil {
+		return nil, apperror.NotFound("User not found")
+	}
+
+	return user, nil
+}
+
+func (repository *UsersRepository) find(match func(user model.User) bool) *model.User {
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	for _, id := range sortedIds(repository.Db.tables.users) {
+		row := repository.Db.tables.users[id]
+		if match(row) {
+			row.Model = cloneModel(row.Model)
+			return &row
+		}
+	}
+
+	return &model.User{}
+}
+
+func (repository *UsersRepository) FindById(ctx context.Context, userId uint) (*model.User, error) {
+
+	if err := repository.call(ctx, "FindById"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.users[userId]
+	if !ok {
+		return nil, apperror.NotFound("User not found")
+	}
+
+	row.Model = cloneModel(row.Model)
+	return &row, nil
+}
+
+func (repository *UsersRepository) UpdatePassword(ctx context.Context, userId uint, passwordHash string) error {
+
+	if err := repository.call(ctx, "UpdatePassword"); err != nil {
+		return err
+	}
+
+	return repository.update(userId, func(user *model.User) {
+		user.Password = passwordHash
+	})
+}
+
+// MarkEmailVerified keeps the time of the first call.
+func (repository *UsersRepository) MarkEmailVerified(ctx context.Context, userId uint) error {
+
+	if err := repository.call(ctx, "MarkEmailVerified"); err != nil {
+		return err
+	}
+
+	return repository.update(userId, func(user *model.User) {
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+	})
+}
+
+func (repository *UsersRepository) UpdateVerificationSentAt(ctx context.Context, userId uint, sentAt time.Time) error {
+
+	if err := repository.call(ctx, "UpdateVerificationSentAt"); err != nil {
+		return err
+	}
+
+	return repository.update(userId, func(user *model.User) {
+		user.VerificationSentAt = &sentAt
+	})
+}
+
+// RecordLoginFailure returns 0 for a missing user.
+func (repository *UsersRepository) RecordLoginFailure(ctx context.Context, userId uint) (int, error) {
+
+	if err := repository.call(ctx, "RecordLoginFailure"); err != nil {
+		return 0, err
+	}
+
+	var failedLogins int
+	err := repository.update(userId, func(user *model.User) {
+		user.FailedLogins++
+		failedLogins = user.FailedLogins
+	})
+
+	return failedLogins, err
+}
+
+func (repository *UsersRepository) LockUntil(ctx context.Context, userId uint, until time.Time) error {
+
+	if err := repository.call(ctx, "LockUntil"); err != nil {
+		return err
+	}
+
+	return repository.update(userId, func(user *model.User) {
+		user.LockedUntil = &until
+	})
+}
+
+func (repository *UsersRepository) ResetLoginFailures(ctx context.Context, userId uint) error {
+
+	if err := repository.call(ctx, "ResetLoginFailures"); err != nil {
+		return err
+	}
+
+	return repository.update(userId, func(user *model.User) {
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+	})
+}
+
+// update changes the user when it exists, a missing user is not an error.
+func (repository *UsersRepository) update(userId uint, change func(user *model.User)) error {
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row, ok := repository.Db.tables.users[userId]
+	if !ok {
+		return nil
+	}
+
+	change(&row)
+	row.Model = touch(row.Model)
+	repository.Db.tables.users[userId] = row
+
+	return nil
+}
+
+func (repository *UsersRepository) CreatUserProfileById(ctx context.Context, req *model.ProfileCreateRequest) error {
+
+	if err := repository.call(ctx, "CreatUserProfileById"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	row := model.Profile{
+		Model:     repository.Db.newModel("user_profiles"),
+		UserId:    int(req.UserId),
+		Bio:       req.Bio,
+		Role:      req.Role,
+		Facebook:  req.Facebook,
+		Instagram: req.Instagram,
+		LinkedIn:  req.Linkedin,
+		Twitter:   req.Twitter,
+	}
+	repository.Db.tables.profiles[row.ID] = row
+
+	return nil
+}
+
+// UpdateProfileById only writes the fields that are set, like Updates with a
+// struct.
+func (repository *UsersRepository) UpdateProfileById(ctx context.Context, userId uint, req model.ProfileUpdateRequest) error {
+
+	if err := repository.call(ctx, "UpdateProfileById"); err != nil {
+		return err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	for id, row := range repository.Db.tables.profiles {
+		if row.UserId != int(userId) {
+			continue
+		}
+
+		setIfNotEmpty(&row.Bio, req.Bio)
+		setIfNotEmpty(&row.Role, req.Role)
+		setIfNotEmpty(&row.Facebook, req.Facebook)
+		setIfNotEmpty(&row.Instagram, req.Instagram)
+		setIfNotEmpty(&row.LinkedIn, req.Linkedin)
+		setIfNotEmpty(&row.Twitter, req.Twitter)
+		row.Model = touch(row.Model)
+		repository.Db.tables.profiles[id] = row
+	}
+
+	return nil
+}
+
+func (repository *UsersRepository) GetProfileById(ctx context.Context, userId uint) (*model.Profile, error) {
+
+	if err := repository.call(ctx, "GetProfileById"); err != nil {
+		return nil, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	for _, id := range sortedIds(repository.Db.tables.profiles) {
+		row := repository.Db.tables.profiles[id]
+		if row.UserId == int(userId) {
+			row.Model = cloneModel(row.Model)
+			return &row, nil
+		}
+	}
+
+	return nil, apperror.NotFound("Profile not found")
+}
+
+// FindFollowersByUserId searches the username with a case sensitive LIKE, as
+// Postgres does.
+func (repository *UsersRepository) FindFollowersByUserId(ctx context.Context, userId uint, params pagination.Params, searchQuery string) ([]model.UserWithProfile, pagination.Page, error) {
+
+	if err := repository.call(ctx, "FindFollowersByUserId"); err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	repository.Db.mu.Lock()
+	defer repository.Db.mu.Unlock()
+
+	roles := map[int]string{}
+	for _, id := range sortedIds(repository.Db.tables.profiles) {
+		row := repository.Db.tables.profiles[id]
+		if _, ok := roles[row.UserId]; !ok {
+			roles[row.UserId] = row.Role
+		}
+	}
+
+	followers := []model.UserWithProfile{}
+	for _, id := range sortedIds(repository.Db.tables.follows) {
+		row := repository.Db.tables.follows[id]
+		if row.FollowingUserID != userId {
+			continue
+		}
+
+		user, ok := repository.Db.tables.users[row.UserID]
+		if !ok || !strings.Contains(user.Username, searchQuery) {
+			continue
+		}
+
+		followers = append(followers, model.UserWithProfile{
+			FollowId:       row.ID,
+			UserId:         int(row.UserID),
+			FollowedUserId: int(row.FollowingUserID),
+			Role:           roles[int(row.UserID)],
+			Username:       user.Username,
+		})
+	}
+
+	return paginate(followers, params, func(row model.UserWithProfile) []int64 {
+		return []int64{int64(row.FollowId)}
+	})
+}
+
+func setIfNotEmpty(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
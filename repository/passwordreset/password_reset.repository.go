@@ -0,0 +1,126 @@
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/tracing"
+	"project-app/transaction"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, req *model.PasswordReset) error
+	FindByHash(ctx context.Context, tokenHash string) (*model.PasswordReset, error)
+	MarkUsed(ctx context.Context, id uint) error
+	InvalidateByUserId(ctx context.Context, userId uint) error
+}
+
+type PasswordResetRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+
+	return &PasswordResetRepositoryImpl{
+		Db: db,
+	}
+}
+
+var tablePasswordReset = "password_resets"
+
+// ErrPasswordResetUsed is returned by MarkUsed when the token was already used.
+var ErrPasswordResetUsed = errors.New("password reset token already used")
+
+func (repository *PasswordResetRepositoryImpl) Create(ctx context.Context, req *model.PasswordReset) error {
+
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.Create")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tablePasswordReset).
+		Create(req).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *PasswordResetRepositoryImpl) FindByHash(ctx context.Context, tokenHash string) (*model.PasswordReset, error) {
+
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.FindByHash")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.PasswordReset
+	err := tx.
+		Table(tablePasswordReset).
+		Where("token_hash = ?", tokenHash).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Password reset not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// MarkUsed uses the token, only one request can use it.
+func (repository *PasswordResetRepositoryImpl) MarkUsed(ctx context.Context, id uint) error {
+
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.MarkUsed")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	result := tx.
+		Table(tablePasswordReset).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPasswordResetUsed
+	}
+
+	return nil
+}
+
+// InvalidateByUserId uses every open token of the user, so only the latest
+// email can reset the password.
+func (repository *PasswordResetRepositoryImpl) InvalidateByUserId(ctx context.Context, userId uint) error {
+
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.InvalidateByUserId")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tablePasswordReset).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Update("used_at", time.Now()).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
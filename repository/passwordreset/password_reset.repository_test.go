@@ -0,0 +1,76 @@
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/testdb"
+)
+
+func TestPasswordResetRepositoryMarkUsed(t *testing.T) {
+
+	db := testdb.Open(t)
+	repository := NewPasswordResetRepository(db)
+	ctx := context.Background()
+
+	reset := &model.PasswordReset{UserID: 1, TokenHash: "hash-1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repository.Create(ctx, reset); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := repository.MarkUsed(ctx, reset.ID); err != nil {
+		t.Fatalf("MarkUsed: %v", err)
+	}
+
+	found, err := repository.FindByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("FindByHash: %v", err)
+	}
+	if found.UsedAt == nil {
+		t.Errorf("usedAt is not set")
+	}
+
+	// The conditional update lets only one request use the token
+	if err := repository.MarkUsed(ctx, reset.ID); !errors.Is(err, ErrPasswordResetUsed) {
+		t.Errorf("MarkUsed again: err = %v, want %v", err, ErrPasswordResetUsed)
+	}
+
+	if _, err := repository.FindByHash(ctx, "unknown"); !apperror.Is(err, apperror.KindNotFound) {
+		t.Errorf("FindByHash unknown: err = %v, want kind %s", err, apperror.KindNotFound)
+	}
+}
+
+func TestPasswordResetRepositoryInvalidateByUserId(t *testing.T) {
+
+	db := testdb.Open(t)
+	repository := NewPasswordResetRepository(db)
+	ctx := context.Background()
+
+	for _, reset := range []*model.PasswordReset{
+		{UserID: 1, TokenHash: "hash-1", ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: 1, TokenHash: "hash-2", ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: 2, TokenHash: "hash-3", ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		if err := repository.Create(ctx, reset); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if err := repository.InvalidateByUserId(ctx, 1); err != nil {
+		t.Fatalf("InvalidateByUserId: %v", err)
+	}
+
+	for hash, wantUsed := range map[string]bool{"hash-1": true, "hash-2": true, "hash-3": false} {
+		found, err := repository.FindByHash(ctx, hash)
+		if err != nil {
+			t.Fatalf("FindByHash %s: %v", hash, err)
+		}
+		if (found.UsedAt != nil) != wantUsed {
+			t.Errorf("%s usedAt = %v, want used %t", hash, found.UsedAt, wantUsed)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/tracing"
+	"project-app/transaction"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectRepository interface {
+	Create(ctx context.Context, req *model.Project) error
+	Update(ctx context.Context, userId uint, id int, req *model.Project) error
+	Delete(ctx context.Context, userId uint, id int) error
+	FindById(ctx context.Context, userId uint, id int) (*model.Project, error)
+	FindAll(ctx context.Context, userId uint, params pagination.Params, categoryId int, searchQuery string) ([]model.Project, pagination.Page, error)
+}
+
+type ProjectRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func NewProjectRepository(db *gorm.DB) ProjectRepository {
+	return &ProjectRepositoryImpl{
+		Db: db,
+	}
+}
+
+var tableProject = "projects"
+
+func (repository *ProjectRepositoryImpl) Create(ctx context.Context, req *model.Project) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectRepository.Create")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableProject).
+		Omit(clause.Associations).
+		Create(req).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ProjectRepositoryImpl) Update(ctx context.Context, userId uint, id int, req *model.Project) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectRepository.Update")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableProject).
+		Omit(clause.Associations).
+		Where("id = ? AND user_id = ?", id, userId).
+		Updates(map[string]interface{}{
+			"category_id": req.CategoryID,
+			"name":        req.Name,
+			"description": req.Description,
+			"budget":      req.Budget,
+		}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ProjectRepositoryImpl) Delete(ctx context.Context, userId uint, id int) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectRepository.Delete")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableProject).
+		Where("user_id = ?", userId).
+		Delete(&model.Project{}, id).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ProjectRepositoryImpl) FindById(ctx context.Context, userId uint, id int) (*model.Project, error) {
+
+	ctx, span := tracing.Start(ctx, "ProjectRepository.FindById")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.Project
+	err := tx.
+		Table(tableProject).
+		Preload("Category").
+		Preload("ProjectItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		}).
+		Where("id = ? AND user_id = ?", id, userId).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Project not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *ProjectRepositoryImpl) FindAll(ctx context.Context, userId uint, params pagination.Params, categoryId int, searchQuery string) ([]model.Project, pagination.Page, error) {
+
+	ctx, span := tracing.Start(ctx, "ProjectRepository.FindAll")
+	defer span.End()
+
+	var projects []model.Project
+	var totalCount int64
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	// Query
+	query := tx.Model(&model.Project{}).Where("user_id = ?", userId)
+
+	if categoryId > 0 {
+		query = query.Where("category_id = ?", categoryId)
+	}
+
+	if searchQuery != "" {
+		query = query.Where("name LIKE ?", "%"+searchQuery+"%")
+	}
+
+	err := query.Count(&totalCount).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	errResult := query.
+		Preload("Category").
+		Preload("ProjectItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		}).
+		Scopes(params.Scope("id")).
+		Find(&projects).
+		Error
+
+	if errResult != nil {
+		return nil, pagination.Page{}, errResult
+	}
+
+	projects, page := pagination.Finish(params, projects, totalCount, func(row model.Project) []int64 {
+		return []int64{int64(row.ID)}
+	})
+
+	return projects, page, nil
+}
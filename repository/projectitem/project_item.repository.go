@@ -0,0 +1,273 @@
+package projectitem
+
+import (
+	"context"
+	"errors"
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/tracing"
+	"project-app/transaction"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectItemRepository interface {
+	Create(ctx context.Context, userId uint, req *model.ProjectItem) error
+	Update(ctx context.Context, userId uint, projectId uint, itemId uint, req *model.ProjectItem) error
+	ToggleStatus(ctx context.Context, userId uint, projectId uint, itemId uint) error
+	Reorder(ctx context.Context, userId uint, projectId uint, itemIds []uint) error
+	Delete(ctx context.Context, userId uint, projectId uint, itemId uint) error
+	FindById(ctx context.Context, userId uint, projectId uint, itemId uint) (*model.ProjectItem, error)
+	FindByProjectId(ctx context.Context, userId uint, projectId uint, params pagination.Params) ([]model.ProjectItem, pagination.Page, error)
+}
+
+type ProjectItemRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func NewProjectItemRepository(db *gorm.DB) ProjectItemRepository {
+
+	return &ProjectItemRepositoryImpl{
+		Db: db,
+	}
+}
+
+var tableProjectItem = "project_items"
+
+var ErrReorderMismatch = errors.New("item ids must contain every item of the project exactly once")
+
+// ownedBy limits a query to items of projects that belong to userId.
+func ownedBy(userId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("project_id IN (SELECT id FROM projects WHERE user_id = ? AND deleted_at IS NULL)", userId)
+	}
+}
+
+func (repository *ProjectItemRepositoryImpl) Create(ctx context.Context, userId uint, req *model.ProjectItem) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.Create")
+	defer span.End()
+
+	return transaction.Run(ctx, repository.Db, func(tx *gorm.DB) error {
+
+		// 1. Items can only be added to projects of the user
+		var ownedProjects int64
+		err := tx.
+			Table("projects").
+			Where("id = ? AND user_id = ? AND deleted_at IS NULL", req.ProjectID, userId).
+			Count(&ownedProjects).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		if ownedProjects == 0 {
+			return apperror.NotFound("Project not found")
+		}
+
+		// 2. New items are appended after the last item of the project
+		var lastPosition int
+		err = tx.
+			Model(&model.ProjectItem{}).
+			Where("project_id = ?", req.ProjectID).
+			Select("COALESCE(MAX(position), 0)").
+			Scan(&lastPosition).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		req.Position = lastPosition + 1
+
+		// 3. Insert item
+		return tx.
+			Table(tableProjectItem).
+			Omit(clause.Associations).
+			Create(req).
+			Error
+	})
+}
+
+func (repository *ProjectItemRepositoryImpl) Update(ctx context.Context, userId uint, projectId uint, itemId uint, req *model.ProjectItem) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.Update")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Model(&model.ProjectItem{}).
+		Scopes(ownedBy(userId)).
+		Where("id = ? AND project_id = ?", itemId, projectId).
+		Updates(map[string]interface{}{
+			"name":        req.Name,
+			"budget_item": req.BudgetItem,
+			"status":      req.Status,
+		}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ProjectItemRepositoryImpl) ToggleStatus(ctx context.Context, userId uint, projectId uint, itemId uint) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.ToggleStatus")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Model(&model.ProjectItem{}).
+		Scopes(ownedBy(userId)).
+		Where("id = ? AND project_id = ?", itemId, projectId).
+		Update("status", gorm.Expr("NOT status")).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ProjectItemRepositoryImpl) Reorder(ctx context.Context, userId uint, projectId uint, itemIds []uint) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.Reorder")
+	defer span.End()
+
+	return transaction.Run(ctx, repository.Db, func(tx *gorm.DB) error {
+
+		// 1. The new order must be a permutation of the current items
+		var currentIds []uint
+		err := tx.Model(&model.ProjectItem{}).
+			Scopes(ownedBy(userId)).
+			Where("project_id = ?", projectId).
+			Pluck("id", &currentIds).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		if len(currentIds) != len(itemIds) {
+			return ErrReorderMismatch
+		}
+
+		current := make(map[uint]bool, len(currentIds))
+		for _, id := range currentIds {
+			current[id] = true
+		}
+
+		for _, id := range itemIds {
+			if !current[id] {
+				return ErrReorderMismatch
+			}
+			delete(current, id)
+		}
+
+		// 2. Save the position of every item
+		for index, id := range itemIds {
+			err := tx.Model(&model.ProjectItem{}).
+				Scopes(ownedBy(userId)).
+				Where("id = ? AND project_id = ?", id, projectId).
+				Update("position", index+1).
+				Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repository *ProjectItemRepositoryImpl) Delete(ctx context.Context, userId uint, projectId uint, itemId uint) error {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.Delete")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Scopes(ownedBy(userId)).
+		Where("id = ? AND project_id = ?", itemId, projectId).
+		Delete(&model.ProjectItem{}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ProjectItemRepositoryImpl) FindById(ctx context.Context, userId uint, projectId uint, itemId uint) (*model.ProjectItem, error) {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.FindById")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.ProjectItem
+	err := tx.
+		Table(tableProjectItem).
+		Scopes(ownedBy(userId)).
+		Where("id = ? AND project_id = ?", itemId, projectId).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Project item not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *ProjectItemRepositoryImpl) FindByProjectId(ctx context.Context, userId uint, projectId uint, params pagination.Params) ([]model.ProjectItem, pagination.Page, error) {
+
+	ctx, span := tracing.Start(ctx, "ProjectItemRepository.FindByProjectId")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result []model.ProjectItem
+	var totalCount int64
+
+	query := tx.
+		Table(tableProjectItem).
+		Scopes(ownedBy(userId)).
+		Where("project_id = ? AND deleted_at IS NULL", projectId)
+
+	err := query.Count(&totalCount).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	errResult := query.
+		Scopes(params.Scope("position", "id")).
+		Find(&result).
+		Error
+
+	if errResult != nil {
+		return nil, pagination.Page{}, errResult
+	}
+
+	result, page := pagination.Finish(params, result, totalCount, func(row model.ProjectItem) []int64 {
+		return []int64{int64(row.Position), int64(row.ID)}
+	})
+
+	return result, page, nil
+}
@@ -0,0 +1,202 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/tracing"
+	"project-app/transaction"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RbacRepository interface {
+	FindAllRoles(ctx context.Context) ([]model.Role, error)
+	FindRoleById(ctx context.Context, roleId uint) (*model.Role, error)
+	FindRoleByName(ctx context.Context, name string) (*model.Role, error)
+	FindRolesByUserId(ctx context.Context, userId uint) ([]model.Role, error)
+	FindPermissionsByUserId(ctx context.Context, userId uint) ([]string, error)
+	AssignRole(ctx context.Context, userId uint, roleId uint) error
+	RevokeRole(ctx context.Context, userId uint, roleId uint) error
+}
+
+type RbacRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func NewRbacRepository(db *gorm.DB) RbacRepository {
+
+	return &RbacRepositoryImpl{
+		Db: db,
+	}
+}
+
+var tableRole = "roles"
+var tableUserRole = "user_roles"
+
+func (repository *RbacRepositoryImpl) FindAllRoles(ctx context.Context) ([]model.Role, error) {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.FindAllRoles")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result []model.Role
+	err := tx.
+		Table(tableRole).
+		Preload("Permissions").
+		Order("id").
+		Find(&result).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (repository *RbacRepositoryImpl) FindRoleById(ctx context.Context, roleId uint) (*model.Role, error) {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.FindRoleById")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.Role
+	err := tx.
+		Table(tableRole).
+		Where("id = ?", roleId).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Role not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *RbacRepositoryImpl) FindRoleByName(ctx context.Context, name string) (*model.Role, error) {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.FindRoleByName")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.Role
+	err := tx.
+		Table(tableRole).
+		Where("name = ?", name).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Role not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *RbacRepositoryImpl) FindRolesByUserId(ctx context.Context, userId uint) ([]model.Role, error) {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.FindRolesByUserId")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result []model.Role
+	err := tx.
+		Table(tableRole).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userId).
+		Order("roles.id").
+		Find(&result).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (repository *RbacRepositoryImpl) FindPermissionsByUserId(ctx context.Context, userId uint) ([]string, error) {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.FindPermissionsByUserId")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result []string
+	err := tx.
+		Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id AND roles.deleted_at IS NULL").
+		Where("user_roles.user_id = ? AND permissions.deleted_at IS NULL", userId).
+		Pluck("permissions.name", &result).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (repository *RbacRepositoryImpl) AssignRole(ctx context.Context, userId uint, roleId uint) error {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.AssignRole")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUserRole).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.UserRole{
+			UserID:    userId,
+			RoleID:    roleId,
+			CreatedAt: time.Now(),
+		}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *RbacRepositoryImpl) RevokeRole(ctx context.Context, userId uint, roleId uint) error {
+
+	ctx, span := tracing.Start(ctx, "RbacRepository.RevokeRole")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUserRole).
+		Where("user_id = ? AND role_id = ?", userId, roleId).
+		Delete(&model.UserRole{}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
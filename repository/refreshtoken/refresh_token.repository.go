@@ -0,0 +1,149 @@
+package refreshtoken
+
+import (
+	"context"
+	"errors"
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/tracing"
+	"project-app/transaction"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, req *model.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	Rotate(ctx context.Context, usedId uint, req *model.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyId string) error
+	RevokeByUserId(ctx context.Context, userId uint) error
+}
+
+type RefreshTokenRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+
+	return &RefreshTokenRepositoryImpl{
+		Db: db,
+	}
+}
+
+var tableRefreshToken = "refresh_tokens"
+
+// ErrRefreshTokenReused is returned by Rotate when the token was already
+// rotated or revoked by another request.
+var ErrRefreshTokenReused = errors.New("refresh token already used")
+
+func (repository *RefreshTokenRepositoryImpl) Create(ctx context.Context, req *model.RefreshToken) error {
+
+	ctx, span := tracing.Start(ctx, "RefreshTokenRepository.Create")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableRefreshToken).
+		Create(req).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *RefreshTokenRepositoryImpl) FindByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+
+	ctx, span := tracing.Start(ctx, "RefreshTokenRepository.FindByHash")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.RefreshToken
+	err := tx.
+		Table(tableRefreshToken).
+		Where("token_hash = ?", tokenHash).
+		Take(&result).
+		Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Refresh token not found").Wrap(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Rotate marks the used token and saves its replacement in one transaction.
+// Only one request can use a token, the others get ErrRefreshTokenReused.
+func (repository *RefreshTokenRepositoryImpl) Rotate(ctx context.Context, usedId uint, req *model.RefreshToken) error {
+
+	ctx, span := tracing.Start(ctx, "RefreshTokenRepository.Rotate")
+	defer span.End()
+
+	return transaction.Run(ctx, repository.Db, func(tx *gorm.DB) error {
+
+		// 1. Mark the old token as used
+		result := tx.Table(tableRefreshToken).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", usedId).
+			Update("used_at", time.Now())
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		// 2. Insert the new token in the same family
+		return tx.Table(tableRefreshToken).Create(req).Error
+	})
+}
+
+func (repository *RefreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyId string) error {
+
+	ctx, span := tracing.Start(ctx, "RefreshTokenRepository.RevokeFamily")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableRefreshToken).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *RefreshTokenRepositoryImpl) RevokeByUserId(ctx context.Context, userId uint) error {
+
+	ctx, span := tracing.Start(ctx, "RefreshTokenRepository.RevokeByUserId")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableRefreshToken).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
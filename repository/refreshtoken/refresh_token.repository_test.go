@@ -0,0 +1,191 @@
+package refreshtoken
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/testdb"
+)
+
+func newToken(userId uint, familyId string, hash string) *model.RefreshToken {
+	return &model.RefreshToken{
+		UserID:    userId,
+		FamilyID:  familyId,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func TestRefreshTokenRepositoryRotate(t *testing.T) {
+
+	db := testdb.Open(t)
+	repository := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	first := newToken(1, "family-1", "hash-1")
+	if err := repository.Create(ctx, first); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := repository.Rotate(ctx, first.ID, newToken(1, "family-1", "hash-2")); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	used, err := repository.FindByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("FindByHash used: %v", err)
+	}
+	if used.UsedAt == nil || used.RevokedAt != nil {
+		t.Errorf("used token: usedAt = %v, revokedAt = %v, want used and not revoked", used.UsedAt, used.RevokedAt)
+	}
+
+	second, err := repository.FindByHash(ctx, "hash-2")
+	if err != nil {
+		t.Fatalf("FindByHash new: %v", err)
+	}
+	if second.FamilyID != "family-1" || second.UsedAt != nil {
+		t.Errorf("new token = %+v, want an unused token of family-1", second)
+	}
+
+	// A used token cannot be rotated again, nor its replacement saved
+	err = repository.Rotate(ctx, first.ID, newToken(1, "family-1", "hash-3"))
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("Rotate again: err = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := repository.FindByHash(ctx, "hash-3"); !apperror.Is(err, apperror.KindNotFound) {
+		t.Errorf("FindByHash hash-3: err = %v, want kind %s", err, apperror.KindNotFound)
+	}
+
+	// Nor can a revoked token
+	if err := repository.RevokeFamily(ctx, "family-1"); err != nil {
+		t.Fatalf("RevokeFamily: %v", err)
+	}
+	err = repository.Rotate(ctx, second.ID, newToken(1, "family-1", "hash-4"))
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("Rotate revoked: err = %v, want %v", err, ErrRefreshTokenReused)
+	}
+}
+
+// TestRefreshTokenRepositoryRotateConcurrent rotates one token from several
+// goroutines, the conditional update on used_at lets exactly one of them win.
+func TestRefreshTokenRepositoryRotateConcurrent(t *testing.T) {
+
+	db := testdb.Open(t)
+	repository := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	used := newToken(1, "family-1", "hash-used")
+	if err := repository.Create(ctx, used); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	const requests = 8
+
+	var wg sync.WaitGroup
+	errs := make([]error, requests)
+	start := make(chan struct{})
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repository.Rotate(ctx, used.ID, newToken(1, "family-1", "hash-"+strconv.Itoa(i)))
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	won := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, ErrRefreshTokenReused):
+			t.Errorf("Rotate %d: %v", i, err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d rotations won, want 1", won)
+	}
+
+	// Only the replacement of the winner was saved
+	var count int64
+	if err := db.Table(tableRefreshToken).Where("family_id = ?", "family-1").Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("family has %d tokens, want 2", count)
+	}
+}
+
+func TestRefreshTokenRepositoryRevoke(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		revoke      func(repository RefreshTokenRepository) error
+		wantRevoked []string
+	}{
+		{
+			name: "family",
+			revoke: func(repository RefreshTokenRepository) error {
+				return repository.RevokeFamily(context.Background(), "family-1")
+			},
+			wantRevoked: []string{"hash-1", "hash-2"},
+		},
+		{
+			name: "user",
+			revoke: func(repository RefreshTokenRepository) error {
+				return repository.RevokeByUserId(context.Background(), 1)
+			},
+			wantRevoked: []string{"hash-1", "hash-2", "hash-3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			db := testdb.Open(t)
+			repository := NewRefreshTokenRepository(db)
+			ctx := context.Background()
+
+			// Two logins of user 1 and one of user 2
+			for _, token := range []*model.RefreshToken{
+				newToken(1, "family-1", "hash-1"),
+				newToken(1, "family-1", "hash-2"),
+				newToken(1, "family-2", "hash-3"),
+				newToken(2, "family-3", "hash-4"),
+			} {
+				if err := repository.Create(ctx, token); err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+			}
+
+			if err := test.revoke(repository); err != nil {
+				t.Fatalf("revoke: %v", err)
+			}
+
+			revoked := map[string]bool{}
+			for _, hash := range test.wantRevoked {
+				revoked[hash] = true
+			}
+
+			for _, hash := range []string{"hash-1", "hash-2", "hash-3", "hash-4"} {
+				token, err := repository.FindByHash(ctx, hash)
+				if err != nil {
+					t.Fatalf("FindByHash %s: %v", hash, err)
+				}
+				if (token.RevokedAt != nil) != revoked[hash] {
+					t.Errorf("%s revokedAt = %v, want revoked %t", hash, token.RevokedAt, revoked[hash])
+				}
+			}
+		})
+	}
+}
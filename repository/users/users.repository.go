@@ -1,23 +1,34 @@
 package users
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"project-app/helper"
+	"project-app/apperror"
 	"project-app/model"
+	"project-app/pagination"
+	"project-app/tracing"
+	"project-app/transaction"
 	"strings"
+	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
 type UsersRepository interface {
-	Register(ctx *fiber.Ctx, req *model.User) (*uint, error)
-	FindByUsernameOrEmail(ctx *fiber.Ctx, req string, isEmail bool) (*model.User, error)
-	FindByEmail(ctx *fiber.Ctx, email string) (*model.User, error)
-	CreatUserProfileById(ctx *fiber.Ctx, req *model.ProfileCreateRequest) error
-	UpdateProfileById(ctx *fiber.Ctx, userId uint, req model.ProfileUpdateRequest) error
-	GetProfileById(ctx *fiber.Ctx, userId uint) (*model.Profile, error)
+	Register(ctx context.Context, req *model.User) (*uint, error)
+	FindByUsernameOrEmail(ctx context.Context, req string, isEmail bool) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	FindById(ctx context.Context, userId uint) (*model.User, error)
+	UpdatePassword(ctx context.Context, userId uint, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userId uint) error
+	UpdateVerificationSentAt(ctx context.Context, userId uint, sentAt time.Time) error
+	RecordLoginFailure(ctx context.Context, userId uint) (int, error)
+	LockUntil(ctx context.Context, userId uint, until time.Time) error
+	ResetLoginFailures(ctx context.Context, userId uint) error
+	CreatUserProfileById(ctx context.Context, req *model.ProfileCreateRequest) error
+	UpdateProfileById(ctx context.Context, userId uint, req model.ProfileUpdateRequest) error
+	GetProfileById(ctx context.Context, userId uint) (*model.Profile, error)
+	FindFollowersByUserId(ctx context.Context, userId uint, params pagination.Params, searchQuery string) ([]model.UserWithProfile, pagination.Page, error)
 }
 
 type UsersRepositoryImpl struct {
@@ -35,18 +46,23 @@ var tableUser = "users"
 var tableProfile = "user_profiles"
 var tableFollowers = "follow_users"
 
-func (repository *UsersRepositoryImpl) GetProfileById(ctx *fiber.Ctx, userId uint) (*model.Profile, error) {
+func (repository *UsersRepositoryImpl) GetProfileById(ctx context.Context, userId uint) (*model.Profile, error) {
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	ctx, span := tracing.Start(ctx, "UsersRepository.GetProfileById")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
 
 	var result model.Profile
 	err := tx.
-		WithContext(ctx.Context()).
 		Table(tableProfile).
 		Where("user_id = ?", userId).
 		Take(&result)
 
+	if errors.Is(err.Error, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Profile not found").Wrap(err.Error)
+	}
+
 	if err.Error != nil {
 		return nil, err.Error
 	}
@@ -54,17 +70,23 @@ func (repository *UsersRepositoryImpl) GetProfileById(ctx *fiber.Ctx, userId uin
 	return &result, nil
 }
 
-func (repository *UsersRepositoryImpl) Register(ctx *fiber.Ctx, req *model.User) (*uint, error) {
+func (repository *UsersRepositoryImpl) Register(ctx context.Context, req *model.User) (*uint, error) {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.Register")
+	defer span.End()
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	tx := transaction.DB(ctx, repository.Db)
 
 	// 1. Insert user to user table
 	result := tx.
-		WithContext(ctx.Context()).
 		Table(tableUser).
 		Create(&req)
 
+	// 2. Two registrations of one email can race past the lookup of the service
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, apperror.Conflict("User already exist").Wrap(result.Error)
+	}
+
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -72,14 +94,16 @@ func (repository *UsersRepositoryImpl) Register(ctx *fiber.Ctx, req *model.User)
 	return &req.ID, nil
 }
 
-func (repository *UsersRepositoryImpl) FindByUsernameOrEmail(ctx *fiber.Ctx, req string, isEmail bool) (*model.User, error) {
+func (repository *UsersRepositoryImpl) FindByUsernameOrEmail(ctx context.Context, req string, isEmail bool) (*model.User, error) {
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	ctx, span := tracing.Start(ctx, "UsersRepository.FindByUsernameOrEmail")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
 
 	var result model.User
 
-	query := tx.WithContext(ctx.Context()).Table(tableUser)
+	query := tx.Table(tableUser)
 
 	if isEmail {
 		query = query.Where("email = ?", req).Find(&result)
@@ -95,58 +119,229 @@ func (repository *UsersRepositoryImpl) FindByUsernameOrEmail(ctx *fiber.Ctx, req
 	return &result, nil
 }
 
-func (repository *UsersRepositoryImpl) FindByEmail(ctx *fiber.Ctx, email string) (*model.User, error) {
+func (repository *UsersRepositoryImpl) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.FindByEmail")
+	defer span.End()
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	tx := transaction.DB(ctx, repository.Db)
 
 	var result model.User
+	err := tx.
+		Table(tableUser).
+		Where("email = ? AND deleted_at IS NULL", strings.ToLower(email)).
+		Take(&result).
+		Error
 
-	query := tx.WithContext(ctx.Context()).Table(tableUser)
-	query = query.Where("email = ?", strings.ToLower(email)).Find(&result)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("User not found").Wrap(err)
+	}
 
-	err := query.Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *UsersRepositoryImpl) FindById(ctx context.Context, userId uint) (*model.User, error) {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.FindById")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var result model.User
+	err := tx.
+		Table(tableUser).
+		Where("id = ? AND deleted_at IS NULL", userId).
+		Take(&result).
+		Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("User not found").Wrap(err)
+	}
+
+	if err != nil {
 		return nil, err
 	}
 
 	return &result, nil
 }
 
-func (repository *UsersRepositoryImpl) FindFollowersByUserId(ctx *fiber.Ctx, userId uint, page int, pageSize int, searchQuery string) ([]model.UserWithProfile, int64, error) {
+func (repository *UsersRepositoryImpl) UpdatePassword(ctx context.Context, userId uint, passwordHash string) error {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.UpdatePassword")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUser).
+		Where("id = ?", userId).
+		Updates(map[string]interface{}{
+			"password":   passwordHash,
+			"updated_at": time.Now(),
+		}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *UsersRepositoryImpl) MarkEmailVerified(ctx context.Context, userId uint) error {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.MarkEmailVerified")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUser).
+		Where("id = ? AND email_verified_at IS NULL", userId).
+		Update("email_verified_at", time.Now()).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *UsersRepositoryImpl) UpdateVerificationSentAt(ctx context.Context, userId uint, sentAt time.Time) error {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.UpdateVerificationSentAt")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUser).
+		Where("id = ?", userId).
+		Update("verification_sent_at", sentAt).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordLoginFailure counts a wrong password and returns the wrong passwords
+// in a row. The count is incremented by the database, so concurrent logins
+// do not lose one.
+func (repository *UsersRepositoryImpl) RecordLoginFailure(ctx context.Context, userId uint) (int, error) {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.RecordLoginFailure")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	var failedLogins int
+	err := tx.
+		Raw("UPDATE users SET failed_logins = failed_logins + 1, updated_at = ? WHERE id = ? RETURNING failed_logins", time.Now(), userId).
+		Scan(&failedLogins).
+		Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return failedLogins, nil
+}
+
+func (repository *UsersRepositoryImpl) LockUntil(ctx context.Context, userId uint, until time.Time) error {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.LockUntil")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUser).
+		Where("id = ?", userId).
+		Update("locked_until", until).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResetLoginFailures clears the count and the lock after a good password.
+func (repository *UsersRepositoryImpl) ResetLoginFailures(ctx context.Context, userId uint) error {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.ResetLoginFailures")
+	defer span.End()
+
+	tx := transaction.DB(ctx, repository.Db)
+
+	err := tx.
+		Table(tableUser).
+		Where("id = ?", userId).
+		Updates(map[string]interface{}{
+			"failed_logins": 0,
+			"locked_until":  nil,
+		}).
+		Error
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindFollowersByUserId returns the users following userId, searched by
+// username.
+func (repository *UsersRepositoryImpl) FindFollowersByUserId(ctx context.Context, userId uint, params pagination.Params, searchQuery string) ([]model.UserWithProfile, pagination.Page, error) {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.FindFollowersByUserId")
+	defer span.End()
 
 	var userWithProfile []model.UserWithProfile
 	var totalCount int64
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
-
-	// Offset
-	offset := (page - 1) * pageSize
+	tx := transaction.DB(ctx, repository.Db)
 
 	// Query
-	query := tx.WithContext(ctx.Context()).Table(tableFollowers)
+	query := tx.
+		Table(tableFollowers).
+		Select("follow_users.id AS follow_id, follow_users.user_id, follow_users.following_user_id AS followed_user_id, user_profiles.role, users.username").
+		Joins("JOIN users ON users.id = follow_users.user_id AND users.deleted_at IS NULL").
+		Joins("LEFT JOIN user_profiles ON user_profiles.user_id = follow_users.user_id AND user_profiles.deleted_at IS NULL").
+		Where("follow_users.following_user_id = ? AND follow_users.deleted_at IS NULL", userId)
+
 	if searchQuery != "" {
-		query = query.Where("username LIKE ? ", "%"+searchQuery+"%")
+		query = query.Where("users.username LIKE ? ", "%"+searchQuery+"%")
 	}
 
 	err := query.Count(&totalCount).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, pagination.Page{}, err
 	}
 
 	errResult := query.
-		Offset(offset).
-		Limit(pageSize).
+		Scopes(params.Scope("follow_users.id")).
 		Find(&userWithProfile).
 		Error
 
 	if errResult != nil {
-		return nil, 0, errResult
+		return nil, pagination.Page{}, errResult
 	}
 
-	return userWithProfile, totalCount, nil
+	userWithProfile, page := pagination.Finish(params, userWithProfile, totalCount, func(row model.UserWithProfile) []int64 {
+		return []int64{int64(row.FollowId)}
+	})
+
+	return userWithProfile, page, nil
 }
 
 // func (repository *UsersRepositoryImpl) FindFollowingByUserId(userId int) ([]*model.UserWithProfile, error) {
@@ -157,14 +352,14 @@ func (repository *UsersRepositoryImpl) FindFollowersByUserId(ctx *fiber.Ctx, use
 
 // }
 
-func (repository *UsersRepositoryImpl) UpdateProfileById(ctx *fiber.Ctx, userId uint, req model.ProfileUpdateRequest) error {
+func (repository *UsersRepositoryImpl) UpdateProfileById(ctx context.Context, userId uint, req model.ProfileUpdateRequest) error {
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	ctx, span := tracing.Start(ctx, "UsersRepository.UpdateProfileById")
+	defer span.End()
 
-	fmt.Println(userId)
+	tx := transaction.DB(ctx, repository.Db)
 
-	err := tx.WithContext(ctx.Context()).Table(tableProfile).Where("user_id = ?", userId).Updates(&req)
+	err := tx.Table(tableProfile).Where("user_id = ?", userId).Updates(&req)
 
 	if err != nil {
 		return err.Error
@@ -173,12 +368,14 @@ func (repository *UsersRepositoryImpl) UpdateProfileById(ctx *fiber.Ctx, userId
 	return nil
 }
 
-func (repository *UsersRepositoryImpl) CreatUserProfileById(ctx *fiber.Ctx, req *model.ProfileCreateRequest) error {
+func (repository *UsersRepositoryImpl) CreatUserProfileById(ctx context.Context, req *model.ProfileCreateRequest) error {
+
+	ctx, span := tracing.Start(ctx, "UsersRepository.CreatUserProfileById")
+	defer span.End()
 
-	tx := repository.Db.Begin()
-	defer helper.CommitOrRollback(tx)
+	tx := transaction.DB(ctx, repository.Db)
 
-	err := tx.WithContext(ctx.Context()).Table(tableProfile).Create(&req).Error
+	err := tx.Table(tableProfile).Create(&req).Error
 
 	if err != nil {
 		return err
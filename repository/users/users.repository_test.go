@@ -0,0 +1,427 @@
+package users
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/schema"
+	"project-app/testdb"
+
+	"gorm.io/gorm"
+)
+
+// seedUsers creates alice (1), bob (2), carol (3), dave (4) and erin (5),
+// each with the profile role "<username> role".
+func seedUsers(t *testing.T, db *gorm.DB) {
+
+	t.Helper()
+
+	for _, username := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		user := schema.Users{Username: username, Email: username + "@example.com", Password: "hash"}
+		testdb.Seed(t, db, &user)
+		testdb.Seed(t, db, &schema.UserProfile{UserID: int(user.ID), Role: username + " role"})
+	}
+}
+
+func TestUsersRepositoryRegisterAndFindById(t *testing.T) {
+
+	db := testdb.Open(t)
+	repository := NewUsersRepository(db)
+	ctx := context.Background()
+
+	id, err := repository.Register(ctx, &model.User{Username: "alice", Email: "alice@example.com", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		userId       uint
+		wantUsername string
+		wantKind     apperror.Kind
+	}{
+		{name: "registered user", userId: *id, wantUsername: "alice"},
+		{name: "missing user", userId: *id + 1, wantKind: apperror.KindNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			user, err := repository.FindById(ctx, test.userId)
+
+			if test.wantKind != "" {
+				if !apperror.Is(err, test.wantKind) {
+					t.Fatalf("err = %v, want kind %s", err, test.wantKind)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FindById: %v", err)
+			}
+
+			if user.Username != test.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, test.wantUsername)
+			}
+		})
+	}
+}
+
+func TestUsersRepositoryRegisterDuplicateEmail(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+	repository := NewUsersRepository(db)
+
+	// The unique index is on lower(email), the lookup of the service can be raced
+	_, err := repository.Register(context.Background(), &model.User{Username: "bobby", Email: "BOB@example.com", Password: "hash"})
+	if !apperror.Is(err, apperror.KindConflict) {
+		t.Errorf("err = %v, want kind %s", err, apperror.KindConflict)
+	}
+
+	// A deleted account does not hold on to its email
+	if err := db.Delete(&schema.Users{}, 2).Error; err != nil {
+		t.Fatalf("delete bob: %v", err)
+	}
+	if _, err := repository.Register(context.Background(), &model.User{Username: "bobby", Email: "bob@example.com", Password: "hash"}); err != nil {
+		t.Errorf("Register after delete: %v", err)
+	}
+}
+
+func TestUsersRepositoryFindByEmail(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+	repository := NewUsersRepository(db)
+
+	tests := []struct {
+		name         string
+		email        string
+		wantUsername string
+		wantKind     apperror.Kind
+	}{
+		{name: "existing email", email: "bob@example.com", wantUsername: "bob"},
+		{name: "email in other case", email: "Bob@Example.com", wantUsername: "bob"},
+		{name: "missing email", email: "nobody@example.com", wantKind: apperror.KindNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			user, err := repository.FindByEmail(context.Background(), test.email)
+
+			if test.wantKind != "" {
+				if !apperror.Is(err, test.wantKind) {
+					t.Fatalf("err = %v, want kind %s", err, test.wantKind)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FindByEmail: %v", err)
+			}
+
+			if user.Username != test.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, test.wantUsername)
+			}
+		})
+	}
+}
+
+func TestUsersRepositoryFindByEmailFails(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+	repository := NewUsersRepository(db)
+
+	// A failed query is not a missing user
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repository.FindByEmail(ctx, "bob@example.com")
+	if err == nil || apperror.Is(err, apperror.KindNotFound) {
+		t.Errorf("err = %v, want the error of the query", err)
+	}
+}
+
+func TestUsersRepositoryFindByUsernameOrEmail(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+	repository := NewUsersRepository(db)
+
+	tests := []struct {
+		name         string
+		value        string
+		isEmail      bool
+		wantUsername string
+	}{
+		{name: "by username", value: "carol", wantUsername: "carol"},
+		{name: "by email", value: "dave@example.com", isEmail: true, wantUsername: "dave"},
+		{name: "email is not a username", value: "dave@example.com", wantUsername: ""},
+		{name: "missing username", value: "nobody", wantUsername: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			user, err := repository.FindByUsernameOrEmail(context.Background(), test.value, test.isEmail)
+			if err != nil {
+				t.Fatalf("FindByUsernameOrEmail: %v", err)
+			}
+
+			if user.Username != test.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, test.wantUsername)
+			}
+		})
+	}
+}
+
+func TestUsersRepositoryUpdates(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+	repository := NewUsersRepository(db)
+	ctx := context.Background()
+
+	// 1. Password
+	if err := repository.UpdatePassword(ctx, 1, "new hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+
+	// 2. Verification, a second call keeps the first time
+	if err := repository.MarkEmailVerified(ctx, 1); err != nil {
+		t.Fatalf("MarkEmailVerified: %v", err)
+	}
+
+	first, err := repository.FindById(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := repository.MarkEmailVerified(ctx, 1); err != nil {
+		t.Fatalf("MarkEmailVerified: %v", err)
+	}
+
+	// 3. Verification mail
+	sentAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if err := repository.UpdateVerificationSentAt(ctx, 1, sentAt); err != nil {
+		t.Fatalf("UpdateVerificationSentAt: %v", err)
+	}
+
+	user, err := repository.FindById(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+
+	if user.Password != "new hash" {
+		t.Errorf("password = %q, want %q", user.Password, "new hash")
+	}
+
+	if user.EmailVerifiedAt == nil || !user.EmailVerifiedAt.Equal(*first.EmailVerifiedAt) {
+		t.Errorf("email verified at = %v, want %v", user.EmailVerifiedAt, first.EmailVerifiedAt)
+	}
+
+	if user.VerificationSentAt == nil || !user.VerificationSentAt.Equal(sentAt) {
+		t.Errorf("verification sent at = %v, want %v", user.VerificationSentAt, sentAt)
+	}
+
+	// 4. The other users are untouched
+	other, err := repository.FindById(ctx, 2)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+
+	if other.Password != "hash" || other.EmailVerifiedAt != nil || other.VerificationSentAt != nil {
+		t.Errorf("user 2 was changed: %+v", other)
+	}
+}
+
+func TestUsersRepositoryLoginFailures(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+	repository := NewUsersRepository(db)
+	ctx := context.Background()
+
+	// 1. Every failure is counted
+	for want := 1; want <= 3; want++ {
+		failedLogins, err := repository.RecordLoginFailure(ctx, 1)
+		if err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+		if failedLogins != want {
+			t.Fatalf("failed logins = %d, want %d", failedLogins, want)
+		}
+	}
+
+	until := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if err := repository.LockUntil(ctx, 1, until); err != nil {
+		t.Fatalf("LockUntil: %v", err)
+	}
+
+	user, err := repository.FindByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("FindByEmail: %v", err)
+	}
+	if user.FailedLogins != 3 || user.LockedUntil == nil || !user.LockedUntil.Equal(until) {
+		t.Errorf("failed logins = %d, locked until = %v, want 3 and %v", user.FailedLogins, user.LockedUntil, until)
+	}
+
+	// 2. A good password clears both
+	if err := repository.ResetLoginFailures(ctx, 1); err != nil {
+		t.Fatalf("ResetLoginFailures: %v", err)
+	}
+
+	user, err = repository.FindById(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if user.FailedLogins != 0 || user.LockedUntil != nil {
+		t.Errorf("failed logins = %d, locked until = %v, want cleared", user.FailedLogins, user.LockedUntil)
+	}
+
+	// 3. A missing user has nothing to count
+	failedLogins, err := repository.RecordLoginFailure(ctx, 99)
+	if err != nil || failedLogins != 0 {
+		t.Errorf("RecordLoginFailure of a missing user = %d, %v", failedLogins, err)
+	}
+}
+
+func TestUsersRepositoryProfile(t *testing.T) {
+
+	db := testdb.Open(t)
+	testdb.Seed(t, db, &schema.Users{Username: "alice", Email: "alice@example.com"})
+	repository := NewUsersRepository(db)
+	ctx := context.Background()
+
+	_, err := repository.GetProfileById(ctx, 1)
+	if !apperror.Is(err, apperror.KindNotFound) {
+		t.Fatalf("err = %v before the profile exists, want not found", err)
+	}
+
+	if err := repository.CreatUserProfileById(ctx, &model.ProfileCreateRequest{UserId: 1, Role: "Designer"}); err != nil {
+		t.Fatalf("CreatUserProfileById: %v", err)
+	}
+
+	if err := repository.UpdateProfileById(ctx, 1, model.ProfileUpdateRequest{Bio: "Hello", Twitter: "https://twitter.com/alice"}); err != nil {
+		t.Fatalf("UpdateProfileById: %v", err)
+	}
+
+	profile, err := repository.GetProfileById(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetProfileById: %v", err)
+	}
+
+	// Empty fields of the update keep their value
+	if profile.Role != "Designer" || profile.Bio != "Hello" || profile.Twitter != "https://twitter.com/alice" {
+		t.Errorf("profile = %+v", profile)
+	}
+}
+
+func TestUsersRepositoryFindFollowersByUserId(t *testing.T) {
+
+	db := testdb.Open(t)
+	seedUsers(t, db)
+
+	// bob, carol, dave and erin follow alice, alice follows bob. dave stopped
+	// following alice.
+	testdb.Seed(t, db,
+		&schema.FollowUsers{UserID: 2, FollowingUserID: 1},
+		&schema.FollowUsers{UserID: 3, FollowingUserID: 1},
+		&schema.FollowUsers{UserID: 4, FollowingUserID: 1},
+		&schema.FollowUsers{UserID: 5, FollowingUserID: 1},
+		&schema.FollowUsers{UserID: 1, FollowingUserID: 2},
+	)
+	if err := db.Delete(&schema.FollowUsers{}, 3).Error; err != nil {
+		t.Fatalf("unfollow: %v", err)
+	}
+
+	repository := &UsersRepositoryImpl{Db: db}
+
+	tests := []struct {
+		name          string
+		userId        uint
+		page          int
+		pageSize      int
+		searchQuery   string
+		wantUsernames []string
+		wantTotal     int64
+	}{
+		{
+			name:          "first page",
+			userId:        1,
+			page:          1,
+			pageSize:      2,
+			wantUsernames: []string{"bob", "carol"},
+			wantTotal:     3,
+		},
+		{
+			name:          "second page",
+			userId:        1,
+			page:          2,
+			pageSize:      2,
+			wantUsernames: []string{"erin"},
+			wantTotal:     3,
+		},
+		{
+			name:          "search by username",
+			userId:        1,
+			page:          1,
+			pageSize:      10,
+			searchQuery:   "ri",
+			wantUsernames: []string{"erin"},
+			wantTotal:     1,
+		},
+		{
+			name:          "search without matches",
+			userId:        1,
+			page:          1,
+			pageSize:      10,
+			searchQuery:   "dave",
+			wantUsernames: []string{},
+			wantTotal:     0,
+		},
+		{
+			name:          "followers of another user",
+			userId:        2,
+			page:          1,
+			pageSize:      10,
+			wantUsernames: []string{"alice"},
+			wantTotal:     1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			followers, page, err := repository.FindFollowersByUserId(context.Background(), test.userId, pagination.Offset(test.page, test.pageSize), test.searchQuery)
+			if err != nil {
+				t.Fatalf("FindFollowersByUserId: %v", err)
+			}
+
+			usernames := []string{}
+			for _, follower := range followers {
+				usernames = append(usernames, follower.Username)
+
+				if follower.FollowId == 0 || follower.FollowedUserId != int(test.userId) || follower.Role != follower.Username+" role" {
+					t.Errorf("follower = %+v", follower)
+				}
+			}
+
+			if !reflect.DeepEqual(usernames, test.wantUsernames) {
+				t.Errorf("usernames = %q, want %q", usernames, test.wantUsernames)
+			}
+
+			if page.TotalEntries != test.wantTotal {
+				t.Errorf("total = %d, want %d", page.TotalEntries, test.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	app2 "project-app/app"
+	"project-app/config"
+	"project-app/helper"
+	"project-app/mailer"
+	"project-app/metrics"
+	"project-app/testdb"
+	"project-app/tracing"
+	"project-app/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// volatileKeys are replaced in the golden files, their values change on
+// every run.
+var volatileKeys = map[string]bool{
+	"token":        true,
+	"refreshToken": true,
+	"CreatedAt":    true,
+	"UpdatedAt":    true,
+}
+
+// testApp is the app of SetupRoutes on an empty database.
+type testApp struct {
+	t      *testing.T
+	App    *fiber.App
+	Db     *gorm.DB
+	Config *config.Config
+}
+
+type response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// newTestApp runs the app with the default configuration, after options
+// changed it.
+func newTestApp(t *testing.T, options ...func(cfg *config.Config)) *testApp {
+
+	t.Helper()
+
+	db := testdb.Open(t)
+	if err := app2.SeedRoles(db, ""); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+
+	// Like main, without the stats of the pool that are registered once
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		t.Fatalf("metrics plugin: %v", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		t.Fatalf("tracing plugin: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.Mail.LogFile = filepath.Join(t.TempDir(), "mail.log")
+	for _, option := range options {
+		option(cfg)
+	}
+	helper.SetJwtSecret("test")
+	helper.PasswordHashCost = bcrypt.MinCost
+
+	app := fiber.New(app2.FiberConfig(cfg))
+	SetupRoutes(app, db, validation.Validator(), cfg, mailer.NewMailer(cfg.Mail))
+
+	return &testApp{t: t, App: app, Db: db, Config: cfg}
+}
+
+// Do sends the request with body encoded as JSON when it is not nil, and
+// with the access token when it is not empty.
+func (test *testApp) Do(method string, path string, token string, body interface{}) response {
+
+	test.t.Helper()
+
+	return test.DoWithHeaders(method, path, token, body, nil)
+}
+
+// DoWithHeaders is Do with more request headers.
+func (test *testApp) DoWithHeaders(method string, path string, token string, body interface{}, headers map[string]string) response {
+
+	test.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			test.t.Fatalf("encode body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := test.App.Test(req, -1)
+	if err != nil {
+		test.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		test.t.Fatalf("%s %s: %v", method, path, err)
+	}
+
+	return response{Status: res.StatusCode, Header: res.Header, Body: responseBody}
+}
+
+// Register registers a user with the password "password123".
+func (test *testApp) Register(username string) {
+
+	test.t.Helper()
+
+	res := test.Do(fiber.MethodPost, "/api/v1/user/register", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": "password123",
+	})
+	if res.Status != fiber.StatusOK {
+		test.t.Fatalf("register %s: %d %s", username, res.Status, res.Body)
+	}
+}
+
+// Login returns the access token of a user created by Register.
+func (test *testApp) Login(username string) string {
+
+	test.t.Helper()
+
+	res := test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{
+		"email":    username + "@example.com",
+		"password": "password123",
+	})
+	if res.Status != fiber.StatusOK {
+		test.t.Fatalf("login %s: %d %s", username, res.Status, res.Body)
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		test.t.Fatalf("login %s: %v", username, err)
+	}
+
+	return body.Token
+}
+
+// mailToken matches the token of the links in the emails.
+var mailToken = regexp.MustCompile(`\?token=([^\s]+)`)
+
+// MailToken returns the token of the link in the last email sent to email.
+func (test *testApp) MailToken(email string) string {
+
+	test.t.Helper()
+
+	content, err := os.ReadFile(test.Config.Mail.LogFile)
+	if err != nil {
+		test.t.Fatalf("read emails: %v", err)
+	}
+
+	// Emails are appended, the last one comes last
+	messages := strings.Split(string(content), "\r\nTo: ")
+	for i := len(messages) - 1; i > 0; i-- {
+		if !strings.HasPrefix(messages[i], email+"\r\n") {
+			continue
+		}
+
+		match := mailToken.FindStringSubmatch(messages[i])
+		if match == nil {
+			test.t.Fatalf("email to %s has no link with a token", email)
+		}
+
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			test.t.Fatalf("email to %s: %v", email, err)
+		}
+		return token
+	}
+
+	test.t.Fatalf("no email sent to %s", email)
+	return ""
+}
+
+// Golden compares the response with testdata/<test name>/<name>.json. Run
+// the tests with -update to write the files.
+func (test *testApp) Golden(name string, res response) {
+
+	test.t.Helper()
+
+	var body interface{}
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		test.t.Fatalf("%s: response is not JSON: %s", name, res.Body)
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(map[string]interface{}{
+		"status": res.Status,
+		"body":   normalize(body),
+	})
+	if err != nil {
+		test.t.Fatalf("%s: %v", name, err)
+	}
+	got := buffer.Bytes()
+
+	path := filepath.Join("testdata", filepath.FromSlash(test.t.Name()), name+".json")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			test.t.Fatalf("%s: %v", name, err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			test.t.Fatalf("%s: %v", name, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		test.t.Fatalf("%s: %v, run the tests with -update to create it", name, err)
+	}
+
+	if !bytes.Equal(got, want) {
+		test.t.Errorf("%s: response does not match %s\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+// normalize replaces the values of volatileKeys that are set.
+func normalize(value interface{}) interface{} {
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if volatileKeys[key] && field != nil && field != "" {
+				value[key] = "<" + strings.ToLower(key) + ">"
+				continue
+			}
+			value[key] = normalize(field)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalize(item)
+		}
+	}
+
+	return value
+}
@@ -1,26 +1,48 @@
 package routes
 
 import (
-	"fmt"
+	"project-app/config"
 	"project-app/handler/category"
+	healthHandler "project-app/handler/health"
+	"project-app/handler/project"
+	"project-app/handler/projectitem"
+	"project-app/handler/rbac"
 	"project-app/handler/users"
+	"project-app/health"
 	"project-app/helper"
+	"project-app/mailer"
+	"project-app/metrics"
+	"project-app/middleware"
+	"project-app/model"
+	"project-app/ratelimit"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/swagger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(app *fiber.App, db *gorm.DB, validate *validator.Validate) {
+func SetupRoutes(app *fiber.App, db *gorm.DB, validate *validator.Validate, cfg *config.Config, mail mailer.Mailer) {
 
-	app.Use(func(c *fiber.Ctx) error {
-		fmt.Printf("Request: %s %s \n", c.Method(), c.OriginalURL())
-		return c.Next()
-	})
+	app.Use(middleware.RequestId())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.AccessLog())
+	app.Use(middleware.Metrics())
+	app.Use(middleware.RequestContext(cfg.App.RequestTimeout, cfg.App.ShutdownGrace()))
 
-	userHandler := users.NewUsersHandler(db, validate)
+	userHandler := users.NewUsersHandler(db, validate, cfg, mail)
 	categoryHandler := category.NewCategoryHandler(db, validate)
+	projectHandler := project.NewProjectHandler(db, validate)
+	projectItemHandler := projectitem.NewProjectItemHandler(db, validate)
+	rbacHandler := rbac.NewRbacHandler(db, validate)
+	rbacMiddleware := middleware.NewRbacMiddleware(db)
+	can := rbacMiddleware.RequirePermission
+	verified := middleware.NewEmailVerificationMiddleware(db, cfg.Auth.EmailVerificationPolicy).RequireVerifiedEmail
+	limits := ratelimit.NewMemoryStore()
+	loginPerIp := middleware.RateLimit(limits, "login:ip", ratelimit.PerMinute(cfg.RateLimit.LoginPerIp), middleware.KeyByIp)
+	loginPerAccount := middleware.RateLimit(limits, "login:account", ratelimit.PerMinute(cfg.RateLimit.LoginPerAccount), middleware.KeyByLoginEmail)
 
 	appGroup := app.Group("/api/v1")
 
@@ -29,16 +51,63 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, validate *validator.Validate) {
 
 	// Users
 	usersGroup := appGroup.Group("user")
-	usersGroup.Post("/login", userHandler.Login)
+	usersGroup.Post("/login", loginPerIp, loginPerAccount, userHandler.Login)
 	usersGroup.Post("/register", userHandler.Register)
+	usersGroup.Post("/token/refresh", userHandler.RefreshToken)
+	usersGroup.Post("/logout", userHandler.Logout)
+	usersGroup.Post("/password/forgot", userHandler.ForgotPassword)
+	usersGroup.Post("/password/reset", userHandler.ResetPassword)
+	usersGroup.Get("/verify", userHandler.VerifyEmail)
+	usersGroup.Post("/verify/resend", userHandler.ResendVerification)
 	usersGroup.Get("/profile/:user_id", helper.VerifyToken, userHandler.GetProfileById)
-	usersGroup.Put("/profile", helper.VerifyToken, userHandler.UpdateProfileById)
+	usersGroup.Put("/profile", helper.VerifyToken, verified, userHandler.UpdateProfileById)
+	usersGroup.Get("/:user_id/followers", helper.VerifyToken, userHandler.FindFollowers)
 
 	// Category
-	categoryGroup := appGroup.Group("category")
-	categoryGroup.Post("/", categoryHandler.Create)
-	categoryGroup.Put("/", categoryHandler.Update)
-	categoryGroup.Delete("/:id", categoryHandler.Delete)
-	categoryGroup.Get("/", categoryHandler.FindAll)
+	categoryGroup := appGroup.Group("category", helper.VerifyToken, verified)
+	categoryGroup.Post("/", can(model.PermissionCategoryWrite), categoryHandler.Create)
+	categoryGroup.Put("/:id", can(model.PermissionCategoryWrite), categoryHandler.Update)
+	categoryGroup.Delete("/:id", can(model.PermissionCategoryWrite), categoryHandler.Delete)
+	categoryGroup.Get("/", can(model.PermissionCategoryRead), categoryHandler.FindAll)
 
+	// Project
+	projectGroup := appGroup.Group("project", helper.VerifyToken, verified)
+	projectGroup.Post("/", can(model.PermissionProjectWrite), projectHandler.Create)
+	projectGroup.Get("/", can(model.PermissionProjectRead), projectHandler.FindAll)
+	projectGroup.Get("/:id", can(model.PermissionProjectRead), projectHandler.FindById)
+	projectGroup.Put("/:id", can(model.PermissionProjectWrite), projectHandler.Update)
+	projectGroup.Delete("/:id", can(model.PermissionProjectWrite), projectHandler.Delete)
+
+	// Project item
+	projectGroup.Get("/:id/items", can(model.PermissionProjectRead), projectItemHandler.FindAll)
+	projectGroup.Post("/:id/items", can(model.PermissionProjectWrite), projectItemHandler.Create)
+	projectGroup.Put("/:id/items/order", can(model.PermissionProjectWrite), projectItemHandler.Reorder)
+	projectGroup.Put("/:id/items/:itemId", can(model.PermissionProjectWrite), projectItemHandler.Update)
+	projectGroup.Patch("/:id/items/:itemId/status", can(model.PermissionProjectWrite), projectItemHandler.ToggleStatus)
+	projectGroup.Delete("/:id/items/:itemId", can(model.PermissionProjectWrite), projectItemHandler.Delete)
+
+	// Admin
+	adminGroup := appGroup.Group("admin", helper.VerifyToken)
+	adminGroup.Get("/roles", can(model.PermissionRoleRead), rbacHandler.FindAllRoles)
+	adminGroup.Get("/users/:user_id/roles", can(model.PermissionRoleRead), rbacHandler.FindUserRoles)
+	adminGroup.Post("/users/:user_id/roles", can(model.PermissionRoleWrite), rbacHandler.AssignRole)
+	adminGroup.Delete("/users/:user_id/roles/:role_id", can(model.PermissionRoleWrite), rbacHandler.RevokeRole)
+
+}
+
+// SetupHealthRoutes serves the probes of the orchestrator. Call it before
+// SetupRoutes, so the probes skip the middlewares of the API.
+func SetupHealthRoutes(app *fiber.App, registry *health.Registry) {
+
+	healthHandler := healthHandler.NewHealthHandler(registry)
+
+	app.Get("/healthz", healthHandler.Liveness)
+	app.Get("/readyz", healthHandler.Readiness)
+}
+
+// SetupMetricsRoutes serves the metrics for Prometheus. Call it before
+// SetupRoutes, so the scrapes are not counted.
+func SetupMetricsRoutes(app *fiber.App) {
+
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
 }
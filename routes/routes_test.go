@@ -0,0 +1,771 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	app2 "project-app/app"
+	"project-app/config"
+	"project-app/logger"
+	"project-app/metrics"
+	"project-app/schema"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/exp/slog"
+)
+
+func TestUserEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+
+	// 1. Register
+	body := map[string]string{"username": "alice", "email": "alice@example.com", "password": "password123"}
+	test.Golden("register", test.Do(fiber.MethodPost, "/api/v1/user/register", "", body))
+	test.Golden("register_duplicate", test.Do(fiber.MethodPost, "/api/v1/user/register", "", body))
+	test.Golden("register_invalid", test.Do(fiber.MethodPost, "/api/v1/user/register", "", map[string]string{
+		"username": "al",
+		"email":    "alice",
+		"password": "short",
+	}))
+	test.Golden("register_invalid_id", test.DoWithHeaders(fiber.MethodPost, "/api/v1/user/register", "", map[string]string{
+		"username": "al",
+		"email":    "alice",
+		"password": "short",
+	}, map[string]string{fiber.HeaderAcceptLanguage: "id-ID,id;q=0.9,en;q=0.8"}))
+
+	// 2. Login
+	test.Golden("login", test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{
+		"email":    "alice@example.com",
+		"password": "password123",
+	}))
+	test.Golden("login_wrong_password", test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{
+		"email":    "alice@example.com",
+		"password": "wrong password",
+	}))
+
+	token := test.Login("alice")
+
+	// 3. Profile
+	test.Golden("profile_without_token", test.Do(fiber.MethodGet, "/api/v1/user/profile/1", "", nil))
+	test.Golden("profile", test.Do(fiber.MethodGet, "/api/v1/user/profile/1", token, nil))
+	test.Golden("profile_missing", test.Do(fiber.MethodGet, "/api/v1/user/profile/100", token, nil))
+	test.Golden("profile_invalid_id", test.Do(fiber.MethodGet, "/api/v1/user/profile/abc", token, nil))
+
+	test.Golden("profile_update", test.Do(fiber.MethodPut, "/api/v1/user/profile", token, map[string]string{
+		"bio":     "Designer from Bandung",
+		"role":    "Designer",
+		"twitter": "https://twitter.com/alice",
+	}))
+	test.Golden("profile_update_invalid", test.Do(fiber.MethodPut, "/api/v1/user/profile", token, map[string]string{
+		"twitter": "not a url",
+	}))
+	test.Golden("profile_updated", test.Do(fiber.MethodGet, "/api/v1/user/profile/1", token, nil))
+}
+
+func TestCategoryEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+	test.Register("bob")
+	alice := test.Login("alice")
+	bob := test.Login("bob")
+
+	// 1. Create
+	test.Golden("create_without_token", test.Do(fiber.MethodPost, "/api/v1/category/", "", map[string]string{"name": "Design"}))
+	test.Golden("create", test.Do(fiber.MethodPost, "/api/v1/category/", alice, map[string]string{"name": "Design"}))
+	test.Golden("create_invalid", test.Do(fiber.MethodPost, "/api/v1/category/", alice, map[string]string{"name": ""}))
+
+	// 2. Update
+	test.Golden("update", test.Do(fiber.MethodPut, "/api/v1/category/1", alice, map[string]string{"name": "Marketing"}))
+	test.Golden("update_by_other_user", test.Do(fiber.MethodPut, "/api/v1/category/1", bob, map[string]string{"name": "Sales"}))
+	test.Golden("update_missing", test.Do(fiber.MethodPut, "/api/v1/category/100", alice, map[string]string{"name": "Sales"}))
+	test.Golden("list_after_update", test.Do(fiber.MethodGet, "/api/v1/category/", alice, nil))
+	test.Golden("list_of_other_user", test.Do(fiber.MethodGet, "/api/v1/category/", bob, nil))
+
+	// 3. Delete
+	test.Golden("delete_by_other_user", test.Do(fiber.MethodDelete, "/api/v1/category/1", bob, nil))
+	test.Golden("delete", test.Do(fiber.MethodDelete, "/api/v1/category/1", alice, nil))
+	test.Golden("delete_again", test.Do(fiber.MethodDelete, "/api/v1/category/1", alice, nil))
+	test.Golden("list_after_delete", test.Do(fiber.MethodGet, "/api/v1/category/", alice, nil))
+}
+
+func TestCategoryPagination(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+	token := test.Login("alice")
+
+	for i := 1; i <= 5; i++ {
+		res := test.Do(fiber.MethodPost, "/api/v1/category/", token, map[string]string{"name": fmt.Sprintf("Design %d", i)})
+		if res.Status != fiber.StatusOK {
+			t.Fatalf("create category: %d %s", res.Status, res.Body)
+		}
+	}
+	test.Do(fiber.MethodPost, "/api/v1/category/", token, map[string]string{"name": "Marketing"})
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "default_page", path: "/api/v1/category/"},
+		{name: "first_page", path: "/api/v1/category/?page=1&pageSize=4"},
+		{name: "second_page", path: "/api/v1/category/?page=2&pageSize=4"},
+		{name: "page_past_the_end", path: "/api/v1/category/?page=3&pageSize=4"},
+		{name: "search", path: "/api/v1/category/?categoryName=Design&pageSize=2"},
+		{name: "search_second_page", path: "/api/v1/category/?categoryName=Design&page=2&pageSize=2"},
+		{name: "search_without_matches", path: "/api/v1/category/?categoryName=Finance"},
+		{name: "page_size_zero", path: "/api/v1/category/?pageSize=0"},
+		{name: "page_size_above_max", path: "/api/v1/category/?pageSize=101"},
+		{name: "invalid_page_and_cursor", path: "/api/v1/category/?page=two&cursor=bad"},
+	}
+
+	for _, tt := range tests {
+		test.Golden(tt.name, test.Do(fiber.MethodGet, tt.path, token, nil))
+	}
+
+	// Follow the Link header from the first page to the last and back
+	links := func(res response) map[string]string {
+		t.Helper()
+		if res.Status != fiber.StatusOK {
+			t.Fatalf("list: %d %s", res.Status, res.Body)
+		}
+
+		found := map[string]string{}
+		for _, link := range strings.Split(res.Header.Get(fiber.HeaderLink), ", ") {
+			target, rel, _ := strings.Cut(link, "; ")
+			found[strings.Trim(strings.TrimPrefix(rel, "rel="), `"`)] = strings.TrimPrefix(strings.Trim(target, "<>"), "http://example.com")
+		}
+		return found
+	}
+
+	first := test.Do(fiber.MethodGet, "/api/v1/category/?pageSize=4&categoryName=Design", token, nil)
+	if got := links(first); got["next"] != "/api/v1/category/?categoryName=Design&page=2&pageSize=4" || got["last"] != "/api/v1/category/?categoryName=Design&page=2&pageSize=4" {
+		t.Errorf("links of the first page = %v", got)
+	}
+
+	var body struct {
+		NextCursor string `json:"nextCursor"`
+	}
+	if err := json.Unmarshal(first.Body, &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	test.Golden("after_cursor", test.Do(fiber.MethodGet, "/api/v1/category/?pageSize=4&categoryName=Design&cursor="+body.NextCursor, token, nil))
+
+	next := test.Do(fiber.MethodGet, "/api/v1/category/?pageSize=4&categoryName=Design&cursor="+body.NextCursor, token, nil)
+	prev := links(next)["prev"]
+	if _, ok := links(next)["next"]; ok || prev == "" {
+		t.Fatalf("links after the cursor = %v", links(next))
+	}
+
+	test.Golden("before_cursor", test.Do(fiber.MethodGet, prev, token, nil))
+}
+
+func TestFollowerEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	for _, username := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		test.Register(username)
+	}
+	token := test.Login("alice")
+
+	// Everyone follows alice, there is no route to follow yet
+	for userId := 2; userId <= 5; userId++ {
+		if err := test.Db.Create(&schema.FollowUsers{UserID: userId, FollowingUserID: 1}).Error; err != nil {
+			t.Fatalf("follow: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+	}{
+		{name: "without_token", path: "/api/v1/user/1/followers"},
+		{name: "first_page", path: "/api/v1/user/1/followers?pageSize=3", token: token},
+		{name: "second_page", path: "/api/v1/user/1/followers?page=2&pageSize=3", token: token},
+		{name: "search", path: "/api/v1/user/1/followers?username=ar", token: token},
+		{name: "without_followers", path: "/api/v1/user/2/followers", token: token},
+		{name: "unknown_user", path: "/api/v1/user/100/followers", token: token},
+		{name: "invalid_user_id", path: "/api/v1/user/alice/followers", token: token},
+		{name: "page_size_above_max", path: "/api/v1/user/1/followers?pageSize=101", token: token},
+	}
+
+	for _, tt := range tests {
+		test.Golden(tt.name, test.Do(fiber.MethodGet, tt.path, tt.token, nil))
+	}
+
+	// The cursor of the first page continues where it ended
+	var body struct {
+		NextCursor string `json:"nextCursor"`
+	}
+	first := test.Do(fiber.MethodGet, "/api/v1/user/1/followers?pageSize=3", token, nil)
+	if err := json.Unmarshal(first.Body, &body); err != nil || body.NextCursor == "" {
+		t.Fatalf("first page: %v %s", err, first.Body)
+	}
+
+	test.Golden("after_cursor", test.Do(fiber.MethodGet, "/api/v1/user/1/followers?pageSize=3&cursor="+body.NextCursor, token, nil))
+}
+
+func TestProjectEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+	test.Register("bob")
+	alice := test.Login("alice")
+	bob := test.Login("bob")
+
+	test.Do(fiber.MethodPost, "/api/v1/category/", alice, map[string]string{"name": "Design"})
+	project := map[string]interface{}{"categoryId": 1, "name": "Website", "description": "Company profile", "budget": 1000}
+
+	// 1. Create
+	test.Golden("create_without_token", test.Do(fiber.MethodPost, "/api/v1/project/", "", project))
+	test.Golden("create", test.Do(fiber.MethodPost, "/api/v1/project/", alice, project))
+	test.Golden("create_invalid", test.Do(fiber.MethodPost, "/api/v1/project/", alice, map[string]interface{}{"name": "", "budget": -1}))
+
+	// 2. Read
+	test.Golden("find", test.Do(fiber.MethodGet, "/api/v1/project/1", alice, nil))
+	test.Golden("find_missing", test.Do(fiber.MethodGet, "/api/v1/project/100", alice, nil))
+	test.Golden("find_invalid_id", test.Do(fiber.MethodGet, "/api/v1/project/abc", alice, nil))
+	test.Golden("list", test.Do(fiber.MethodGet, "/api/v1/project/", alice, nil))
+
+	// 3. Update
+	project["name"] = "Website v2"
+	project["budget"] = 2000
+	test.Golden("update", test.Do(fiber.MethodPut, "/api/v1/project/1", alice, project))
+	test.Golden("update_invalid", test.Do(fiber.MethodPut, "/api/v1/project/1", alice, map[string]interface{}{"categoryId": 1, "name": ""}))
+	test.Golden("find_after_update", test.Do(fiber.MethodGet, "/api/v1/project/1", alice, nil))
+
+	// 4. Another user cannot see the project
+	test.Golden("find_by_other_user", test.Do(fiber.MethodGet, "/api/v1/project/1", bob, nil))
+	test.Golden("update_by_other_user", test.Do(fiber.MethodPut, "/api/v1/project/1", bob, project))
+	test.Golden("delete_by_other_user", test.Do(fiber.MethodDelete, "/api/v1/project/1", bob, nil))
+	test.Golden("list_of_other_user", test.Do(fiber.MethodGet, "/api/v1/project/", bob, nil))
+
+	// 5. Delete
+	test.Golden("delete", test.Do(fiber.MethodDelete, "/api/v1/project/1", alice, nil))
+	test.Golden("delete_again", test.Do(fiber.MethodDelete, "/api/v1/project/1", alice, nil))
+	test.Golden("list_after_delete", test.Do(fiber.MethodGet, "/api/v1/project/", alice, nil))
+}
+
+func TestProjectItemEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+	test.Register("bob")
+	alice := test.Login("alice")
+	bob := test.Login("bob")
+
+	test.Do(fiber.MethodPost, "/api/v1/category/", alice, map[string]string{"name": "Design"})
+	test.Do(fiber.MethodPost, "/api/v1/project/", alice, map[string]interface{}{"categoryId": 1, "name": "Website", "budget": 1000})
+
+	// 1. Create
+	test.Golden("create", test.Do(fiber.MethodPost, "/api/v1/project/1/items", alice, map[string]interface{}{"name": "Design", "budgetItem": 300}))
+	test.Do(fiber.MethodPost, "/api/v1/project/1/items", alice, map[string]interface{}{"name": "Development", "budgetItem": 500})
+	test.Do(fiber.MethodPost, "/api/v1/project/1/items", alice, map[string]interface{}{"name": "Hosting", "budgetItem": 100})
+	test.Golden("create_invalid", test.Do(fiber.MethodPost, "/api/v1/project/1/items", alice, map[string]interface{}{"name": "", "budgetItem": -1}))
+	test.Golden("create_in_missing_project", test.Do(fiber.MethodPost, "/api/v1/project/100/items", alice, map[string]interface{}{"name": "Design"}))
+	test.Golden("list", test.Do(fiber.MethodGet, "/api/v1/project/1/items", alice, nil))
+
+	// 2. Update and toggle
+	test.Golden("update", test.Do(fiber.MethodPut, "/api/v1/project/1/items/1", alice, map[string]interface{}{"name": "UI design", "budgetItem": 400}))
+	test.Golden("update_missing", test.Do(fiber.MethodPut, "/api/v1/project/1/items/100", alice, map[string]interface{}{"name": "UI design"}))
+	test.Golden("update_invalid_id", test.Do(fiber.MethodPut, "/api/v1/project/1/items/abc", alice, map[string]interface{}{"name": "UI design"}))
+	test.Golden("toggle", test.Do(fiber.MethodPatch, "/api/v1/project/1/items/2/status", alice, nil))
+	test.Golden("toggle_missing", test.Do(fiber.MethodPatch, "/api/v1/project/1/items/100/status", alice, nil))
+
+	// 3. Reorder
+	test.Golden("reorder", test.Do(fiber.MethodPut, "/api/v1/project/1/items/order", alice, map[string]interface{}{"itemIds": []uint{3, 1, 2}}))
+	test.Golden("reorder_invalid", test.Do(fiber.MethodPut, "/api/v1/project/1/items/order", alice, map[string]interface{}{"itemIds": []uint{}}))
+	test.Golden("reorder_unknown_item", test.Do(fiber.MethodPut, "/api/v1/project/1/items/order", alice, map[string]interface{}{"itemIds": []uint{3, 1, 100}}))
+	test.Golden("list_after_reorder", test.Do(fiber.MethodGet, "/api/v1/project/1/items", alice, nil))
+
+	// 4. Another user cannot see the items of the project
+	test.Golden("list_by_other_user", test.Do(fiber.MethodGet, "/api/v1/project/1/items", bob, nil))
+	test.Golden("create_by_other_user", test.Do(fiber.MethodPost, "/api/v1/project/1/items", bob, map[string]interface{}{"name": "Design"}))
+	test.Golden("update_by_other_user", test.Do(fiber.MethodPut, "/api/v1/project/1/items/1", bob, map[string]interface{}{"name": "Sales"}))
+	test.Golden("toggle_by_other_user", test.Do(fiber.MethodPatch, "/api/v1/project/1/items/1/status", bob, nil))
+	test.Golden("reorder_by_other_user", test.Do(fiber.MethodPut, "/api/v1/project/1/items/order", bob, map[string]interface{}{"itemIds": []uint{1, 2, 3}}))
+	test.Golden("delete_by_other_user", test.Do(fiber.MethodDelete, "/api/v1/project/1/items/1", bob, nil))
+
+	// 5. Delete
+	test.Golden("delete", test.Do(fiber.MethodDelete, "/api/v1/project/1/items/1", alice, nil))
+	test.Golden("delete_again", test.Do(fiber.MethodDelete, "/api/v1/project/1/items/1", alice, nil))
+	test.Golden("list_after_delete", test.Do(fiber.MethodGet, "/api/v1/project/1/items", alice, nil))
+}
+
+func TestTokenEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+
+	refreshToken := func(res response) string {
+		t.Helper()
+		var body struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		if err := json.Unmarshal(res.Body, &body); err != nil || body.RefreshToken == "" {
+			t.Fatalf("no refresh token in %d %s", res.Status, res.Body)
+		}
+		return body.RefreshToken
+	}
+
+	login := test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{"email": "alice@example.com", "password": "password123"})
+	first := refreshToken(login)
+
+	// 1. Refresh rotates the token
+	refreshed := test.Do(fiber.MethodPost, "/api/v1/user/token/refresh", "", map[string]string{"refreshToken": first})
+	test.Golden("refresh", refreshed)
+	second := refreshToken(refreshed)
+	test.Golden("refresh_invalid", test.Do(fiber.MethodPost, "/api/v1/user/token/refresh", "", map[string]string{"refreshToken": "unknown"}))
+	test.Golden("refresh_without_token", test.Do(fiber.MethodPost, "/api/v1/user/token/refresh", "", map[string]string{}))
+
+	// 2. Reusing a rotated token revokes the whole login
+	test.Golden("refresh_reused", test.Do(fiber.MethodPost, "/api/v1/user/token/refresh", "", map[string]string{"refreshToken": first}))
+	test.Golden("refresh_after_reuse", test.Do(fiber.MethodPost, "/api/v1/user/token/refresh", "", map[string]string{"refreshToken": second}))
+
+	// 3. Logout revokes the login
+	login = test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{"email": "alice@example.com", "password": "password123"})
+	third := refreshToken(login)
+	test.Golden("logout", test.Do(fiber.MethodPost, "/api/v1/user/logout", "", map[string]string{"refreshToken": third}))
+	test.Golden("refresh_after_logout", test.Do(fiber.MethodPost, "/api/v1/user/token/refresh", "", map[string]string{"refreshToken": third}))
+	test.Golden("logout_invalid", test.Do(fiber.MethodPost, "/api/v1/user/logout", "", map[string]string{"refreshToken": "unknown"}))
+}
+
+func TestPasswordEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+
+	// 1. Forgot answers the same for unknown emails
+	test.Golden("forgot", test.Do(fiber.MethodPost, "/api/v1/user/password/forgot", "", map[string]string{"email": "alice@example.com"}))
+	test.Golden("forgot_unknown_email", test.Do(fiber.MethodPost, "/api/v1/user/password/forgot", "", map[string]string{"email": "nobody@example.com"}))
+	test.Golden("forgot_invalid", test.Do(fiber.MethodPost, "/api/v1/user/password/forgot", "", map[string]string{"email": "alice"}))
+
+	token := test.MailToken("alice@example.com")
+
+	// 2. Reset with the token of the email, once
+	test.Golden("reset_invalid_token", test.Do(fiber.MethodPost, "/api/v1/user/password/reset", "", map[string]string{"token": "unknown", "password": "new password"}))
+	test.Golden("reset_short_password", test.Do(fiber.MethodPost, "/api/v1/user/password/reset", "", map[string]string{"token": token, "password": "short"}))
+	test.Golden("reset", test.Do(fiber.MethodPost, "/api/v1/user/password/reset", "", map[string]string{"token": token, "password": "new password"}))
+	test.Golden("reset_again", test.Do(fiber.MethodPost, "/api/v1/user/password/reset", "", map[string]string{"token": token, "password": "other password"}))
+
+	// 3. Only the new password works
+	test.Golden("login_old_password", test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{"email": "alice@example.com", "password": "password123"}))
+	test.Golden("login_new_password", test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{"email": "alice@example.com", "password": "new password"}))
+}
+
+func TestVerifyEndpoints(t *testing.T) {
+
+	test := newTestApp(t, func(cfg *config.Config) {
+		cfg.Auth.EmailVerificationPolicy = config.EmailVerificationWrite
+	})
+	test.Register("alice")
+	token := test.Login("alice")
+
+	// 1. Unverified accounts cannot write
+	test.Golden("write_unverified", test.Do(fiber.MethodPost, "/api/v1/category/", token, map[string]string{"name": "Design"}))
+
+	// 2. Register sent a link, a new one has to wait
+	test.Golden("resend_too_soon", test.Do(fiber.MethodPost, "/api/v1/user/verify/resend", "", map[string]string{"email": "alice@example.com"}))
+	test.Golden("resend_unknown_email", test.Do(fiber.MethodPost, "/api/v1/user/verify/resend", "", map[string]string{"email": "nobody@example.com"}))
+	test.Golden("resend_invalid", test.Do(fiber.MethodPost, "/api/v1/user/verify/resend", "", map[string]string{"email": "alice"}))
+
+	// 3. Verify with the link of the email
+	test.Golden("verify_invalid_token", test.Do(fiber.MethodGet, "/api/v1/user/verify?token=unknown", "", nil))
+	test.Golden("verify_without_token", test.Do(fiber.MethodGet, "/api/v1/user/verify", "", nil))
+	test.Golden("verify", test.Do(fiber.MethodGet, "/api/v1/user/verify?token="+url.QueryEscape(test.MailToken("alice@example.com")), "", nil))
+	test.Golden("write_verified", test.Do(fiber.MethodPost, "/api/v1/category/", token, map[string]string{"name": "Design"}))
+
+	// 4. Verified accounts get no more links
+	test.Golden("resend_verified", test.Do(fiber.MethodPost, "/api/v1/user/verify/resend", "", map[string]string{"email": "alice@example.com"}))
+}
+
+func TestAdminEndpoints(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("admin")
+	test.Register("alice")
+
+	// The admin email of the configuration gets the admin role on startup,
+	// whatever its case
+	if err := app2.SeedRoles(test.Db, " Admin@Example.com "); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	admin := test.Login("admin")
+	alice := test.Login("alice")
+
+	// 1. Users cannot manage roles
+	test.Golden("roles_without_token", test.Do(fiber.MethodGet, "/api/v1/admin/roles", "", nil))
+	test.Golden("roles_by_user", test.Do(fiber.MethodGet, "/api/v1/admin/roles", alice, nil))
+	test.Golden("assign_by_user", test.Do(fiber.MethodPost, "/api/v1/admin/users/2/roles", alice, map[string]uint{"roleId": 1}))
+
+	// 2. List the roles
+	test.Golden("roles", test.Do(fiber.MethodGet, "/api/v1/admin/roles", admin, nil))
+	test.Golden("user_roles", test.Do(fiber.MethodGet, "/api/v1/admin/users/2/roles", admin, nil))
+	test.Golden("user_roles_invalid_id", test.Do(fiber.MethodGet, "/api/v1/admin/users/abc/roles", admin, nil))
+
+	// 3. Assign the admin role and revoke it
+	test.Golden("assign", test.Do(fiber.MethodPost, "/api/v1/admin/users/2/roles", admin, map[string]uint{"roleId": 1}))
+	test.Golden("assign_invalid", test.Do(fiber.MethodPost, "/api/v1/admin/users/2/roles", admin, map[string]uint{}))
+	test.Golden("assign_missing_role", test.Do(fiber.MethodPost, "/api/v1/admin/users/2/roles", admin, map[string]uint{"roleId": 100}))
+	test.Golden("roles_by_new_admin", test.Do(fiber.MethodGet, "/api/v1/admin/roles", alice, nil))
+	test.Golden("user_roles_after_assign", test.Do(fiber.MethodGet, "/api/v1/admin/users/2/roles", admin, nil))
+	test.Golden("revoke", test.Do(fiber.MethodDelete, "/api/v1/admin/users/2/roles/1", admin, nil))
+	test.Golden("user_roles_after_revoke", test.Do(fiber.MethodGet, "/api/v1/admin/users/2/roles", admin, nil))
+	test.Golden("roles_after_revoke", test.Do(fiber.MethodGet, "/api/v1/admin/roles", alice, nil))
+}
+
+func TestRequestId(t *testing.T) {
+
+	test := newTestApp(t)
+
+	var buffer bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(logger.New(config.LogConfig{Level: "info", Format: "json"}, &buffer))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"from the client", "client-id-1", true},
+		{"missing", "", false},
+		{"invalid", "bad id\nforged line", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer.Reset()
+
+			req := httptest.NewRequest(fiber.MethodGet, "/api/v1/user/verify?token=secret", nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderXRequestID, tt.header)
+			}
+
+			res, err := test.App.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			requestId := res.Header.Get(fiber.HeaderXRequestID)
+			if tt.keep && requestId != tt.header {
+				t.Errorf("X-Request-ID = %q, want %q", requestId, tt.header)
+			}
+			if !tt.keep && (requestId == "" || requestId == tt.header) {
+				t.Errorf("X-Request-ID = %q, want a generated one", requestId)
+			}
+
+			// The access log is the last line
+			lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+			var line map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &line); err != nil {
+				t.Fatalf("decode %q: %v", buffer.String(), err)
+			}
+
+			if line["msg"] != "request" || line["requestId"] != requestId || line["status"] != float64(res.StatusCode) {
+				t.Errorf("access log = %v", line)
+			}
+			if strings.Contains(buffer.String(), "secret") {
+				t.Errorf("token of the query in the log: %s", buffer.String())
+			}
+		})
+	}
+}
+
+func TestLoginRateLimit(t *testing.T) {
+
+	login := func(test *testApp, email string) response {
+		return test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{
+			"email":    email,
+			"password": "wrong password",
+		})
+	}
+
+	t.Run("per account", func(t *testing.T) {
+
+		test := newTestApp(t, func(cfg *config.Config) {
+			cfg.RateLimit.LoginPerIp = 0
+			cfg.RateLimit.LoginPerAccount = 2
+		})
+
+		for i := 0; i < 2; i++ {
+			if res := login(test, "nobody@example.com"); res.Status != fiber.StatusUnauthorized {
+				t.Fatalf("login %d: %d %s", i, res.Status, res.Body)
+			}
+		}
+
+		// The email is the same in any case, a minute refills 2 tokens
+		res := login(test, "Nobody@Example.com")
+		if res.Status != fiber.StatusTooManyRequests || res.Header.Get(fiber.HeaderRetryAfter) != "30" {
+			t.Fatalf("third login: %d, Retry-After %q, %s", res.Status, res.Header.Get(fiber.HeaderRetryAfter), res.Body)
+		}
+
+		if res := login(test, "other@example.com"); res.Status != fiber.StatusUnauthorized {
+			t.Errorf("login of another account: %d %s", res.Status, res.Body)
+		}
+	})
+
+	t.Run("per ip", func(t *testing.T) {
+
+		test := newTestApp(t, func(cfg *config.Config) {
+			cfg.RateLimit.LoginPerIp = 2
+			cfg.RateLimit.LoginPerAccount = 0
+		})
+
+		login(test, "a@example.com")
+		login(test, "b@example.com")
+
+		if res := login(test, "c@example.com"); res.Status != fiber.StatusTooManyRequests || res.Header.Get(fiber.HeaderRetryAfter) == "" {
+			t.Errorf("third login: %d, Retry-After %q", res.Status, res.Header.Get(fiber.HeaderRetryAfter))
+		}
+	})
+
+	// app.Test connects from 0.0.0.0
+	loginFrom := func(test *testApp, clientIp string) int {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/user/login", strings.NewReader(`{"email":"a@example.com","password":"wrong password"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set("X-Real-IP", clientIp)
+
+		res, err := test.App.Test(req, -1)
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		wantThird      int
+	}{
+		{name: "per ip behind a trusted proxy", trustedProxies: []string{"0.0.0.0/8"}, wantThird: fiber.StatusUnauthorized},
+		{name: "per ip behind an untrusted proxy", trustedProxies: []string{"10.0.0.1"}, wantThird: fiber.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			test := newTestApp(t, func(cfg *config.Config) {
+				cfg.App.ProxyHeader = "X-Real-IP"
+				cfg.App.TrustedProxies = tt.trustedProxies
+				cfg.RateLimit.LoginPerIp = 2
+				cfg.RateLimit.LoginPerAccount = 0
+			})
+
+			loginFrom(test, "203.0.113.1")
+			loginFrom(test, "203.0.113.1")
+
+			// The header of an untrusted proxy is ignored, all logins come from 0.0.0.0
+			if status := loginFrom(test, "203.0.113.2"); status != tt.wantThird {
+				t.Errorf("login of another client: %d, want %d", status, tt.wantThird)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+
+	test := newTestApp(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginPerIp = 0
+		cfg.RateLimit.LoginPerAccount = 0
+		cfg.Auth.LockoutThreshold = 2
+		cfg.Auth.LockoutDuration = time.Minute
+		cfg.Auth.LockoutMaxDuration = time.Hour
+	})
+	test.Register("alice")
+
+	login := func(password string) response {
+		return test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{
+			"email":    "alice@example.com",
+			"password": password,
+		})
+	}
+
+	// 1. The second wrong password locks the account
+	for i := 0; i < 2; i++ {
+		if res := login("wrong password"); res.Status != fiber.StatusUnauthorized {
+			t.Fatalf("wrong password %d: %d %s", i, res.Status, res.Body)
+		}
+	}
+
+	// 2. Even the right password waits for the lock
+	res := login("password123")
+	if res.Status != fiber.StatusTooManyRequests {
+		t.Fatalf("login of a locked account: %d %s", res.Status, res.Body)
+	}
+	if retryAfter := res.Header.Get(fiber.HeaderRetryAfter); retryAfter != "60" && retryAfter != "59" {
+		t.Errorf("Retry-After = %q, want 60", retryAfter)
+	}
+
+	// 3. Once the lock is over the right password clears the failures
+	if err := test.Db.Exec("UPDATE users SET locked_until = ?", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("end the lock: %v", err)
+	}
+
+	if res := login("password123"); res.Status != fiber.StatusOK {
+		t.Fatalf("login after the lock: %d %s", res.Status, res.Body)
+	}
+
+	var failedLogins int
+	if err := test.Db.Raw("SELECT failed_logins FROM users").Scan(&failedLogins).Error; err != nil {
+		t.Fatalf("read failed logins: %v", err)
+	}
+	if failedLogins != 0 {
+		t.Errorf("failed logins = %d, want 0", failedLogins)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+
+	test := newTestApp(t)
+	SetupMetricsRoutes(test.App)
+
+	// The counters are global, only their increase is checked
+	counter := func(collector prometheus.Collector) func() float64 {
+		before := testutil.ToFloat64(collector)
+		return func() float64 { return testutil.ToFloat64(collector) - before }
+	}
+	requests := func(method string, route string, status string) func() float64 {
+		return counter(metrics.HttpRequests.WithLabelValues(method, route, status))
+	}
+
+	registered := counter(metrics.UsersRegistered)
+	logins := counter(metrics.Logins)
+	failedLogins := counter(metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials))
+	projectsCreated := counter(metrics.ProjectsCreated)
+	projectsMissing := requests(fiber.MethodGet, "/api/v1/project/:id", "404")
+	unauthorized := requests(fiber.MethodGet, "/api/v1/category", "401")
+	unmatched := requests(fiber.MethodGet, "unmatched", "404")
+
+	// 1. Business counters
+	test.Register("alice")
+	token := test.Login("alice")
+	test.Do(fiber.MethodPost, "/api/v1/user/login", "", map[string]string{
+		"email":    "alice@example.com",
+		"password": "wrong password",
+	})
+
+	res := test.Do(fiber.MethodPost, "/api/v1/category/", token, map[string]string{"name": "Work"})
+	if res.Status != fiber.StatusOK {
+		t.Fatalf("create category: %d %s", res.Status, res.Body)
+	}
+	res = test.Do(fiber.MethodPost, "/api/v1/project/", token, map[string]interface{}{
+		"categoryId": 1,
+		"name":       "Website",
+	})
+	if res.Status != fiber.StatusOK {
+		t.Fatalf("create project: %d %s", res.Status, res.Body)
+	}
+
+	for name, got := range map[string]float64{
+		"registered":       registered(),
+		"logins":           logins(),
+		"failed logins":    failedLogins(),
+		"projects created": projectsCreated(),
+	} {
+		if got != 1 {
+			t.Errorf("%s increased by %v, want 1", name, got)
+		}
+	}
+
+	// 2. Requests are labelled with the route template
+	test.Do(fiber.MethodGet, "/api/v1/project/100", token, nil)
+	test.Do(fiber.MethodGet, "/api/v1/project/200", token, nil)
+	test.Do(fiber.MethodGet, "/api/v1/category/", "", nil)
+	test.Do(fiber.MethodGet, "/missing/1", "", nil)
+	test.Do(fiber.MethodGet, "/missing/2", "", nil)
+
+	if got := projectsMissing(); got != 2 {
+		t.Errorf("project not found counted %v times, want 2", got)
+	}
+	if got := unauthorized(); got != 1 {
+		t.Errorf("refused by the middleware of the group counted %v times, want 1", got)
+	}
+	if got := unmatched(); got != 2 {
+		t.Errorf("unmatched counted %v times, want 2", got)
+	}
+
+	// 3. The endpoint serves the registry
+	res = test.Do(fiber.MethodGet, "/metrics", "", nil)
+	if res.Status != fiber.StatusOK {
+		t.Fatalf("metrics: %d %s", res.Status, res.Body)
+	}
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/project/:id",status="404"}`,
+		`users_registered_total`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(res.Body), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+
+	test := newTestApp(t)
+	test.Register("alice")
+	token := test.Login("alice")
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	// The caller sent its trace
+	traceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentId := "00f067aa0ba902b7"
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/category/?page=1", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	req.Header.Set("traceparent", "00-"+traceId+"-"+parentId+"-01")
+
+	res, err := test.App.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d", res.StatusCode)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	server, repository, query := spans["GET /api/v1/category/"], spans["CategoryRepository.FindAll"], spans["query categories"]
+	if server == nil || repository == nil || query == nil {
+		names := make([]string, 0, len(spans))
+		for name := range spans {
+			names = append(names, name)
+		}
+		t.Fatalf("missing spans, got %q", names)
+	}
+
+	// The request continues the trace of the caller, the query is nested in
+	// the repository call
+	if server.SpanContext().TraceID().String() != traceId || server.Parent().SpanID().String() != parentId {
+		t.Errorf("server span is not a child of the traceparent: %v", server.Parent())
+	}
+	if repository.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("repository span is not a child of the server span")
+	}
+	if query.Parent().SpanID() != repository.SpanContext().SpanID() {
+		t.Errorf("query span is not a child of the repository span")
+	}
+}
@@ -4,5 +4,6 @@ import "gorm.io/gorm"
 
 type Category struct {
 	*gorm.Model
-	Name string
+	UserID uint `gorm:"index"`
+	Name   string
 }
@@ -0,0 +1,15 @@
+package schema
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordReset struct {
+	*gorm.Model
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
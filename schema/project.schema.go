@@ -6,6 +6,7 @@ import (
 
 type Project struct {
 	*gorm.Model
+	UserID       uint `gorm:"index"`
 	CategoryID   uint
 	Category     Category `gorm:"foreignKey:CategoryID"`
 	Name         string   `gorm:"type:varchar(100)"`
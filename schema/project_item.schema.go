@@ -9,4 +9,5 @@ type ProjectItem struct {
 	Name       string
 	BudgetItem int
 	Status     bool
+	Position   int
 }
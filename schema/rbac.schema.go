@@ -0,0 +1,26 @@
+package schema
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Role struct {
+	*gorm.Model
+	Name        string       `gorm:"type:varchar(50);uniqueIndex"`
+	Description string       `gorm:"type:varchar(255)"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+}
+
+type Permission struct {
+	*gorm.Model
+	Name        string `gorm:"type:varchar(100);uniqueIndex"`
+	Description string `gorm:"type:varchar(255)"`
+}
+
+type UserRole struct {
+	UserID    uint `gorm:"primaryKey;autoIncrement:false"`
+	RoleID    uint `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time
+}
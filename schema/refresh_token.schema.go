@@ -0,0 +1,17 @@
+package schema
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshToken struct {
+	*gorm.Model
+	UserID    uint   `gorm:"index"`
+	FamilyID  string `gorm:"type:varchar(36);index"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
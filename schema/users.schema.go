@@ -1,12 +1,20 @@
 package schema
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Users struct {
 	*gorm.Model
-	Username string
-	Email    string
-	Password string
+	Username           string
+	Email              string
+	Password           string
+	EmailVerifiedAt    *time.Time
+	VerificationSentAt *time.Time
+	FailedLogins       int `gorm:"not null;default:0"`
+	LockedUntil        *time.Time
 }
 
 type UserProfile struct {
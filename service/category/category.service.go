@@ -0,0 +1,92 @@
+package category
+
+import (
+	"context"
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	categoryRepository "project-app/repository/category"
+	"project-app/transaction"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type CategoryService interface {
+	Create(ctx context.Context, userId uint, request model.CategoryCreateRequest) (*model.Category, error)
+	Update(ctx context.Context, userId uint, id int, request model.CategoryUpdateRequest) error
+	Delete(ctx context.Context, userId uint, id int) error
+	FindAll(ctx context.Context, userId uint, params pagination.Params, categoryName string) ([]model.Category, pagination.Page, error)
+}
+
+type CategoryServiceImpl struct {
+	CategoryRepository categoryRepository.CategoryRepository
+	Transaction        transaction.Manager
+	Validate           *validator.Validate
+}
+
+func NewCategoryService(db *gorm.DB, validate *validator.Validate) CategoryService {
+	return &CategoryServiceImpl{
+		CategoryRepository: categoryRepository.NewCategoryRepository(db),
+		Transaction:        transaction.NewManager(db),
+		Validate:           validate,
+	}
+}
+
+func (service *CategoryServiceImpl) Create(ctx context.Context, userId uint, request model.CategoryCreateRequest) (*model.Category, error) {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return nil, apperror.FromValidator(errValidate)
+	}
+
+	category := model.Category{
+		UserID: userId,
+		Name:   request.Name,
+	}
+
+	err := service.CategoryRepository.Create(ctx, &category)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (service *CategoryServiceImpl) Update(ctx context.Context, userId uint, id int, request model.CategoryUpdateRequest) error {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		// Make sure the category belongs to the user
+		_, errCategory := service.CategoryRepository.FindById(ctx, userId, id)
+		if errCategory != nil {
+			return errCategory
+		}
+
+		return service.CategoryRepository.Update(ctx, userId, id, &model.Category{
+			Name: request.Name,
+		})
+	})
+}
+
+func (service *CategoryServiceImpl) Delete(ctx context.Context, userId uint, id int) error {
+
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		_, errCategory := service.CategoryRepository.FindById(ctx, userId, id)
+		if errCategory != nil {
+			return errCategory
+		}
+
+		return service.CategoryRepository.Delete(ctx, userId, id)
+	})
+}
+
+func (service *CategoryServiceImpl) FindAll(ctx context.Context, userId uint, params pagination.Params, categoryName string) ([]model.Category, pagination.Page, error) {
+	return service.CategoryRepository.FindAll(ctx, userId, params, categoryName)
+}
@@ -0,0 +1,121 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/repository/fake"
+	"project-app/validation"
+)
+
+func newTestService() (*CategoryServiceImpl, *fake.CategoryRepository) {
+
+	db := fake.NewDatabase()
+	categories := fake.NewCategoryRepository(db)
+
+	return &CategoryServiceImpl{
+		CategoryRepository: categories,
+		Transaction:        fake.NewTransactionManager(db),
+		Validate:           validation.Validator(),
+	}, categories
+}
+
+func TestCategoryServiceCreate(t *testing.T) {
+
+	service, categories := newTestService()
+	ctx := context.Background()
+
+	category, err := service.Create(ctx, 1, model.CategoryCreateRequest{Name: "Design"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if category.ID != 1 || category.UserID != 1 || category.Name != "Design" {
+		t.Errorf("category = %+v", category)
+	}
+
+	// An invalid request does not reach the repository
+	_, err = service.Create(ctx, 1, model.CategoryCreateRequest{})
+	if !apperror.Is(err, apperror.KindValidation) {
+		t.Errorf("Create without name: err = %v, want validation error", err)
+	}
+	if calls := categories.Calls("Create"); calls != 1 {
+		t.Errorf("repository Create calls = %d, want 1", calls)
+	}
+}
+
+func TestCategoryServiceUpdateAndDelete(t *testing.T) {
+
+	errDown := errors.New("database is down")
+
+	tests := []struct {
+		name     string
+		userId   uint
+		id       int
+		fail     string
+		wantKind apperror.Kind
+		wantErr  error
+	}{
+		{name: "own category", userId: 1, id: 1},
+		{name: "category of another user", userId: 2, id: 1, wantKind: apperror.KindNotFound},
+		{name: "missing category", userId: 1, id: 100, wantKind: apperror.KindNotFound},
+		{name: "repository error", userId: 1, id: 1, fail: "FindById", wantErr: errDown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, categories := newTestService()
+			ctx := context.Background()
+
+			if _, err := service.Create(ctx, 1, model.CategoryCreateRequest{Name: "Design"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			if test.fail != "" {
+				categories.Fail(test.fail, errDown)
+			}
+
+			errUpdate := service.Update(ctx, test.userId, test.id, model.CategoryUpdateRequest{Name: "Marketing"})
+			errDelete := service.Delete(ctx, test.userId, test.id)
+
+			for _, err := range []error{errUpdate, errDelete} {
+				switch {
+				case test.wantErr != nil:
+					if !errors.Is(err, test.wantErr) {
+						t.Errorf("err = %v, want %v", err, test.wantErr)
+					}
+				case test.wantKind != "":
+					if !apperror.Is(err, test.wantKind) {
+						t.Errorf("err = %v, want kind %s", err, test.wantKind)
+					}
+				case err != nil:
+					t.Errorf("err = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCategoryServiceDeleteFails(t *testing.T) {
+
+	service, categories := newTestService()
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, 1, model.CategoryCreateRequest{Name: "Design"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	errDown := errors.New("database is down")
+	categories.FailNext("Delete", errDown)
+
+	if err := service.Delete(ctx, 1, 1); !errors.Is(err, errDown) {
+		t.Fatalf("Delete: err = %v, want %v", err, errDown)
+	}
+
+	if _, err := categories.FindById(ctx, 1, 1); err != nil {
+		t.Errorf("category is gone after the failed delete: %v", err)
+	}
+}
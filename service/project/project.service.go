@@ -0,0 +1,289 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"project-app/apperror"
+	"project-app/metrics"
+	"project-app/model"
+	"project-app/pagination"
+	categoryRepository "project-app/repository/category"
+	projectRepository "project-app/repository/project"
+	projectItemRepository "project-app/repository/projectitem"
+	"project-app/transaction"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// ProjectService manages the projects of a user and their items. Every
+// method only sees the projects of userId.
+type ProjectService interface {
+	Create(ctx context.Context, userId uint, request model.ProjectCreateRequest) (*model.Project, error)
+	Update(ctx context.Context, userId uint, id int, request model.ProjectUpdateRequest) error
+	Delete(ctx context.Context, userId uint, id int) error
+	FindById(ctx context.Context, userId uint, id int) (*model.Project, error)
+	FindAll(ctx context.Context, userId uint, params pagination.Params, categoryId int, projectName string) ([]model.Project, pagination.Page, error)
+
+	CreateItem(ctx context.Context, userId uint, projectId uint, request model.ProjectItemCreateRequest) (*model.ProjectItem, error)
+	UpdateItem(ctx context.Context, userId uint, projectId uint, itemId uint, request model.ProjectItemUpdateRequest) error
+	ToggleItemStatus(ctx context.Context, userId uint, projectId uint, itemId uint) (*model.ProjectItem, error)
+	ReorderItems(ctx context.Context, userId uint, projectId uint, request model.ProjectItemReorderRequest) error
+	DeleteItem(ctx context.Context, userId uint, projectId uint, itemId uint) error
+	FindItems(ctx context.Context, userId uint, projectId uint, params pagination.Params) ([]model.ProjectItem, pagination.Page, error)
+}
+
+type ProjectServiceImpl struct {
+	ProjectRepository     projectRepository.ProjectRepository
+	ProjectItemRepository projectItemRepository.ProjectItemRepository
+	CategoryRepository    categoryRepository.CategoryRepository
+	Transaction           transaction.Manager
+	Validate              *validator.Validate
+}
+
+func NewProjectService(db *gorm.DB, validate *validator.Validate) ProjectService {
+	return &ProjectServiceImpl{
+		ProjectRepository:     projectRepository.NewProjectRepository(db),
+		ProjectItemRepository: projectItemRepository.NewProjectItemRepository(db),
+		CategoryRepository:    categoryRepository.NewCategoryRepository(db),
+		Transaction:           transaction.NewManager(db),
+		Validate:              validate,
+	}
+}
+
+func (service *ProjectServiceImpl) Create(ctx context.Context, userId uint, request model.ProjectCreateRequest) (*model.Project, error) {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return nil, apperror.FromValidator(errValidate)
+	}
+
+	project := model.Project{
+		UserID:      userId,
+		CategoryID:  request.CategoryID,
+		Name:        request.Name,
+		Description: request.Description,
+		Budget:      request.Budget,
+	}
+
+	err := service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		// Make sure the category exists and belongs to the user
+		errCategory := service.checkCategory(ctx, userId, request.CategoryID)
+		if errCategory != nil {
+			return errCategory
+		}
+
+		return service.ProjectRepository.Create(ctx, &project)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ProjectsCreated.Inc()
+
+	return &project, nil
+}
+
+func (service *ProjectServiceImpl) Update(ctx context.Context, userId uint, id int, request model.ProjectUpdateRequest) error {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		// Make sure the project and the category exist and belong to the user
+		_, errProject := service.ProjectRepository.FindById(ctx, userId, id)
+		if errProject != nil {
+			return errProject
+		}
+
+		errCategory := service.checkCategory(ctx, userId, request.CategoryID)
+		if errCategory != nil {
+			return errCategory
+		}
+
+		return service.ProjectRepository.Update(ctx, userId, id, &model.Project{
+			CategoryID:  request.CategoryID,
+			Name:        request.Name,
+			Description: request.Description,
+			Budget:      request.Budget,
+		})
+	})
+}
+
+func (service *ProjectServiceImpl) Delete(ctx context.Context, userId uint, id int) error {
+
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		_, errProject := service.ProjectRepository.FindById(ctx, userId, id)
+		if errProject != nil {
+			return errProject
+		}
+
+		return service.ProjectRepository.Delete(ctx, userId, id)
+	})
+}
+
+func (service *ProjectServiceImpl) FindById(ctx context.Context, userId uint, id int) (*model.Project, error) {
+	return service.ProjectRepository.FindById(ctx, userId, id)
+}
+
+func (service *ProjectServiceImpl) FindAll(ctx context.Context, userId uint, params pagination.Params, categoryId int, projectName string) ([]model.Project, pagination.Page, error) {
+	return service.ProjectRepository.FindAll(ctx, userId, params, categoryId, projectName)
+}
+
+// CreateItem adds an item to the end of the project.
+func (service *ProjectServiceImpl) CreateItem(ctx context.Context, userId uint, projectId uint, request model.ProjectItemCreateRequest) (*model.ProjectItem, error) {
+
+	errProject := service.checkProject(ctx, userId, projectId)
+	if errProject != nil {
+		return nil, errProject
+	}
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return nil, apperror.FromValidator(errValidate)
+	}
+
+	item := model.ProjectItem{
+		ProjectID:  projectId,
+		Name:       request.Name,
+		BudgetItem: request.BudgetItem,
+		Status:     request.Status,
+	}
+
+	err := service.ProjectItemRepository.Create(ctx, userId, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (service *ProjectServiceImpl) UpdateItem(ctx context.Context, userId uint, projectId uint, itemId uint, request model.ProjectItemUpdateRequest) error {
+
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		errItem := service.checkItem(ctx, userId, projectId, itemId)
+		if errItem != nil {
+			return errItem
+		}
+
+		errValidate := service.Validate.Struct(request)
+		if errValidate != nil {
+			return apperror.FromValidator(errValidate)
+		}
+
+		return service.ProjectItemRepository.Update(ctx, userId, projectId, itemId, &model.ProjectItem{
+			Name:       request.Name,
+			BudgetItem: request.BudgetItem,
+			Status:     request.Status,
+		})
+	})
+}
+
+// ToggleItemStatus marks the item as done, or as not done when it already
+// is, and returns the updated item.
+func (service *ProjectServiceImpl) ToggleItemStatus(ctx context.Context, userId uint, projectId uint, itemId uint) (*model.ProjectItem, error) {
+
+	var item *model.ProjectItem
+	err := service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		errItem := service.checkItem(ctx, userId, projectId, itemId)
+		if errItem != nil {
+			return errItem
+		}
+
+		err := service.ProjectItemRepository.ToggleStatus(ctx, userId, projectId, itemId)
+		if err != nil {
+			return err
+		}
+
+		item, err = service.ProjectItemRepository.FindById(ctx, userId, projectId, itemId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// ReorderItems moves the items to the order of the ids, which must contain
+// every item of the project exactly once.
+func (service *ProjectServiceImpl) ReorderItems(ctx context.Context, userId uint, projectId uint, request model.ProjectItemReorderRequest) error {
+
+	errProject := service.checkProject(ctx, userId, projectId)
+	if errProject != nil {
+		return errProject
+	}
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	err := service.ProjectItemRepository.Reorder(ctx, userId, projectId, request.ItemIds)
+	if errors.Is(err, projectItemRepository.ErrReorderMismatch) {
+		return apperror.Validation("Item ids must contain every item of the project exactly once").Wrap(err)
+	}
+
+	return err
+}
+
+func (service *ProjectServiceImpl) DeleteItem(ctx context.Context, userId uint, projectId uint, itemId uint) error {
+
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		errItem := service.checkItem(ctx, userId, projectId, itemId)
+		if errItem != nil {
+			return errItem
+		}
+
+		return service.ProjectItemRepository.Delete(ctx, userId, projectId, itemId)
+	})
+}
+
+// FindItems returns a page of the items of the project ordered by position.
+func (service *ProjectServiceImpl) FindItems(ctx context.Context, userId uint, projectId uint, params pagination.Params) ([]model.ProjectItem, pagination.Page, error) {
+
+	errProject := service.checkProject(ctx, userId, projectId)
+	if errProject != nil {
+		return nil, pagination.Page{}, errProject
+	}
+
+	return service.ProjectItemRepository.FindByProjectId(ctx, userId, projectId, params)
+}
+
+// checkCategory reports a missing category as an invalid request, the
+// category comes from the body and not from the path.
+func (service *ProjectServiceImpl) checkCategory(ctx context.Context, userId uint, categoryId uint) error {
+
+	_, err := service.CategoryRepository.FindById(ctx, userId, int(categoryId))
+	if apperror.Is(err, apperror.KindNotFound) {
+		return apperror.Validation("Category not found").Wrap(err)
+	}
+
+	return err
+}
+
+// checkProject makes sure the project exists and belongs to the user.
+func (service *ProjectServiceImpl) checkProject(ctx context.Context, userId uint, projectId uint) error {
+	_, err := service.ProjectRepository.FindById(ctx, userId, int(projectId))
+	return err
+}
+
+// checkItem is checkProject for a single item of the project.
+func (service *ProjectServiceImpl) checkItem(ctx context.Context, userId uint, projectId uint, itemId uint) error {
+
+	errProject := service.checkProject(ctx, userId, projectId)
+	if errProject != nil {
+		return errProject
+	}
+
+	_, err := service.ProjectItemRepository.FindById(ctx, userId, projectId, itemId)
+	return err
+}
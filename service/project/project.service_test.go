@@ -0,0 +1,301 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"project-app/apperror"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/repository/fake"
+	"project-app/validation"
+)
+
+type testRepositories struct {
+	categories *fake.CategoryRepository
+	projects   *fake.ProjectRepository
+	items      *fake.ProjectItemRepository
+}
+
+// newTestService returns a service where user 1 has the category 1 and user
+// 2 the category 2.
+func newTestService(t *testing.T) (*ProjectServiceImpl, *testRepositories) {
+
+	t.Helper()
+
+	db := fake.NewDatabase()
+	repositories := &testRepositories{
+		categories: fake.NewCategoryRepository(db),
+		projects:   fake.NewProjectRepository(db),
+		items:      fake.NewProjectItemRepository(db),
+	}
+
+	for _, category := range []*model.Category{{UserID: 1, Name: "Design"}, {UserID: 2, Name: "Marketing"}} {
+		if err := repositories.categories.Create(context.Background(), category); err != nil {
+			t.Fatalf("create category: %v", err)
+		}
+	}
+
+	return &ProjectServiceImpl{
+		ProjectRepository:     repositories.projects,
+		ProjectItemRepository: repositories.items,
+		CategoryRepository:    repositories.categories,
+		Transaction:           fake.NewTransactionManager(db),
+		Validate:              validation.Validator(),
+	}, repositories
+}
+
+func TestProjectServiceCreate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		request  model.ProjectCreateRequest
+		wantKind apperror.Kind
+	}{
+		{name: "own category", request: model.ProjectCreateRequest{CategoryID: 1, Name: "Website", Budget: 1000}},
+		{name: "category of another user", request: model.ProjectCreateRequest{CategoryID: 2, Name: "Website"}, wantKind: apperror.KindValidation},
+		{name: "missing category", request: model.ProjectCreateRequest{CategoryID: 100, Name: "Website"}, wantKind: apperror.KindValidation},
+		{name: "invalid request", request: model.ProjectCreateRequest{CategoryID: 1, Budget: -1}, wantKind: apperror.KindValidation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService(t)
+
+			project, err := service.Create(context.Background(), 1, test.request)
+			if test.wantKind != "" {
+				if !apperror.Is(err, test.wantKind) {
+					t.Errorf("err = %v, want kind %s", err, test.wantKind)
+				}
+				if calls := repositories.projects.Calls("Create"); calls != 0 {
+					t.Errorf("repository Create calls = %d, want 0", calls)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if project.ID != 1 || project.UserID != 1 || project.CategoryID != 1 || project.Budget != 1000 {
+				t.Errorf("project = %+v", project)
+			}
+		})
+	}
+}
+
+func TestProjectServiceUpdateAndDelete(t *testing.T) {
+
+	errDown := errors.New("database is down")
+
+	tests := []struct {
+		name       string
+		userId     uint
+		id         int
+		categoryId uint
+		fail       string
+		wantKind   apperror.Kind
+		wantErr    error
+	}{
+		{name: "own project", userId: 1, id: 1, categoryId: 1},
+		{name: "project of another user", userId: 2, id: 1, categoryId: 2, wantKind: apperror.KindNotFound},
+		{name: "missing project", userId: 1, id: 100, categoryId: 1, wantKind: apperror.KindNotFound},
+		{name: "repository error", userId: 1, id: 1, categoryId: 1, fail: "FindById", wantErr: errDown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService(t)
+			ctx := context.Background()
+
+			if _, err := service.Create(ctx, 1, model.ProjectCreateRequest{CategoryID: 1, Name: "Website"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			if test.fail != "" {
+				repositories.projects.Fail(test.fail, errDown)
+			}
+
+			errUpdate := service.Update(ctx, test.userId, test.id, model.ProjectUpdateRequest{CategoryID: test.categoryId, Name: "Shop"})
+			errDelete := service.Delete(ctx, test.userId, test.id)
+
+			for _, err := range []error{errUpdate, errDelete} {
+				switch {
+				case test.wantErr != nil:
+					if !errors.Is(err, test.wantErr) {
+						t.Errorf("err = %v, want %v", err, test.wantErr)
+					}
+				case test.wantKind != "":
+					if !apperror.Is(err, test.wantKind) {
+						t.Errorf("err = %v, want kind %s", err, test.wantKind)
+					}
+				case err != nil:
+					t.Errorf("err = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectServiceUpdateCategory(t *testing.T) {
+
+	service, repositories := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, 1, model.ProjectCreateRequest{CategoryID: 1, Name: "Website"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// The category of another user is refused and the project is unchanged
+	err := service.Update(ctx, 1, 1, model.ProjectUpdateRequest{CategoryID: 2, Name: "Shop"})
+	if !apperror.Is(err, apperror.KindValidation) {
+		t.Fatalf("Update: err = %v, want kind %s", err, apperror.KindValidation)
+	}
+	if calls := repositories.projects.Calls("Update"); calls != 0 {
+		t.Errorf("repository Update calls = %d, want 0", calls)
+	}
+
+	project, err := service.FindById(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if project.Name != "Website" || project.CategoryID != 1 {
+		t.Errorf("project = %+v, want it unchanged", project)
+	}
+}
+
+func TestProjectServiceItems(t *testing.T) {
+
+	service, _ := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, 1, model.ProjectCreateRequest{CategoryID: 1, Name: "Website"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 1. Items are added at the end
+	for _, name := range []string{"Design", "Build", "Launch"} {
+		if _, err := service.CreateItem(ctx, 1, 1, model.ProjectItemCreateRequest{Name: name}); err != nil {
+			t.Fatalf("CreateItem %s: %v", name, err)
+		}
+	}
+
+	names := func() []string {
+		t.Helper()
+		items, _, err := service.FindItems(ctx, 1, 1, pagination.Offset(1, 10))
+		if err != nil {
+			t.Fatalf("FindItems: %v", err)
+		}
+		result := []string{}
+		for _, item := range items {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	if got := names(); !reflect.DeepEqual(got, []string{"Design", "Build", "Launch"}) {
+		t.Errorf("items = %v", got)
+	}
+
+	// 2. Toggle returns the updated item
+	item, err := service.ToggleItemStatus(ctx, 1, 1, 2)
+	if err != nil {
+		t.Fatalf("ToggleItemStatus: %v", err)
+	}
+	if !item.Status || item.Name != "Build" {
+		t.Errorf("toggled item = %+v, want Build done", item)
+	}
+
+	// 3. Reorder needs every item exactly once
+	err = service.ReorderItems(ctx, 1, 1, model.ProjectItemReorderRequest{ItemIds: []uint{3, 1}})
+	if !apperror.Is(err, apperror.KindValidation) {
+		t.Errorf("ReorderItems with a missing item: err = %v, want kind %s", err, apperror.KindValidation)
+	}
+	if err := service.ReorderItems(ctx, 1, 1, model.ProjectItemReorderRequest{ItemIds: []uint{3, 1, 2}}); err != nil {
+		t.Fatalf("ReorderItems: %v", err)
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"Launch", "Design", "Build"}) {
+		t.Errorf("items after reorder = %v", got)
+	}
+
+	// 4. Delete
+	if err := service.DeleteItem(ctx, 1, 1, 1); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"Launch", "Build"}) {
+		t.Errorf("items after delete = %v", got)
+	}
+}
+
+func TestProjectServiceItemsOfAnotherUser(t *testing.T) {
+
+	service, repositories := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, 1, model.ProjectCreateRequest{CategoryID: 1, Name: "Website"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := service.CreateItem(ctx, 1, 1, model.ProjectItemCreateRequest{Name: "Design"}); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	// User 2 cannot see nor change the items of the project of user 1
+	_, errCreate := service.CreateItem(ctx, 2, 1, model.ProjectItemCreateRequest{Name: "Build"})
+	errUpdate := service.UpdateItem(ctx, 2, 1, 1, model.ProjectItemUpdateRequest{Name: "Build"})
+	_, errToggle := service.ToggleItemStatus(ctx, 2, 1, 1)
+	errReorder := service.ReorderItems(ctx, 2, 1, model.ProjectItemReorderRequest{ItemIds: []uint{1}})
+	errDelete := service.DeleteItem(ctx, 2, 1, 1)
+	_, _, errFind := service.FindItems(ctx, 2, 1, pagination.Offset(1, 10))
+
+	for name, err := range map[string]error{
+		"CreateItem":       errCreate,
+		"UpdateItem":       errUpdate,
+		"ToggleItemStatus": errToggle,
+		"ReorderItems":     errReorder,
+		"DeleteItem":       errDelete,
+		"FindItems":        errFind,
+	} {
+		if !apperror.Is(err, apperror.KindNotFound) {
+			t.Errorf("%s: err = %v, want kind %s", name, err, apperror.KindNotFound)
+		}
+	}
+
+	item, err := repositories.items.FindById(ctx, 1, 1, 1)
+	if err != nil || item.Name != "Design" || item.Status {
+		t.Errorf("item = %+v, %v, want it unchanged", item, err)
+	}
+}
+
+func TestProjectServiceToggleItemStatusRollsBack(t *testing.T) {
+
+	service, repositories := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, 1, model.ProjectCreateRequest{CategoryID: 1, Name: "Website"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := service.CreateItem(ctx, 1, 1, model.ProjectItemCreateRequest{Name: "Design"}); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	// The item is read once to check it and once after the toggle, the
+	// second read fails
+	errDown := errors.New("database is down")
+	repositories.items.FailNext("FindById", nil)
+	repositories.items.FailNext("FindById", errDown)
+
+	if _, err := service.ToggleItemStatus(ctx, 1, 1, 1); !errors.Is(err, errDown) {
+		t.Fatalf("ToggleItemStatus: err = %v, want %v", err, errDown)
+	}
+
+	item, err := repositories.items.FindById(ctx, 1, 1, 1)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if item.Status {
+		t.Errorf("item is done after the failed toggle, want it rolled back")
+	}
+}
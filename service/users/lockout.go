@@ -0,0 +1,131 @@
+package users
+
+import (
+	"project-app/config"
+	"project-app/helper"
+	"sync"
+	"time"
+)
+
+// maxUnknownAccounts bounds the memory of unknownAccounts, a client can try
+// any number of emails.
+const maxUnknownAccounts = 10000
+
+var dummyHash struct {
+	once sync.Once
+	hash string
+}
+
+// dummyPasswordHash is compared with the password of a login to an unknown
+// email, so it takes as long as a wrong password. It is made on first use with
+// helper.PasswordHashCost, which tests lower before.
+func dummyPasswordHash() string {
+
+	dummyHash.once.Do(func() {
+		dummyHash.hash, _ = helper.HashPassword("not the password of any account")
+	})
+
+	return dummyHash.hash
+}
+
+// lockDuration is how long an account is locked after failedLogins wrong
+// passwords in a row, 0 below the threshold. The lock doubles with every
+// wrong password after the threshold, up to the max duration.
+func lockDuration(cfg config.AuthConfig, failedLogins int) time.Duration {
+
+	if cfg.LockoutThreshold <= 0 || failedLogins < cfg.LockoutThreshold {
+		return 0
+	}
+
+	duration := cfg.LockoutDuration
+	for i := cfg.LockoutThreshold; i < failedLogins && duration < cfg.LockoutMaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > cfg.LockoutMaxDuration {
+		duration = cfg.LockoutMaxDuration
+	}
+
+	return duration
+}
+
+type unknownAccount struct {
+	failedLogins int
+	lockedUntil  time.Time
+	lastFailure  time.Time
+}
+
+// unknownAccounts counts the wrong logins of emails without an account and
+// locks them like real accounts, so a 429 does not tell that an account
+// exists. The counts are kept in the memory of the instance.
+type unknownAccounts struct {
+	mu       sync.Mutex
+	accounts map[string]*unknownAccount
+}
+
+func newUnknownAccounts() *unknownAccounts {
+	return &unknownAccounts{
+		accounts: map[string]*unknownAccount{},
+	}
+}
+
+// lockedFor returns how long the email is still locked.
+func (accounts *unknownAccounts) lockedFor(email string) time.Duration {
+
+	accounts.mu.Lock()
+	defer accounts.mu.Unlock()
+
+	account, ok := accounts.accounts[email]
+	if !ok {
+		return 0
+	}
+
+	if wait := time.Until(account.lockedUntil); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// recordFailure counts a wrong login of the email and locks it once the count
+// reaches the threshold.
+func (accounts *unknownAccounts) recordFailure(email string, cfg config.AuthConfig) {
+
+	if cfg.LockoutThreshold <= 0 {
+		return
+	}
+
+	accounts.mu.Lock()
+	defer accounts.mu.Unlock()
+
+	now := time.Now()
+
+	account, ok := accounts.accounts[email]
+	if !ok {
+		if len(accounts.accounts) >= maxUnknownAccounts {
+			accounts.sweep(now, cfg)
+		}
+		if len(accounts.accounts) >= maxUnknownAccounts {
+			return
+		}
+
+		account = &unknownAccount{}
+		accounts.accounts[email] = account
+	}
+
+	account.failedLogins++
+	account.lastFailure = now
+	if duration := lockDuration(cfg, account.failedLogins); duration > 0 {
+		account.lockedUntil = now.Add(duration)
+	}
+}
+
+// sweep drops the emails that are not locked and had no wrong login for the
+// max lock duration.
+func (accounts *unknownAccounts) sweep(now time.Time, cfg config.AuthConfig) {
+
+	for email, account := range accounts.accounts {
+		if now.After(account.lockedUntil) && now.Sub(account.lastFailure) > cfg.LockoutMaxDuration {
+			delete(accounts.accounts, email)
+		}
+	}
+}
@@ -0,0 +1,665 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"project-app/apperror"
+	"project-app/config"
+	"project-app/helper"
+	"project-app/logger"
+	"project-app/mailer"
+	"project-app/metrics"
+	"project-app/model"
+	"project-app/pagination"
+	passwordResetRepository "project-app/repository/passwordreset"
+	rbacRepository "project-app/repository/rbac"
+	refreshTokenRepository "project-app/repository/refreshtoken"
+	userRepository "project-app/repository/users"
+	"project-app/tracing"
+	"project-app/transaction"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UsersService interface {
+	Register(ctx context.Context, request model.RegisterRequest) (*model.User, error)
+	Login(ctx context.Context, request model.LoginRequest) (*model.User, *Tokens, error)
+	RefreshToken(ctx context.Context, request model.RefreshTokenRequest) (*Tokens, error)
+	Logout(ctx context.Context, request model.LogoutRequest) error
+	ForgotPassword(ctx context.Context, request model.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, request model.ResetPasswordRequest) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, request model.ResendVerificationRequest) error
+	UpdateProfile(ctx context.Context, userId uint, request model.ProfileUpdateRequestBody) error
+	FindProfile(ctx context.Context, userId uint) (*model.Profile, error)
+	FindFollowers(ctx context.Context, userId uint, params pagination.Params, username string) ([]model.UserWithProfile, pagination.Page, error)
+}
+
+// Tokens are the credentials given to the client on login and refresh.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+type UsersServiceImpl struct {
+	UsersRepository         userRepository.UsersRepository
+	RefreshTokenRepository  refreshTokenRepository.RefreshTokenRepository
+	RbacRepository          rbacRepository.RbacRepository
+	PasswordResetRepository passwordResetRepository.PasswordResetRepository
+	Transaction             transaction.Manager
+	Mailer                  mailer.Mailer
+	Validate                *validator.Validate
+	Config                  config.AuthConfig
+	UnknownAccounts         *unknownAccounts
+}
+
+func NewUsersService(db *gorm.DB, validate *validator.Validate, cfg *config.Config, mail mailer.Mailer) UsersService {
+	return &UsersServiceImpl{
+		UsersRepository:         userRepository.NewUsersRepository(db),
+		RefreshTokenRepository:  refreshTokenRepository.NewRefreshTokenRepository(db),
+		RbacRepository:          rbacRepository.NewRbacRepository(db),
+		PasswordResetRepository: passwordResetRepository.NewPasswordResetRepository(db),
+		Transaction:             transaction.NewManager(db),
+		Mailer:                  mail,
+		Validate:                validate,
+		Config:                  cfg.Auth,
+		UnknownAccounts:         newUnknownAccounts(),
+	}
+}
+
+func (service *UsersServiceImpl) Register(ctx context.Context, request model.RegisterRequest) (*model.User, error) {
+
+	request.Email = normalizeEmail(request.Email)
+
+	// 1. Validate the request
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return nil, apperror.FromValidator(errValidate)
+	}
+
+	// 2. Hash user password
+	hashResult, errHash := hashPassword(ctx, request.Password)
+	if errHash != nil {
+		return nil, apperror.Internal(errHash)
+	}
+
+	user := model.User{
+		Username: request.Username,
+		Email:    request.Email,
+		Password: hashResult,
+	}
+
+	// 3. The user, the profile and the role are saved together or not at all
+	err := service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		// 4. Cek apakah user dengan email yang dikirim sudah ada di database
+		_, err := service.UsersRepository.FindByEmail(ctx, request.Email)
+		if err == nil {
+			return apperror.Conflict("User already exist")
+		}
+
+		if !apperror.Is(err, apperror.KindNotFound) {
+			return err
+		}
+
+		// 5. Save user to database
+		_, err = service.UsersRepository.Register(ctx, &user)
+		if err != nil {
+			return err
+		}
+
+		// 6. Every user has a profile, empty until they update it
+		err = service.UsersRepository.CreatUserProfileById(ctx, &model.ProfileCreateRequest{
+			UserId: user.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 7. Every new user gets the default role
+		defaultRole, err := service.RbacRepository.FindRoleByName(ctx, model.RoleUser)
+		if err != nil {
+			return err
+		}
+
+		return service.RbacRepository.AssignRole(ctx, user.ID, defaultRole.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.UsersRegistered.Inc()
+
+	// 8. Send the verification link once committed, the user can ask for a new one
+	errVerification := service.sendVerificationEmail(ctx, &user)
+	if errVerification != nil {
+		logger.FromContext(ctx).Error("send verification email", "userId", user.ID, "error", errVerification)
+	}
+
+	return &user, nil
+}
+
+func (service *UsersServiceImpl) Login(ctx context.Context, request model.LoginRequest) (*model.User, *Tokens, error) {
+
+	request.Email = normalizeEmail(request.Email)
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return nil, nil, apperror.FromValidator(errValidate)
+	}
+
+	// Get user by email
+	userResult, errUserFindByEmail := service.UsersRepository.FindByEmail(ctx, request.Email)
+	if apperror.Is(errUserFindByEmail, apperror.KindNotFound) {
+		return nil, nil, service.loginUnknownEmail(ctx, request)
+	}
+
+	if errUserFindByEmail != nil {
+		return nil, nil, errUserFindByEmail
+	}
+
+	// a locked account is refused before bcrypt, guessing has to wait for the lock
+	if userResult.LockedUntil != nil {
+		if wait := time.Until(*userResult.LockedUntil); wait > 0 {
+			metrics.LoginFailures.WithLabelValues(metrics.LoginLocked).Inc()
+			return nil, nil, apperror.TooManyRequests("Too many failed logins, account locked").RetryIn(wait)
+		}
+	}
+
+	// compare password from body request and from database
+	errComparePassword := checkPasswordHash(ctx, request.Password, userResult.Password)
+	if !errComparePassword {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
+		if err := service.recordLoginFailure(ctx, userResult); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, apperror.Unauthorized("Wrong password!")
+	}
+
+	if userResult.FailedLogins > 0 || userResult.LockedUntil != nil {
+		if err := service.UsersRepository.ResetLoginFailures(ctx, userResult.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if service.Config.EmailVerificationPolicy == config.EmailVerificationLogin && userResult.EmailVerifiedAt == nil {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginEmailNotVerified).Inc()
+		return nil, nil, apperror.Forbidden("Email not verified")
+	}
+
+	// every login starts a new refresh token family
+	tokens, err := service.issueTokens(ctx, userResult.ID, uuid.NewString())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metrics.Logins.Inc()
+
+	return userResult, tokens, nil
+}
+
+// loginUnknownEmail answers a login to an email without an account like a
+// wrong password: it runs bcrypt against a dummy hash and counts the failure
+// towards a lock, so neither the time nor a 429 tell that no account exists.
+func (service *UsersServiceImpl) loginUnknownEmail(ctx context.Context, request model.LoginRequest) error {
+
+	if wait := service.UnknownAccounts.lockedFor(request.Email); wait > 0 {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginLocked).Inc()
+		return apperror.TooManyRequests("Too many failed logins, account locked").RetryIn(wait)
+	}
+
+	checkPasswordHash(ctx, request.Password, dummyPasswordHash())
+
+	metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
+	service.UnknownAccounts.recordFailure(request.Email, service.Config)
+
+	return apperror.Unauthorized("Wrong password!")
+}
+
+// recordLoginFailure counts a wrong password of the user, and locks
+// the account once the count reaches the threshold, see lockDuration.
+func (service *UsersServiceImpl) recordLoginFailure(ctx context.Context, user *model.User) error {
+
+	if service.Config.LockoutThreshold <= 0 {
+		return nil
+	}
+
+	failedLogins, err := service.UsersRepository.RecordLoginFailure(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	duration := lockDuration(service.Config, failedLogins)
+	if duration == 0 {
+		return nil
+	}
+
+	logger.FromContext(ctx).Warn("account locked", "userId", user.ID, "failedLogins", failedLogins, "duration", duration.String())
+
+	return service.UsersRepository.LockUntil(ctx, user.ID, time.Now().Add(duration))
+}
+
+// RefreshToken exchanges a refresh token for new tokens. Using a refresh
+// token twice revokes every token of its login.
+func (service *UsersServiceImpl) RefreshToken(ctx context.Context, request model.RefreshTokenRequest) (*Tokens, error) {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return nil, apperror.FromValidator(errValidate)
+	}
+
+	// 1. Find the stored token
+	stored, errFind := service.RefreshTokenRepository.FindByHash(ctx, helper.HashSecureToken(request.RefreshToken))
+	if apperror.Is(errFind, apperror.KindNotFound) {
+		return nil, apperror.Unauthorized("Refresh token invalid")
+	}
+
+	if errFind != nil {
+		return nil, errFind
+	}
+
+	if stored.RevokedAt != nil || stored.ExpiresAt.Before(time.Now()) {
+		return nil, apperror.Unauthorized("Refresh token expired or revoked")
+	}
+
+	// 2. A token that was already rotated is being replayed, kill the whole family
+	if stored.UsedAt != nil {
+		return nil, service.revokeReusedFamily(ctx, stored.FamilyID)
+	}
+
+	// 3. Rotate the refresh token and issue a new access token
+	refreshToken, refreshTokenRecord, errRefreshToken := newRefreshToken(stored.UserID, stored.FamilyID)
+	if errRefreshToken != nil {
+		return nil, errRefreshToken
+	}
+
+	errRotate := service.RefreshTokenRepository.Rotate(ctx, stored.ID, refreshTokenRecord)
+	if errors.Is(errRotate, refreshTokenRepository.ErrRefreshTokenReused) {
+		return nil, service.revokeReusedFamily(ctx, stored.FamilyID)
+	}
+
+	if errRotate != nil {
+		return nil, errRotate
+	}
+
+	token, errGenerateToken := service.generateAccessToken(ctx, stored.UserID)
+	if errGenerateToken != nil {
+		return nil, errGenerateToken
+	}
+
+	return &Tokens{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    helper.AccessTokenTTL,
+	}, nil
+}
+
+// Logout revokes the refresh token and every token rotated from the same login.
+func (service *UsersServiceImpl) Logout(ctx context.Context, request model.LogoutRequest) error {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	stored, errFind := service.RefreshTokenRepository.FindByHash(ctx, helper.HashSecureToken(request.RefreshToken))
+	if apperror.Is(errFind, apperror.KindNotFound) {
+		return apperror.Unauthorized("Refresh token invalid")
+	}
+
+	if errFind != nil {
+		return errFind
+	}
+
+	return service.RefreshTokenRepository.RevokeFamily(ctx, stored.FamilyID)
+}
+
+// ForgotPassword sends a reset link when the email is registered. Unknown
+// emails are not an error, so accounts cannot be enumerated. The mailer of
+// the server queues the email, see mailer.QueueMailer, so the time of the
+// answer does not tell either.
+func (service *UsersServiceImpl) ForgotPassword(ctx context.Context, request model.ForgotPasswordRequest) error {
+
+	request.Email = normalizeEmail(request.Email)
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	// 1. Unknown emails get the same answer
+	user, errFind := service.UsersRepository.FindByEmail(ctx, request.Email)
+	if apperror.Is(errFind, apperror.KindNotFound) {
+		return nil
+	}
+
+	if errFind != nil {
+		return errFind
+	}
+
+	// 2. Only the latest reset link stays valid
+	token, tokenHash, errToken := helper.GenerateSecureToken()
+	if errToken != nil {
+		return errToken
+	}
+
+	errReset := service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		err := service.PasswordResetRepository.InvalidateByUserId(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return service.PasswordResetRepository.Create(ctx, &model.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(helper.PasswordResetTokenTTL),
+		})
+	})
+	if errReset != nil {
+		return errReset
+	}
+
+	// 3. Send the link, a failure is logged so the answer stays the same
+	errSend := service.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Hi " + user.Username + ",\r\n\r\n" +
+			"Open the link below to choose a new password. It expires in " + helper.PasswordResetTokenTTL.String() + ".\r\n\r\n" +
+			service.passwordResetLink(token) + "\r\n\r\n" +
+			"If you did not ask for a new password you can ignore this email.\r\n",
+	})
+	if errSend != nil {
+		logger.FromContext(ctx).Error("send password reset email", "userId", user.ID, "error", errSend)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with the token of the reset link. The
+// token can only be used once, and every refresh token of the user is revoked.
+func (service *UsersServiceImpl) ResetPassword(ctx context.Context, request model.ResetPasswordRequest) error {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	invalidToken := func() error {
+		return apperror.Validation("Reset token invalid or expired")
+	}
+
+	hashResult, errHash := hashPassword(ctx, request.Password)
+	if errHash != nil {
+		return apperror.Internal(errHash)
+	}
+
+	// The token is only spent when the password is saved
+	return service.Transaction.Do(ctx, func(ctx context.Context) error {
+
+		// 1. Find and use the token
+		reset, errFind := service.PasswordResetRepository.FindByHash(ctx, helper.HashSecureToken(request.Token))
+		if apperror.Is(errFind, apperror.KindNotFound) {
+			return invalidToken()
+		}
+
+		if errFind != nil {
+			return errFind
+		}
+
+		if reset.UsedAt != nil || reset.ExpiresAt.Before(time.Now()) {
+			return invalidToken()
+		}
+
+		errUsed := service.PasswordResetRepository.MarkUsed(ctx, reset.ID)
+		if errors.Is(errUsed, passwordResetRepository.ErrPasswordResetUsed) {
+			return invalidToken()
+		}
+
+		if errUsed != nil {
+			return errUsed
+		}
+
+		// 2. Save the new password
+		errUpdate := service.UsersRepository.UpdatePassword(ctx, reset.UserID, hashResult)
+		if errUpdate != nil {
+			return errUpdate
+		}
+
+		// 3. The new password ends a lock of wrong logins
+		errUnlock := service.UsersRepository.ResetLoginFailures(ctx, reset.UserID)
+		if errUnlock != nil {
+			return errUnlock
+		}
+
+		// 4. Sign out every session of the user
+		return service.RefreshTokenRepository.RevokeByUserId(ctx, reset.UserID)
+	})
+}
+
+// VerifyEmail marks the email of the user as verified with the token of the
+// verification link.
+func (service *UsersServiceImpl) VerifyEmail(ctx context.Context, token string) error {
+
+	userId, email, errToken := helper.ParseEmailVerificationToken(token)
+	invalidToken := func() error {
+		return apperror.Validation("Verification token invalid or expired")
+	}
+
+	if errToken != nil {
+		return invalidToken()
+	}
+
+	// The link is only valid for the email it was sent to
+	user, errFind := service.UsersRepository.FindById(ctx, userId)
+	if apperror.Is(errFind, apperror.KindNotFound) || (errFind == nil && user.Email != email) {
+		return invalidToken()
+	}
+
+	if errFind != nil {
+		return errFind
+	}
+
+	return service.UsersRepository.MarkEmailVerified(ctx, userId)
+}
+
+// ResendVerification sends a new verification link when the email is
+// registered and not verified yet. A new link can be asked once per
+// helper.EmailVerificationResendDelay.
+func (service *UsersServiceImpl) ResendVerification(ctx context.Context, request model.ResendVerificationRequest) error {
+
+	request.Email = normalizeEmail(request.Email)
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	user, errFind := service.UsersRepository.FindByEmail(ctx, request.Email)
+	if apperror.Is(errFind, apperror.KindNotFound) {
+		return nil
+	}
+
+	if errFind != nil {
+		return errFind
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	// Throttle the emails sent to one address
+	if user.VerificationSentAt != nil {
+		wait := time.Until(user.VerificationSentAt.Add(helper.EmailVerificationResendDelay))
+		if wait > 0 {
+			return apperror.TooManyRequests("Verification link sent too recently, please try again later").RetryIn(wait)
+		}
+	}
+
+	return service.sendVerificationEmail(ctx, user)
+}
+
+func (service *UsersServiceImpl) UpdateProfile(ctx context.Context, userId uint, request model.ProfileUpdateRequestBody) error {
+
+	errValidate := service.Validate.Struct(request)
+	if errValidate != nil {
+		return apperror.FromValidator(errValidate)
+	}
+
+	return service.UsersRepository.UpdateProfileById(ctx, userId, model.ProfileUpdateRequest{
+		Bio:       request.Bio,
+		Role:      request.Role,
+		Facebook:  request.Facebook,
+		Instagram: request.Instagram,
+		Linkedin:  request.LinkedIn,
+		Twitter:   request.Twitter,
+	})
+}
+
+func (service *UsersServiceImpl) FindProfile(ctx context.Context, userId uint) (*model.Profile, error) {
+	return service.UsersRepository.GetProfileById(ctx, userId)
+}
+
+// FindFollowers returns the users following userId, an unknown user is not
+// found rather than an empty page.
+func (service *UsersServiceImpl) FindFollowers(ctx context.Context, userId uint, params pagination.Params, username string) ([]model.UserWithProfile, pagination.Page, error) {
+
+	if _, err := service.UsersRepository.FindById(ctx, userId); err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	return service.UsersRepository.FindFollowersByUserId(ctx, userId, params, username)
+}
+
+// sendVerificationEmail sends the verification link and remembers when, for
+// the resend throttle.
+func (service *UsersServiceImpl) sendVerificationEmail(ctx context.Context, user *model.User) error {
+
+	token, err := helper.GenerateEmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	err = service.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: "Hi " + user.Username + ",\r\n\r\n" +
+			"Open the link below to verify your email. It expires in " + helper.EmailVerificationTTL.String() + ".\r\n\r\n" +
+			service.emailVerificationLink(token) + "\r\n",
+	})
+	if err != nil {
+		return err
+	}
+
+	return service.UsersRepository.UpdateVerificationSentAt(ctx, user.ID, time.Now())
+}
+
+func (service *UsersServiceImpl) revokeReusedFamily(ctx context.Context, familyId string) error {
+
+	errRevoke := service.RefreshTokenRepository.RevokeFamily(ctx, familyId)
+	if errRevoke != nil {
+		return errRevoke
+	}
+
+	return apperror.Unauthorized("Refresh token reused, please login again")
+}
+
+// issueTokens issues an access token and stores a new refresh token of the
+// family.
+func (service *UsersServiceImpl) issueTokens(ctx context.Context, userId uint, familyId string) (*Tokens, error) {
+
+	token, err := service.generateAccessToken(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshTokenRecord, err := newRefreshToken(userId, familyId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.RefreshTokenRepository.Create(ctx, refreshTokenRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    helper.AccessTokenTTL,
+	}, nil
+}
+
+// passwordResetLink builds the link of the reset email, the page of the
+// frontend that asks for the new password.
+func (service *UsersServiceImpl) passwordResetLink(token string) string {
+	return service.Config.ResetPasswordUrl + "?token=" + url.QueryEscape(token)
+}
+
+// emailVerificationLink builds the link of the verification email, by default
+// the /user/verify endpoint.
+func (service *UsersServiceImpl) emailVerificationLink(token string) string {
+	return service.Config.VerifyEmailUrl + "?token=" + url.QueryEscape(token)
+}
+
+// generateAccessToken issues an access token with the current roles of the user.
+func (service *UsersServiceImpl) generateAccessToken(ctx context.Context, userId uint) (string, error) {
+
+	roles, err := service.RbacRepository.FindRolesByUserId(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	return helper.GenerateToken(userId, roleNames)
+}
+
+// normalizeEmail is the form emails are stored and looked up in, the unique
+// index of the users is on lower(email).
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newRefreshToken returns the token for the client and the record to store.
+func newRefreshToken(userId uint, familyId string) (string, *model.RefreshToken, error) {
+
+	token, tokenHash, err := helper.GenerateSecureToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &model.RefreshToken{
+		UserID:    userId,
+		FamilyID:  familyId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(helper.RefreshTokenTTL),
+	}, nil
+}
+
+// hashPassword is helper.HashPassword in its own span, bcrypt is slow on
+// purpose and takes most of the time of a register.
+func hashPassword(ctx context.Context, password string) (string, error) {
+
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	return helper.HashPassword(password)
+}
+
+func checkPasswordHash(ctx context.Context, password string, hash string) bool {
+
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	return helper.CheckPasswordHash(password, hash)
+}
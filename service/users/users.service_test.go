@@ -0,0 +1,912 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project-app/apperror"
+	"project-app/config"
+	"project-app/helper"
+	"project-app/mailer"
+	"project-app/model"
+	"project-app/pagination"
+	"project-app/repository/fake"
+	passwordResetRepository "project-app/repository/passwordreset"
+	"project-app/validation"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "password123"
+
+func TestMain(m *testing.M) {
+	helper.SetJwtSecret("test-secret")
+	helper.PasswordHashCost = bcrypt.MinCost
+	m.Run()
+}
+
+// testMailer keeps the emails instead of sending them.
+type testMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (testMailer *testMailer) Send(ctx context.Context, message mailer.Message) error {
+
+	testMailer.mu.Lock()
+	defer testMailer.mu.Unlock()
+
+	testMailer.messages = append(testMailer.messages, message)
+	return nil
+}
+
+func (testMailer *testMailer) sent() []mailer.Message {
+
+	testMailer.mu.Lock()
+	defer testMailer.mu.Unlock()
+
+	return append([]mailer.Message(nil), testMailer.messages...)
+}
+
+type testRepositories struct {
+	db             *fake.Database
+	users          *fake.UsersRepository
+	refreshTokens  *fake.RefreshTokenRepository
+	rbac           *fake.RbacRepository
+	passwordResets *fake.PasswordResetRepository
+	mailer         *testMailer
+}
+
+func newTestService() (*UsersServiceImpl, *testRepositories) {
+
+	db := fake.NewDatabase()
+	db.SeedRoles()
+
+	repositories := &testRepositories{
+		db:             db,
+		users:          fake.NewUsersRepository(db),
+		refreshTokens:  fake.NewRefreshTokenRepository(db),
+		rbac:           fake.NewRbacRepository(db),
+		passwordResets: fake.NewPasswordResetRepository(db),
+		mailer:         &testMailer{},
+	}
+
+	return &UsersServiceImpl{
+		UsersRepository:         repositories.users,
+		RefreshTokenRepository:  repositories.refreshTokens,
+		RbacRepository:          repositories.rbac,
+		PasswordResetRepository: repositories.passwordResets,
+		Transaction:             fake.NewTransactionManager(db),
+		Mailer:                  repositories.mailer,
+		Validate:                validation.Validator(),
+		Config: config.AuthConfig{
+			EmailVerificationPolicy: config.EmailVerificationOff,
+			ResetPasswordUrl:        "http://localhost/reset-password",
+			VerifyEmailUrl:          "http://localhost/user/verify",
+		},
+		UnknownAccounts: newUnknownAccounts(),
+	}, repositories
+}
+
+// seedUser saves a user with testPassword, without the checks of Register.
+func seedUser(t *testing.T, repositories *testRepositories, email string) *model.User {
+
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	user := &model.User{
+		Username: email,
+		Email:    email,
+		Password: string(hash),
+	}
+	if _, err := repositories.users.Register(context.Background(), user); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return user
+}
+
+func TestUsersServiceFindByEmailFails(t *testing.T) {
+
+	errDown := errors.New("database is down")
+
+	tests := []struct {
+		name string
+		call func(service *UsersServiceImpl) error
+	}{
+		{
+			name: "login",
+			call: func(service *UsersServiceImpl) error {
+				_, _, err := service.Login(context.Background(), model.LoginRequest{Email: "ana@example.com", Password: testPassword})
+				return err
+			},
+		},
+		{
+			name: "forgot password",
+			call: func(service *UsersServiceImpl) error {
+				return service.ForgotPassword(context.Background(), model.ForgotPasswordRequest{Email: "ana@example.com"})
+			},
+		},
+		{
+			name: "resend verification",
+			call: func(service *UsersServiceImpl) error {
+				return service.ResendVerification(context.Background(), model.ResendVerificationRequest{Email: "ana@example.com"})
+			},
+		},
+		{
+			name: "register",
+			call: func(service *UsersServiceImpl) error {
+				_, err := service.Register(context.Background(), model.RegisterRequest{Username: "ana", Email: "ana@example.com", Password: testPassword})
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService()
+			seedUser(t, repositories, "ana@example.com")
+			repositories.users.Fail("FindByEmail", errDown)
+
+			// A database error is not an unknown email
+			if err := test.call(service); !errors.Is(err, errDown) {
+				t.Errorf("err = %v, want %v", err, errDown)
+			}
+			if sent := repositories.mailer.sent(); len(sent) != 0 {
+				t.Errorf("sent %d emails, want none", len(sent))
+			}
+		})
+	}
+}
+
+func TestUsersServiceUnknownEmail(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	seedUser(t, repositories, "ana@example.com")
+
+	_, _, err := service.Login(ctx, model.LoginRequest{Email: "bob@example.com", Password: testPassword})
+	if !apperror.Is(err, apperror.KindUnauthorized) {
+		t.Errorf("Login: err = %v, want unauthorized", err)
+	}
+
+	// Unknown emails get the same answer as registered ones
+	if err := service.ForgotPassword(ctx, model.ForgotPasswordRequest{Email: "bob@example.com"}); err != nil {
+		t.Errorf("ForgotPassword: %v", err)
+	}
+	if err := service.ResendVerification(ctx, model.ResendVerificationRequest{Email: "bob@example.com"}); err != nil {
+		t.Errorf("ResendVerification: %v", err)
+	}
+	if sent := repositories.mailer.sent(); len(sent) != 0 {
+		t.Errorf("sent %d emails, want none", len(sent))
+	}
+
+	_, _, err = service.Login(ctx, model.LoginRequest{Email: "ana@example.com", Password: testPassword})
+	if err != nil {
+		t.Errorf("Login of a registered email: %v", err)
+	}
+}
+
+func TestUsersServiceLoginLockout(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{"registered email", "ana@example.com"},
+		{"unknown email", "bob@example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService()
+			service.Config.LockoutThreshold = 2
+			service.Config.LockoutDuration = time.Minute
+			service.Config.LockoutMaxDuration = time.Hour
+			ctx := context.Background()
+			seedUser(t, repositories, "ana@example.com")
+
+			// An unknown email is locked like a registered one, the answers
+			// do not tell them apart
+			for i := 0; i < 2; i++ {
+				_, _, err := service.Login(ctx, model.LoginRequest{Email: test.email, Password: "wrong password"})
+				if !apperror.Is(err, apperror.KindUnauthorized) {
+					t.Fatalf("wrong password %d: err = %v, want unauthorized", i, err)
+				}
+			}
+
+			_, _, err := service.Login(ctx, model.LoginRequest{Email: test.email, Password: testPassword})
+			var appErr *apperror.AppError
+			if !errors.As(err, &appErr) || appErr.Kind != apperror.KindTooManyRequests {
+				t.Fatalf("login while locked: err = %v, want too many requests", err)
+			}
+			if appErr.RetryAfter <= 59*time.Second || appErr.RetryAfter > time.Minute {
+				t.Errorf("RetryAfter = %v, want about a minute", appErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestUsersServiceRegisterNormalizesEmail(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, model.RegisterRequest{Username: "ana", Email: "  Ana@Example.COM ", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Email != "ana@example.com" {
+		t.Errorf("email = %q, want %q", user.Email, "ana@example.com")
+	}
+
+	// The same email in another case is the same account
+	_, err = service.Register(ctx, model.RegisterRequest{Username: "ana2", Email: "ANA@example.com", Password: testPassword})
+	if !apperror.Is(err, apperror.KindConflict) {
+		t.Errorf("Register again: err = %v, want conflict", err)
+	}
+	if calls := repositories.users.Calls("Register"); calls != 1 {
+		t.Errorf("repository Register calls = %d, want 1", calls)
+	}
+
+	_, _, err = service.Login(ctx, model.LoginRequest{Email: " ANA@example.com", Password: testPassword})
+	if err != nil {
+		t.Errorf("Login: %v", err)
+	}
+}
+
+// login logs the seeded user in and returns its tokens.
+func login(t *testing.T, service *UsersServiceImpl, email string) *Tokens {
+
+	t.Helper()
+
+	_, tokens, err := service.Login(context.Background(), model.LoginRequest{Email: email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	return tokens
+}
+
+// refreshToken returns the stored refresh token of the client token.
+func refreshToken(t *testing.T, repositories *testRepositories, token string) *model.RefreshToken {
+
+	t.Helper()
+
+	stored, err := repositories.refreshTokens.FindByHash(context.Background(), helper.HashSecureToken(token))
+	if err != nil {
+		t.Fatalf("FindByHash: %v", err)
+	}
+
+	return stored
+}
+
+func TestUsersServiceRefreshTokenRotation(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	seedUser(t, repositories, "ana@example.com")
+
+	first := login(t, service, "ana@example.com")
+
+	second, err := service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Errorf("tokens = %+v, want a new refresh token and an access token", second)
+	}
+
+	// The new token is of the same login and can be rotated in turn
+	if refreshToken(t, repositories, first.RefreshToken).UsedAt == nil {
+		t.Errorf("first refresh token is not used")
+	}
+	if a, b := refreshToken(t, repositories, first.RefreshToken).FamilyID, refreshToken(t, repositories, second.RefreshToken).FamilyID; a != b {
+		t.Errorf("family = %q, want %q", b, a)
+	}
+
+	if _, err := service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: second.RefreshToken}); err != nil {
+		t.Errorf("RefreshToken of the new token: %v", err)
+	}
+
+	_, err = service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: "unknown"})
+	if !apperror.Is(err, apperror.KindUnauthorized) {
+		t.Errorf("RefreshToken unknown: err = %v, want unauthorized", err)
+	}
+}
+
+func TestUsersServiceRefreshTokenReuse(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	seedUser(t, repositories, "ana@example.com")
+
+	stolen := login(t, service, "ana@example.com")
+	other := login(t, service, "ana@example.com")
+
+	rotated, err := service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: stolen.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+
+	// Using the old token again revokes every token of its login
+	_, err = service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: stolen.RefreshToken})
+	if !apperror.Is(err, apperror.KindUnauthorized) {
+		t.Fatalf("RefreshToken reused: err = %v, want unauthorized", err)
+	}
+
+	if refreshToken(t, repositories, rotated.RefreshToken).RevokedAt == nil {
+		t.Errorf("token rotated from the reused one is not revoked")
+	}
+	_, err = service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+	if !apperror.Is(err, apperror.KindUnauthorized) {
+		t.Errorf("RefreshToken of the revoked family: err = %v, want unauthorized", err)
+	}
+
+	// The other login keeps working
+	if _, err := service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: other.RefreshToken}); err != nil {
+		t.Errorf("RefreshToken of another login: %v", err)
+	}
+}
+
+// TestUsersServiceRefreshTokenConcurrent refreshes one token twice at the
+// same time. One request wins, the other one is a reuse and revokes the
+// family, the token of the winner included.
+func TestUsersServiceRefreshTokenConcurrent(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	seedUser(t, repositories, "ana@example.com")
+
+	tokens := login(t, service, "ana@example.com")
+
+	var wg sync.WaitGroup
+	results := make([]*Tokens, 2)
+	errs := make([]error, 2)
+	start := make(chan struct{})
+
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			winner = i
+		case !apperror.Is(err, apperror.KindUnauthorized):
+			t.Errorf("RefreshToken %d: err = %v, want unauthorized", i, err)
+		}
+	}
+	if winner == -1 || (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("errs = %v, want exactly one refresh to succeed", errs)
+	}
+
+	if refreshToken(t, repositories, results[winner].RefreshToken).RevokedAt == nil {
+		t.Errorf("token of the winner is not revoked")
+	}
+}
+
+func TestUsersServiceLogout(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	seedUser(t, repositories, "ana@example.com")
+
+	first := login(t, service, "ana@example.com")
+	other := login(t, service, "ana@example.com")
+
+	rotated, err := service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+
+	// Logging out with the latest token revokes the older ones of the login too
+	if err := service.Logout(ctx, model.LogoutRequest{RefreshToken: rotated.RefreshToken}); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	for name, token := range map[string]string{"first": first.RefreshToken, "rotated": rotated.RefreshToken} {
+		if refreshToken(t, repositories, token).RevokedAt == nil {
+			t.Errorf("%s token is not revoked", name)
+		}
+	}
+	if refreshToken(t, repositories, other.RefreshToken).RevokedAt != nil {
+		t.Errorf("token of another login is revoked")
+	}
+
+	_, err = service.RefreshToken(ctx, model.RefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+	if !apperror.Is(err, apperror.KindUnauthorized) {
+		t.Errorf("RefreshToken after logout: err = %v, want unauthorized", err)
+	}
+
+	err = service.Logout(ctx, model.LogoutRequest{RefreshToken: "unknown"})
+	if !apperror.Is(err, apperror.KindUnauthorized) {
+		t.Errorf("Logout unknown: err = %v, want unauthorized", err)
+	}
+}
+
+// passwordReset saves a reset token of the user and returns the token of
+// the link.
+func passwordReset(t *testing.T, repositories *testRepositories, userId uint, expiresAt time.Time) string {
+
+	t.Helper()
+
+	token, tokenHash, err := helper.GenerateSecureToken()
+	if err != nil {
+		t.Fatalf("GenerateSecureToken: %v", err)
+	}
+
+	err = repositories.passwordResets.Create(context.Background(), &model.PasswordReset{
+		UserID:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	return token
+}
+
+func TestUsersServiceResetPassword(t *testing.T) {
+
+	tests := []struct {
+		name string
+		// token returns the token to reset with
+		token       func(t *testing.T, repositories *testRepositories, userId uint) string
+		wantInvalid bool
+	}{
+		{
+			name: "valid token",
+			token: func(t *testing.T, repositories *testRepositories, userId uint) string {
+				return passwordReset(t, repositories, userId, time.Now().Add(time.Hour))
+			},
+		},
+		{
+			name: "unknown token",
+			token: func(t *testing.T, repositories *testRepositories, userId uint) string {
+				return "unknown"
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T, repositories *testRepositories, userId uint) string {
+				return passwordReset(t, repositories, userId, time.Now().Add(-time.Second))
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "token of an older email",
+			token: func(t *testing.T, repositories *testRepositories, userId uint) string {
+				token := passwordReset(t, repositories, userId, time.Now().Add(time.Hour))
+				if err := repositories.passwordResets.InvalidateByUserId(context.Background(), userId); err != nil {
+					t.Fatalf("InvalidateByUserId: %v", err)
+				}
+				return token
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "token used by a concurrent request",
+			token: func(t *testing.T, repositories *testRepositories, userId uint) string {
+				// The other request marks it between FindByHash and MarkUsed
+				repositories.passwordResets.FailNext("MarkUsed", passwordResetRepository.ErrPasswordResetUsed)
+				return passwordReset(t, repositories, userId, time.Now().Add(time.Hour))
+			},
+			wantInvalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService()
+			ctx := context.Background()
+			user := seedUser(t, repositories, "ana@example.com")
+			tokens := login(t, service, "ana@example.com")
+
+			// The owner forgot the password after a few wrong ones
+			if _, err := repositories.users.RecordLoginFailure(ctx, user.ID); err != nil {
+				t.Fatalf("RecordLoginFailure: %v", err)
+			}
+			if err := repositories.users.LockUntil(ctx, user.ID, time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("LockUntil: %v", err)
+			}
+
+			err := service.ResetPassword(ctx, model.ResetPasswordRequest{
+				Token:    test.token(t, repositories, user.ID),
+				Password: "new password",
+			})
+
+			stored, errFind := repositories.users.FindById(ctx, user.ID)
+			if errFind != nil {
+				t.Fatalf("FindById: %v", errFind)
+			}
+			revoked := refreshToken(t, repositories, tokens.RefreshToken).RevokedAt != nil
+
+			if test.wantInvalid {
+				if !apperror.Is(err, apperror.KindValidation) {
+					t.Errorf("err = %v, want validation error", err)
+				}
+				if stored.Password != user.Password {
+					t.Errorf("password changed")
+				}
+				if revoked {
+					t.Errorf("refresh token revoked")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ResetPassword: %v", err)
+			}
+			if !helper.CheckPasswordHash("new password", stored.Password) {
+				t.Errorf("password is not the new one")
+			}
+			if !revoked {
+				t.Errorf("refresh token of the old password is not revoked")
+			}
+			if stored.FailedLogins != 0 || stored.LockedUntil != nil {
+				t.Errorf("failed logins = %d, locked until %v, want the lock cleared", stored.FailedLogins, stored.LockedUntil)
+			}
+		})
+	}
+}
+
+func TestUsersServiceResetPasswordOnce(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	user := seedUser(t, repositories, "ana@example.com")
+	token := passwordReset(t, repositories, user.ID, time.Now().Add(time.Hour))
+
+	if err := service.ResetPassword(ctx, model.ResetPasswordRequest{Token: token, Password: "new password"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	// The link cannot be replayed, by the user or by whoever saw it
+	err := service.ResetPassword(ctx, model.ResetPasswordRequest{Token: token, Password: "other password"})
+	if !apperror.Is(err, apperror.KindValidation) {
+		t.Errorf("ResetPassword again: err = %v, want validation error", err)
+	}
+
+	stored, err := repositories.users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindById: %v", err)
+	}
+	if !helper.CheckPasswordHash("new password", stored.Password) {
+		t.Errorf("password is not the one of the first reset")
+	}
+}
+
+func TestUsersServiceForgotPassword(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+	seedUser(t, repositories, "ana@example.com")
+
+	for i := 0; i < 2; i++ {
+		if err := service.ForgotPassword(ctx, model.ForgotPasswordRequest{Email: "ana@example.com"}); err != nil {
+			t.Fatalf("ForgotPassword: %v", err)
+		}
+	}
+
+	sent := repositories.mailer.sent()
+	if len(sent) != 2 || sent[0].To != "ana@example.com" || !strings.Contains(sent[0].Body, "http://localhost/reset-password?token=") {
+		t.Fatalf("sent = %+v, want two reset links to ana@example.com", sent)
+	}
+
+	// Only the link of the latest email works
+	links := make([]string, len(sent))
+	for i, message := range sent {
+		link := message.Body[strings.Index(message.Body, "?token=")+len("?token="):]
+		token, err := url.QueryUnescape(strings.Fields(link)[0])
+		if err != nil {
+			t.Fatalf("token of the link: %v", err)
+		}
+		links[i] = token
+	}
+
+	err := service.ResetPassword(ctx, model.ResetPasswordRequest{Token: links[0], Password: "new password"})
+	if !apperror.Is(err, apperror.KindValidation) {
+		t.Errorf("ResetPassword with the first link: err = %v, want validation error", err)
+	}
+	if err := service.ResetPassword(ctx, model.ResetPasswordRequest{Token: links[1], Password: "new password"}); err != nil {
+		t.Errorf("ResetPassword with the latest link: %v", err)
+	}
+}
+
+func TestUsersServiceResendVerification(t *testing.T) {
+
+	tests := []struct {
+		name string
+		// sentAgo is how long ago the last link was sent, 0 when never
+		sentAgo   time.Duration
+		verified  bool
+		wantKind  apperror.Kind
+		wantEmail bool
+	}{
+		{name: "never sent", wantEmail: true},
+		{name: "sent a moment ago", sentAgo: time.Second, wantKind: apperror.KindTooManyRequests},
+		{name: "sent before the delay", sentAgo: helper.EmailVerificationResendDelay + time.Second, wantEmail: true},
+		{name: "already verified", verified: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService()
+			ctx := context.Background()
+			user := seedUser(t, repositories, "ana@example.com")
+
+			if test.sentAgo > 0 {
+				if err := repositories.users.UpdateVerificationSentAt(ctx, user.ID, time.Now().Add(-test.sentAgo)); err != nil {
+					t.Fatalf("UpdateVerificationSentAt: %v", err)
+				}
+			}
+			if test.verified {
+				if err := repositories.users.MarkEmailVerified(ctx, user.ID); err != nil {
+					t.Fatalf("MarkEmailVerified: %v", err)
+				}
+			}
+
+			err := service.ResendVerification(ctx, model.ResendVerificationRequest{Email: "ana@example.com"})
+
+			if test.wantKind != "" {
+				var appErr *apperror.AppError
+				if !errors.As(err, &appErr) || appErr.Kind != test.wantKind {
+					t.Fatalf("err = %v, want kind %s", err, test.wantKind)
+				}
+				if appErr.RetryAfter <= 0 || appErr.RetryAfter > helper.EmailVerificationResendDelay {
+					t.Errorf("retry after %s, want at most %s", appErr.RetryAfter, helper.EmailVerificationResendDelay)
+				}
+			} else if err != nil {
+				t.Fatalf("ResendVerification: %v", err)
+			}
+
+			sent := repositories.mailer.sent()
+			if got := len(sent) == 1; got != test.wantEmail {
+				t.Fatalf("sent %d emails, want email %t", len(sent), test.wantEmail)
+			}
+			if !test.wantEmail {
+				return
+			}
+
+			// The throttle starts again from this email
+			stored, err := repositories.users.FindById(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("FindById: %v", err)
+			}
+			if stored.VerificationSentAt == nil || time.Since(*stored.VerificationSentAt) > time.Minute {
+				t.Errorf("verificationSentAt = %v, want now", stored.VerificationSentAt)
+			}
+			err = service.ResendVerification(ctx, model.ResendVerificationRequest{Email: "ana@example.com"})
+			if !apperror.Is(err, apperror.KindTooManyRequests) {
+				t.Errorf("ResendVerification again: err = %v, want too many requests", err)
+			}
+		})
+	}
+}
+
+func TestUsersServiceVerifyEmail(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		token       func(t *testing.T, user *model.User) string
+		wantInvalid bool
+	}{
+		{
+			name: "token of the email",
+			token: func(t *testing.T, user *model.User) string {
+				token, err := helper.GenerateEmailVerificationToken(user.ID, user.Email)
+				if err != nil {
+					t.Fatalf("GenerateEmailVerificationToken: %v", err)
+				}
+				return token
+			},
+		},
+		{
+			name: "token of a former email",
+			token: func(t *testing.T, user *model.User) string {
+				token, err := helper.GenerateEmailVerificationToken(user.ID, "old@example.com")
+				if err != nil {
+					t.Fatalf("GenerateEmailVerificationToken: %v", err)
+				}
+				return token
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "token of a missing user",
+			token: func(t *testing.T, user *model.User) string {
+				token, err := helper.GenerateEmailVerificationToken(100, user.Email)
+				if err != nil {
+					t.Fatalf("GenerateEmailVerificationToken: %v", err)
+				}
+				return token
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "access token",
+			token: func(t *testing.T, user *model.User) string {
+				token, err := helper.GenerateToken(user.ID, nil)
+				if err != nil {
+					t.Fatalf("GenerateToken: %v", err)
+				}
+				return token
+			},
+			wantInvalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService()
+			ctx := context.Background()
+			user := seedUser(t, repositories, "ana@example.com")
+
+			err := service.VerifyEmail(ctx, test.token(t, user))
+
+			stored, errFind := repositories.users.FindById(ctx, user.ID)
+			if errFind != nil {
+				t.Fatalf("FindById: %v", errFind)
+			}
+
+			if test.wantInvalid {
+				if !apperror.Is(err, apperror.KindValidation) {
+					t.Errorf("err = %v, want validation error", err)
+				}
+				if stored.EmailVerifiedAt != nil {
+					t.Errorf("email is verified")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("VerifyEmail: %v", err)
+			}
+			if stored.EmailVerifiedAt == nil {
+				t.Errorf("email is not verified")
+			}
+		})
+	}
+}
+
+func TestUsersServiceLoginVerificationPolicy(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		policy   string
+		verified bool
+		wantKind apperror.Kind
+	}{
+		{name: "policy off", policy: config.EmailVerificationOff},
+		{name: "policy write", policy: config.EmailVerificationWrite},
+		{name: "policy login, unverified", policy: config.EmailVerificationLogin, wantKind: apperror.KindForbidden},
+		{name: "policy login, verified", policy: config.EmailVerificationLogin, verified: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			service, repositories := newTestService()
+			service.Config.EmailVerificationPolicy = test.policy
+			ctx := context.Background()
+			user := seedUser(t, repositories, "ana@example.com")
+
+			if test.verified {
+				if err := repositories.users.MarkEmailVerified(ctx, user.ID); err != nil {
+					t.Fatalf("MarkEmailVerified: %v", err)
+				}
+			}
+
+			_, tokens, err := service.Login(ctx, model.LoginRequest{Email: "ana@example.com", Password: testPassword})
+
+			if test.wantKind != "" {
+				if !apperror.Is(err, test.wantKind) {
+					t.Errorf("err = %v, want kind %s", err, test.wantKind)
+				}
+				if calls := repositories.refreshTokens.Calls("Create"); calls != 0 {
+					t.Errorf("refresh tokens created = %d, want 0", calls)
+				}
+				return
+			}
+
+			if err != nil || tokens == nil {
+				t.Fatalf("Login: %v", err)
+			}
+		})
+	}
+}
+
+func TestUsersServiceFindFollowers(t *testing.T) {
+
+	service, repositories := newTestService()
+	ctx := context.Background()
+
+	// bob, carol and dave follow alice, alice follows bob
+	alice := seedUser(t, repositories, "alice@example.com")
+	for _, email := range []string{"bob@example.com", "carol@example.com", "dave@example.com"} {
+		follower := seedUser(t, repositories, email)
+		repositories.db.Follow(follower.ID, alice.ID)
+	}
+	repositories.db.Follow(alice.ID, 2)
+
+	tests := []struct {
+		name          string
+		userId        uint
+		params        pagination.Params
+		username      string
+		wantUsernames []string
+		wantTotal     int64
+		wantKind      apperror.Kind
+	}{
+		{name: "first page", userId: alice.ID, params: pagination.Offset(1, 2), wantUsernames: []string{"bob@example.com", "carol@example.com"}, wantTotal: 3},
+		{name: "second page", userId: alice.ID, params: pagination.Offset(2, 2), wantUsernames: []string{"dave@example.com"}, wantTotal: 3},
+		{name: "search", userId: alice.ID, params: pagination.Offset(1, 2), username: "car", wantUsernames: []string{"carol@example.com"}, wantTotal: 1},
+		{name: "followers of another user", userId: 2, params: pagination.Offset(1, 2), wantUsernames: []string{"alice@example.com"}, wantTotal: 1},
+		{name: "unknown user", userId: 100, params: pagination.Offset(1, 2), wantKind: apperror.KindNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			followers, page, err := service.FindFollowers(ctx, test.userId, test.params, test.username)
+			if test.wantKind != "" {
+				if !apperror.Is(err, test.wantKind) {
+					t.Fatalf("err = %v, want kind %s", err, test.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindFollowers: %v", err)
+			}
+
+			usernames := []string{}
+			for _, follower := range followers {
+				usernames = append(usernames, follower.Username)
+			}
+			if !reflect.DeepEqual(usernames, test.wantUsernames) || page.TotalEntries != test.wantTotal {
+				t.Errorf("followers = %v of %d, want %v of %d", usernames, page.TotalEntries, test.wantUsernames, test.wantTotal)
+			}
+		})
+	}
+
+	// The cursor of the first page continues after it
+	_, first, err := service.FindFollowers(ctx, alice.ID, pagination.Offset(1, 2), "")
+	if err != nil {
+		t.Fatalf("FindFollowers: %v", err)
+	}
+	cursor, err := pagination.DecodeCursor(first.NextCursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	followers, _, err := service.FindFollowers(ctx, alice.ID, pagination.After(*cursor, 2), "")
+	if err != nil || len(followers) != 1 || followers[0].Username != "dave@example.com" {
+		t.Errorf("after cursor = %+v, %v, want dave", followers, err)
+	}
+}
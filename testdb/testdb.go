@@ -0,0 +1,183 @@
+// Package testdb gives every test its own empty database with the schema of
+// the app. Tests run on in-memory SQLite, or on Postgres when DsnEnv is set.
+package testdb
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"project-app/migration"
+	"project-app/schema"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DsnEnv names the environment variable with the DSN of a Postgres database
+// for the tests, for example
+// "host=localhost user=postgres password=postgres dbname=test sslmode=disable".
+const DsnEnv = "TEST_DATABASE_DSN"
+
+// Models are the tables of the app. SQLite creates them with AutoMigrate,
+// the migrations in migration/sql are written for Postgres.
+var Models = []interface{}{
+	&schema.Users{},
+	&schema.UserProfile{},
+	&schema.FollowUsers{},
+	&schema.Category{},
+	&schema.Project{},
+	&schema.ProjectItem{},
+	&schema.RefreshToken{},
+	&schema.PasswordReset{},
+	&schema.Role{},
+	&schema.Permission{},
+	&schema.UserRole{},
+}
+
+// sqliteIndexes are the indexes of the migrations that Models cannot declare.
+var sqliteIndexes = []string{
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL",
+}
+
+var counter uint64
+
+var invalidName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Open returns a database with the schema of the app that only this test
+// sees. It is dropped when the test ends.
+func Open(t testing.TB) *gorm.DB {
+
+	t.Helper()
+
+	name := fmt.Sprintf("test_%d_%s", atomic.AddUint64(&counter, 1), invalidName.ReplaceAllString(strings.ToLower(t.Name()), "_"))
+	if len(name) > 60 {
+		name = name[:60]
+	}
+
+	if dsn := os.Getenv(DsnEnv); dsn != "" {
+		return openPostgres(t, dsn, name)
+	}
+
+	return openSqlite(t, name)
+}
+
+func openSqlite(t testing.TB, name string) *gorm.DB {
+
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+
+	// The in-memory database lives as long as a connection to it, and one
+	// connection keeps SQLite from reporting locked tables.
+	sqlDb, err := db.DB()
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDb.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDb.Close() })
+
+	if err := db.AutoMigrate(Models...); err != nil {
+		t.Fatalf("migrate sqlite: %v", err)
+	}
+
+	// AutoMigrate cannot create the index on an expression of the migrations
+	for _, index := range sqliteIndexes {
+		if err := db.Exec(index).Error; err != nil {
+			t.Fatalf("migrate sqlite: %v", err)
+		}
+	}
+
+	return db
+}
+
+// openPostgres creates a schema for the test and runs the migrations in it,
+// so tests can share one database.
+func openPostgres(t testing.TB, dsn string, name string) *gorm.DB {
+
+	t.Helper()
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+
+	adminDb, err := admin.DB()
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+
+	if err := admin.Exec(`CREATE SCHEMA "` + name + `"`).Error; err != nil {
+		adminDb.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA "` + name + `" CASCADE`)
+		adminDb.Close()
+	})
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, name)), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("migrate postgres: %v", err)
+	}
+
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("migrate postgres: %v", err)
+	}
+
+	return db
+}
+
+// withSearchPath adds search_path to a URL or a key=value DSN.
+func withSearchPath(dsn string, name string) string {
+
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + name
+	}
+
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + name
+	}
+
+	return dsn + "?search_path=" + name
+}
+
+// Seed inserts the records in order, for example a user before its profile.
+func Seed(t testing.TB, db *gorm.DB, records ...interface{}) {
+
+	t.Helper()
+
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("seed %T: %v", record, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin starts a span for every SQL statement of GORM, a child of the
+// span of the context of the statement. The statement is recorded with its
+// placeholders, never with its values.
+type GormPlugin struct{}
+
+func (plugin GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (plugin GormPlugin) Initialize(db *gorm.DB) error {
+
+	callback := db.Callback()
+
+	// The span wraps every other callback of the operation
+	return errors.Join(
+		callback.Create().Before("*").Register("tracing:before_create", start("create")),
+		callback.Create().After("*").Register("tracing:after_create", end("create")),
+		callback.Query().Before("*").Register("tracing:before_query", start("query")),
+		callback.Query().After("*").Register("tracing:after_query", end("query")),
+		callback.Update().Before("*").Register("tracing:before_update", start("update")),
+		callback.Update().After("*").Register("tracing:after_update", end("update")),
+		callback.Delete().Before("*").Register("tracing:before_delete", start("delete")),
+		callback.Delete().After("*").Register("tracing:after_delete", end("delete")),
+		callback.Row().Before("*").Register("tracing:before_row", start("row")),
+		callback.Row().After("*").Register("tracing:after_row", end("row")),
+		callback.Raw().Before("*").Register("tracing:before_raw", start("raw")),
+		callback.Raw().After("*").Register("tracing:after_raw", end("raw")),
+	)
+}
+
+func start(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+
+		_, span := Start(db.Statement.Context, operation, trace.WithSpanKind(trace.SpanKindClient))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func end(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+
+		value, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+
+		span, ok := value.(trace.Span)
+		if !ok {
+			return
+		}
+		defer span.End()
+
+		// The table is only known once the statement is built
+		table := db.Statement.Table
+		if table != "" {
+			span.SetName(operation + " " + table)
+		}
+
+		span.SetAttributes(
+			dbSystem(db),
+			semconv.DBOperation(operation),
+			semconv.DBSQLTable(table),
+			semconv.DBStatement(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+}
+
+func dbSystem(db *gorm.DB) attribute.KeyValue {
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemKey.String(db.Dialector.Name())
+	}
+}
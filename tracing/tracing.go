@@ -0,0 +1,75 @@
+// Package tracing sets up OpenTelemetry and starts the spans of the app.
+// Until Setup installs a provider, spans are not recorded, which is what the
+// tests and the migrate command get.
+package tracing
+
+import (
+	"context"
+	"io"
+	"project-app/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "project-app"
+
+// Start starts a span as a child of the span of ctx, the returned context
+// carries it. The caller ends it.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
+
+// Setup installs the W3C trace context propagator and the tracer provider of
+// cfg. The returned function flushes the spans that are left and stops the
+// exporter, call it when the app stops. The stdout exporter writes to
+// stdout.
+func Setup(ctx context.Context, cfg config.TracingConfig, serviceName string, stdout io.Writer) (shutdown func(ctx context.Context) error, err error) {
+
+	// The traceparent of a caller is kept even when nothing is exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.Exporter == config.TracingExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg, stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig, stdout io.Writer) (sdktrace.SpanExporter, error) {
+
+	if cfg.Exporter == config.TracingExporterStdout {
+		return stdouttrace.New(stdouttrace.WithWriter(stdout))
+	}
+
+	// An http:// endpoint is sent without TLS
+	var options []otlptracehttp.Option
+	if cfg.OtlpEndpoint != "" {
+		options = append(options, otlptracehttp.WithEndpointURL(cfg.OtlpEndpoint))
+	}
+
+	return otlptracehttp.New(ctx, options...)
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"project-app/config"
+	"project-app/model"
+	"project-app/testdb"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// record installs a provider that keeps the spans in memory until the test
+// ends.
+func record(t *testing.T) *tracetest.SpanRecorder {
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	return recorder
+}
+
+func find(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	t.Fatalf("no span %q", name)
+	return nil
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[string]string {
+
+	values := map[string]string{}
+	for _, attribute := range span.Attributes() {
+		values[string(attribute.Key)] = attribute.Value.Emit()
+	}
+
+	return values
+}
+
+func TestGormPlugin(t *testing.T) {
+
+	db := testdb.Open(t)
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+
+	recorder := record(t)
+
+	ctx, parent := Start(context.Background(), "CategoryRepository.Create")
+	if err := db.WithContext(ctx).Create(&model.Category{Name: "Secret project", UserID: 1}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := db.WithContext(ctx).Exec("SELECT * FROM missing_table").Error; err == nil {
+		t.Fatalf("query of a missing table did not fail")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+
+	// 1. The statement is a child of the span of its context
+	create := find(t, spans, "create categories")
+	if create.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("create is not a child of the repository span")
+	}
+
+	values := attributes(create)
+	if !strings.HasPrefix(values[string(semconv.DBStatementKey)], "INSERT INTO") {
+		t.Errorf("db.statement = %q", values[string(semconv.DBStatementKey)])
+	}
+	if strings.Contains(values[string(semconv.DBStatementKey)], "Secret project") {
+		t.Errorf("db.statement contains the values: %q", values[string(semconv.DBStatementKey)])
+	}
+	if values[string(semconv.DBSystemKey)] != "sqlite" || values["db.rows_affected"] != "1" {
+		t.Errorf("attributes = %v", values)
+	}
+	if create.Status().Code == codes.Error {
+		t.Errorf("create status = %v", create.Status())
+	}
+
+	// 2. A failed statement is an error
+	raw := find(t, spans, "raw")
+	if raw.Status().Code != codes.Error || len(raw.Events()) == 0 {
+		t.Errorf("raw status = %v, events = %d, want the error recorded", raw.Status(), len(raw.Events()))
+	}
+}
+
+func TestSetupStdout(t *testing.T) {
+
+	var buffer bytes.Buffer
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter:    config.TracingExporterStdout,
+		SampleRatio: 1,
+	}, "project-app-test", &buffer)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := Start(context.Background(), "UsersRepository.FindByEmail")
+	span.End()
+
+	// The spans are batched until the shutdown
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	for _, want := range []string{"UsersRepository.FindByEmail", "project-app-test"} {
+		if !strings.Contains(buffer.String(), want) {
+			t.Errorf("exported spans do not contain %q: %s", want, buffer.String())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package transaction
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type contextKey struct{}
+
+// Manager runs several repository calls in one transaction. The transaction
+// travels in the context, repositories find it with DB.
+type Manager interface {
+	// Do runs fn in a transaction and commits it when fn returns nil. It is
+	// rolled back when fn returns an error or panics. Inside another Do, fn
+	// runs in a savepoint of the outer transaction, so only its own changes
+	// are rolled back.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type ManagerImpl struct {
+	Db *gorm.DB
+}
+
+func NewManager(db *gorm.DB) Manager {
+	return &ManagerImpl{
+		Db: db,
+	}
+}
+
+func (manager *ManagerImpl) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Run(ctx, manager.Db, func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, contextKey{}, tx))
+	})
+}
+
+// Run is Do for a single repository method that needs more than one
+// statement: fn gets the transaction, or a savepoint when ctx already
+// carries one.
+func Run(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return DB(ctx, db).Transaction(fn)
+}
+
+// DB returns the transaction carried by ctx, or db outside of a transaction.
+// Repositories run every query on it.
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+
+	if tx, ok := ctx.Value(contextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,238 @@
+// The tests use the repositories, which import transaction.
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"project-app/model"
+	categoryRepository "project-app/repository/category"
+	"project-app/schema"
+	"project-app/testdb"
+	"project-app/transaction"
+
+	"gorm.io/gorm"
+)
+
+// categoryNames returns the names of the categories in the database, by id.
+func categoryNames(t *testing.T, db *gorm.DB) []string {
+
+	t.Helper()
+
+	names := []string{}
+	err := db.Model(&schema.Category{}).Order("id").Pluck("name", &names).Error
+	if err != nil {
+		t.Fatalf("read categories: %v", err)
+	}
+
+	return names
+}
+
+func TestManagerDo(t *testing.T) {
+
+	errFail := errors.New("fail")
+
+	tests := []struct {
+		name      string
+		fn        func(ctx context.Context, categories categoryRepository.CategoryRepository) error
+		wantErr   error
+		wantNames []string
+	}{
+		{
+			name: "commit",
+			fn: func(ctx context.Context, categories categoryRepository.CategoryRepository) error {
+				if err := categories.Create(ctx, &model.Category{UserID: 1, Name: "Design"}); err != nil {
+					return err
+				}
+				return categories.Create(ctx, &model.Category{UserID: 1, Name: "Marketing"})
+			},
+			wantNames: []string{"Design", "Marketing"},
+		},
+		{
+			name: "rollback on error",
+			fn: func(ctx context.Context, categories categoryRepository.CategoryRepository) error {
+				if err := categories.Create(ctx, &model.Category{UserID: 1, Name: "Design"}); err != nil {
+					return err
+				}
+				return errFail
+			},
+			wantErr:   errFail,
+			wantNames: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			db := testdb.Open(t)
+			manager := transaction.NewManager(db)
+			categories := categoryRepository.NewCategoryRepository(db)
+
+			err := manager.Do(context.Background(), func(ctx context.Context) error {
+				return test.fn(ctx, categories)
+			})
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("err = %v, want %v", err, test.wantErr)
+			}
+
+			if names := categoryNames(t, db); !reflect.DeepEqual(names, test.wantNames) {
+				t.Errorf("categories = %v, want %v", names, test.wantNames)
+			}
+		})
+	}
+}
+
+func TestManagerDoPanic(t *testing.T) {
+
+	db := testdb.Open(t)
+	manager := transaction.NewManager(db)
+	categories := categoryRepository.NewCategoryRepository(db)
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Errorf("recovered %v, want the panic of fn", recovered)
+			}
+		}()
+
+		manager.Do(context.Background(), func(ctx context.Context) error {
+			if err := categories.Create(ctx, &model.Category{UserID: 1, Name: "Design"}); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+
+	if names := categoryNames(t, db); len(names) != 0 {
+		t.Errorf("categories = %v, want none", names)
+	}
+
+	// The connection went back to the pool without the transaction
+	if err := categories.Create(context.Background(), &model.Category{UserID: 1, Name: "Marketing"}); err != nil {
+		t.Fatalf("Create after panic: %v", err)
+	}
+	if names := categoryNames(t, db); !reflect.DeepEqual(names, []string{"Marketing"}) {
+		t.Errorf("categories = %v, want [Marketing]", names)
+	}
+}
+
+func TestManagerDoNested(t *testing.T) {
+
+	errFail := errors.New("fail")
+
+	tests := []struct {
+		name      string
+		innerErr  error
+		outerErr  error
+		wantNames []string
+	}{
+		{name: "both commit", wantNames: []string{"Outer", "Inner", "After"}},
+		{name: "inner rolls back to its savepoint", innerErr: errFail, wantNames: []string{"Outer", "After"}},
+		{name: "outer rolls back the inner too", outerErr: errFail, wantNames: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			db := testdb.Open(t)
+			manager := transaction.NewManager(db)
+			categories := categoryRepository.NewCategoryRepository(db)
+
+			err := manager.Do(context.Background(), func(ctx context.Context) error {
+
+				if err := categories.Create(ctx, &model.Category{UserID: 1, Name: "Outer"}); err != nil {
+					return err
+				}
+
+				errInner := manager.Do(ctx, func(ctx context.Context) error {
+					if err := categories.Create(ctx, &model.Category{UserID: 1, Name: "Inner"}); err != nil {
+						return err
+					}
+					return test.innerErr
+				})
+				if !errors.Is(errInner, test.innerErr) {
+					t.Errorf("inner err = %v, want %v", errInner, test.innerErr)
+				}
+
+				// The outer transaction goes on after the inner one failed
+				if err := categories.Create(ctx, &model.Category{UserID: 1, Name: "After"}); err != nil {
+					return err
+				}
+
+				return test.outerErr
+			})
+			if !errors.Is(err, test.outerErr) {
+				t.Errorf("outer err = %v, want %v", err, test.outerErr)
+			}
+
+			if names := categoryNames(t, db); !reflect.DeepEqual(names, test.wantNames) {
+				t.Errorf("categories = %v, want %v", names, test.wantNames)
+			}
+		})
+	}
+}
+
+func TestRunAndDB(t *testing.T) {
+
+	db := testdb.Open(t)
+	manager := transaction.NewManager(db)
+	errFail := errors.New("fail")
+
+	// Outside of Do, DB is the database
+	ctx := context.Background()
+	if transaction.DB(ctx, db).Statement.ConnPool != db.Statement.ConnPool {
+		t.Errorf("DB without a transaction is not the database")
+	}
+
+	err := manager.Do(ctx, func(ctx context.Context) error {
+
+		// Inside, DB and Run see the rows of the transaction before the commit
+		if err := transaction.DB(ctx, db).Create(&schema.Category{UserID: 1, Name: "Design"}).Error; err != nil {
+			return err
+		}
+
+		errRun := transaction.Run(ctx, db, func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&schema.Category{}).Count(&count).Error; err != nil {
+				return err
+			}
+			if count != 1 {
+				t.Errorf("Run sees %d categories, want 1", count)
+			}
+
+			if err := tx.Create(&schema.Category{UserID: 1, Name: "Marketing"}).Error; err != nil {
+				return err
+			}
+			return errFail
+		})
+		if !errors.Is(errRun, errFail) {
+			t.Errorf("Run: err = %v, want %v", errRun, errFail)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	// Run rolled back its savepoint only
+	if names := categoryNames(t, db); !reflect.DeepEqual(names, []string{"Design"}) {
+		t.Errorf("categories = %v, want [Design]", names)
+	}
+
+	// Run without Do has its own transaction
+	err = transaction.Run(ctx, db, func(tx *gorm.DB) error {
+		if err := tx.Create(&schema.Category{UserID: 1, Name: "Sales"}).Error; err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Errorf("Run: err = %v, want %v", err, errFail)
+	}
+	if names := categoryNames(t, db); !reflect.DeepEqual(names, []string{"Design"}) {
+		t.Errorf("categories = %v, want [Design]", names)
+	}
+}
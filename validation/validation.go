@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
+)
+
+const (
+	LanguageEnglish    = "en"
+	LanguageIndonesian = "id"
+
+	// DefaultLanguage is used when the client accepts no supported language.
+	DefaultLanguage = LanguageEnglish
+)
+
+var universal = ut.New(en.New(), en.New(), id.New())
+
+// The translations are registered on the shared translators, so they can
+// only be registered once per process.
+var (
+	once   sync.Once
+	shared *validator.Validate
+)
+
+// Validator returns the validator of the app. Field errors use the json name
+// of the field and can be translated with Translator. It is safe for
+// concurrent use, every caller gets the same instance.
+func Validator() *validator.Validate {
+	once.Do(func() {
+		shared = newValidator()
+	})
+
+	return shared
+}
+
+func newValidator() *validator.Validate {
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	english, _ := universal.GetTranslator(LanguageEnglish)
+	if err := enTranslations.RegisterDefaultTranslations(validate, english); err != nil {
+		panic(err)
+	}
+
+	indonesian, _ := universal.GetTranslator(LanguageIndonesian)
+	if err := idTranslations.RegisterDefaultTranslations(validate, indonesian); err != nil {
+		panic(err)
+	}
+
+	return validate
+}
+
+// Translator returns the translator of a language returned by Language.
+func Translator(language string) ut.Translator {
+
+	translator, found := universal.GetTranslator(language)
+	if !found {
+		translator, _ = universal.GetTranslator(DefaultLanguage)
+	}
+
+	return translator
+}
+
+// Language picks the supported language the client prefers from an
+// Accept-Language header, e.g. "id-ID,id;q=0.9,en;q=0.8" gives "id".
+func Language(acceptLanguage string) string {
+
+	type option struct {
+		language string
+		quality  float64
+	}
+
+	var options []option
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if value, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+
+		language := strings.ToLower(strings.SplitN(strings.TrimSpace(fields[0]), "-", 2)[0])
+		if language != "" && quality > 0 {
+			options = append(options, option{language: language, quality: quality})
+		}
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].quality > options[j].quality
+	})
+
+	for _, option := range options {
+		if option.language == LanguageEnglish || option.language == LanguageIndonesian {
+			return option.language
+		}
+	}
+
+	return DefaultLanguage
+}
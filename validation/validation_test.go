@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestLanguage(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"empty", "", LanguageEnglish},
+		{"english", "en", LanguageEnglish},
+		{"indonesian", "id", LanguageIndonesian},
+		{"region falls back to the language", "id-ID", LanguageIndonesian},
+		{"case is ignored", "ID-id", LanguageIndonesian},
+		{"highest quality wins", "en;q=0.5,id;q=0.8", LanguageIndonesian},
+		{"no quality is 1", "en,id;q=0.9", LanguageEnglish},
+		{"equal quality keeps the order", "id;q=0.7,en;q=0.7", LanguageIndonesian},
+		{"browser header", "id-ID,id;q=0.9,en-US;q=0.8,en;q=0.7", LanguageIndonesian},
+		{"spaces", " en-GB ; q=0.4 , id ; q=0.6 ", LanguageIndonesian},
+		{"unsupported ones are skipped", "fr-FR,de;q=0.9,id;q=0.1", LanguageIndonesian},
+		{"only unsupported ones", "fr,de", DefaultLanguage},
+		{"q=0 excludes the language", "id;q=0,en;q=0.1", LanguageEnglish},
+		{"q=0 on the only supported one", "id;q=0", DefaultLanguage},
+		{"invalid quality is 1", "en;q=0.5,id;q=high", LanguageIndonesian},
+		{"wildcard", "*", DefaultLanguage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Language(test.acceptLanguage); got != test.want {
+				t.Errorf("Language(%q) = %q, want %q", test.acceptLanguage, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTranslator(t *testing.T) {
+
+	type request struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"min=8"`
+	}
+
+	err := Validator().Struct(request{Password: "short"})
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) || len(validationErrors) != 2 {
+		t.Fatalf("err = %v, want 2 validation errors", err)
+	}
+
+	tests := []struct {
+		language string
+		want     []string
+	}{
+		{LanguageEnglish, []string{"email is a required field", "password must be at least 8 characters in length"}},
+		{LanguageIndonesian, []string{"email wajib diisi", "panjang minimal password adalah 8 karakter"}},
+		{"fr", []string{"email is a required field", "password must be at least 8 characters in length"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.language, func(t *testing.T) {
+			translator := Translator(test.language)
+			for i, fieldError := range validationErrors {
+				if got := fieldError.Translate(translator); got != test.want[i] {
+					t.Errorf("%s: message = %q, want %q", fieldError.Field(), got, test.want[i])
+				}
+			}
+		})
+	}
+}